@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+type userKey struct{}
+
+// Identity is an authenticated caller. Scopes is nil for session tokens,
+// which may do anything the user can, and lists what an access token grants.
+type Identity struct {
+	UserID string
+	Tier   string
+	Scopes []string
+}
+
+// AccessToken reports whether the caller authenticated with an access token.
+func (id Identity) AccessToken() bool {
+	return id.Scopes != nil
+}
+
+// Allows reports whether the caller may act with scope.
+func (id Identity) Allows(scope string) bool {
+	return !id.AccessToken() || slices.Contains(id.Scopes, scope)
+}
+
+// ContextWithUser returns a copy of ctx carrying the authenticated user. It
+// is the non-gin counterpart of the "user_id" and "tier" gin context keys.
+func ContextWithUser(ctx context.Context, userID, tier string) context.Context {
+	return ContextWithIdentity(ctx, Identity{UserID: userID, Tier: tier})
+}
+
+// ContextWithIdentity is ContextWithUser keeping the caller's scopes.
+func ContextWithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, userKey{}, id)
+}
+
+// UserFromContext returns the user stored by ContextWithUser.
+func UserFromContext(ctx context.Context) (userID, tier string, ok bool) {
+	id, ok := IdentityFromContext(ctx)
+	return id.UserID, id.Tier, ok
+}
+
+// IdentityFromContext returns the caller stored by ContextWithIdentity.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(userKey{}).(Identity)
+	return id, ok
+}
+
+// BearerToken extracts the token from an "Authorization: Bearer <token>" value.
+func BearerToken(header string) (string, bool) {
+	if header == "" || !strings.HasPrefix(header, "Bearer ") {
+		return "", false
+	}
+	return strings.TrimPrefix(header, "Bearer "), true
+}
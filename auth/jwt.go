@@ -1,29 +1,203 @@
 package auth
 
 import (
-	"os"
+	"context"
+	"errors"
+	"expense-tracker/config"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret = []byte(os.Getenv("JWT_SECRET"))
+var (
+	keys        = &keyring{}
+	jwtTTL      = 24 * time.Hour
+	jwtIssuer   string
+	jwtAudience string
+)
+
+// ErrInvalidToken is returned for tokens that are malformed, expired or not
+// signed by us.
+var ErrInvalidToken = errors.New("invalid token")
+
+// ErrSessionRevoked is returned for a well-formed token whose session has
+// been ended, for example by a password change.
+var ErrSessionRevoked = errors.New("session revoked")
+
+// SessionValidator returns an error if tokens issued to userID at session
+// version are no longer valid.
+type SessionValidator func(ctx context.Context, userID string, version int) error
+
+var sessionValidator SessionValidator
+
+// Configure loads the signing keys and sets the token lifetime, issuer and
+// audience. It must be called before tokens are issued or parsed.
+func Configure(cfg config.JWT) error {
+	kr, err := loadKeyring(cfg)
+	if err != nil {
+		return err
+	}
+	keys = kr
+	if cfg.TTL.Duration > 0 {
+		jwtTTL = cfg.TTL.Duration
+	}
+	jwtIssuer, jwtAudience = cfg.Issuer, cfg.Audience
+	return nil
+}
+
+// sign adds the registered claims shared by every token and signs it.
+func sign(claims jwt.MapClaims, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(ttl).Unix()
+	if jwtIssuer != "" {
+		claims["iss"] = jwtIssuer
+	}
+	if jwtAudience != "" {
+		claims["aud"] = jwtAudience
+	}
+	return keys.sign(claims)
+}
+
+// SetSessionValidator installs the check Authenticate runs on every token so
+// that sessions can be revoked before their tokens expire.
+func SetSessionValidator(v SessionValidator) {
+	sessionValidator = v
+}
+
+// GenerateToken issues a token for userID. sessionVersion is recorded in the
+// "sv" claim; bumping the user's version revokes every older token.
+func GenerateToken(userID, tier string, sessionVersion int) (string, error) {
+	claims := jwt.MapClaims{
+		"user_id": userID,
+		"tier":    tier,
+		"sv":      sessionVersion,
+	}
+	return sign(claims, jwtTTL)
+}
+
+// ChallengeTTL is how long a user has to complete the second login step.
+const ChallengeTTL = 5 * time.Minute
+
+// challengeType is the "typ" claim of challenge tokens. Session tokens carry
+// no typ, and Authenticate rejects any token that does.
+const challengeType = "mfa_challenge"
 
-func GenerateToken(userID string) (string, error) {
+// GenerateChallengeToken issues the short-lived token a user with two-factor
+// authentication exchanges, with a valid code, for a session token.
+func GenerateChallengeToken(userID string, sessionVersion int) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
+		"sv":      sessionVersion,
+		"typ":     challengeType,
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	return sign(claims, ChallengeTTL)
 }
 
+// ParseChallengeToken validates a token from GenerateChallengeToken.
+func ParseChallengeToken(tokenString string) (userID string, sessionVersion int, err error) {
+	claims, err := ParseToken(tokenString)
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if typ, _ := claims["typ"].(string); typ != challengeType {
+		return "", 0, fmt.Errorf("%w: not a challenge token", ErrInvalidToken)
+	}
+	userID, _ = claims["user_id"].(string)
+	version, _ := claims["sv"].(float64)
+	return userID, int(version), nil
+}
+
+// OIDCStateTTL is how long a user has to sign in at the OIDC provider.
+const OIDCStateTTL = 10 * time.Minute
+
+const oidcStateType = "oidc_state"
+
+// OIDCState is what the service needs to remember between sending a user to
+// the OIDC provider and the provider sending them back. It travels in a
+// signed cookie so any replica can finish the login.
+type OIDCState struct {
+	State    string
+	Nonce    string
+	Verifier string
+	// LinkUserID is set when a signed-in user is linking their account to
+	// the identity rather than signing in with it.
+	LinkUserID string
+}
+
+// GenerateOIDCStateToken signs s for OIDCStateTTL.
+func GenerateOIDCStateToken(s OIDCState) (string, error) {
+	claims := jwt.MapClaims{
+		"typ":      oidcStateType,
+		"state":    s.State,
+		"nonce":    s.Nonce,
+		"verifier": s.Verifier,
+		"link":     s.LinkUserID,
+	}
+	return sign(claims, OIDCStateTTL)
+}
+
+// ParseOIDCStateToken validates a token from GenerateOIDCStateToken.
+func ParseOIDCStateToken(tokenString string) (OIDCState, error) {
+	claims, err := ParseToken(tokenString)
+	if err != nil {
+		return OIDCState{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if typ, _ := claims["typ"].(string); typ != oidcStateType {
+		return OIDCState{}, fmt.Errorf("%w: not an OIDC state token", ErrInvalidToken)
+	}
+	var s OIDCState
+	s.State, _ = claims["state"].(string)
+	s.Nonce, _ = claims["nonce"].(string)
+	s.Verifier, _ = claims["verifier"].(string)
+	s.LinkUserID, _ = claims["link"].(string)
+	return s, nil
+}
+
+// ParseToken verifies a token's signature, algorithm, issuer, audience and
+// expiry and returns its claims.
 func ParseToken(tokenString string) (jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
-	if err != nil || !token.Valid {
+	token, err := jwt.Parse(tokenString, keys.keyFunc,
+		jwt.WithValidMethods(keys.methods),
+		jwt.WithIssuer(jwtIssuer),
+		jwt.WithAudience(jwtAudience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
 		return nil, err
 	}
 	return token.Claims.(jwt.MapClaims), nil
 }
+
+// Authenticate parses a bearer token, either a session JWT or a personal
+// access token, and checks that it is still valid, returning the caller.
+// Errors other than ErrInvalidToken and ErrSessionRevoked come from the
+// session or access token validator.
+func Authenticate(ctx context.Context, tokenString string) (Identity, error) {
+	if strings.HasPrefix(tokenString, AccessTokenPrefix) {
+		if accessTokenValidator == nil {
+			return Identity{}, fmt.Errorf("%w: access tokens are not enabled", ErrInvalidToken)
+		}
+		return accessTokenValidator(ctx, tokenString)
+	}
+	claims, err := ParseToken(tokenString)
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if typ, _ := claims["typ"].(string); typ != "" {
+		return Identity{}, fmt.Errorf("%w: %s token used as a session token", ErrInvalidToken, typ)
+	}
+	var id Identity
+	id.UserID, _ = claims["user_id"].(string)
+	id.Tier, _ = claims["tier"].(string)
+	if sessionValidator != nil {
+		version, _ := claims["sv"].(float64)
+		if err := sessionValidator(ctx, id.UserID, int(version)); err != nil {
+			return Identity{}, err
+		}
+	}
+	return id, nil
+}
@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"expense-tracker/config"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKey saves key as PEM in a temporary file and returns its path.
+func writeKey(t *testing.T, key interface{}) string {
+	t.Helper()
+	var block *pem.Block
+	if _, ok := key.(crypto.Signer); ok {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		require.NoError(t, err)
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	} else {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		require.NoError(t, err)
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	}
+	path := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), 0o600))
+	return path
+}
+
+func configure(t *testing.T, cfg config.JWT) {
+	t.Helper()
+	prevKeys, prevIss, prevAud := keys, jwtIssuer, jwtAudience
+	t.Cleanup(func() { keys, jwtIssuer, jwtAudience = prevKeys, prevIss, prevAud })
+	cfg.Issuer, cfg.Audience = "tracker", "tracker-api"
+	require.NoError(t, Configure(cfg))
+}
+
+func TestJWT_SignsWithConfiguredKey(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		key interface{}
+		alg string
+	}{{rsaKey, "RS256"}, {edKey, "EdDSA"}} {
+		t.Run(tc.alg, func(t *testing.T) {
+			configure(t, config.JWT{SigningKeyFile: writeKey(t, tc.key)})
+			token, err := GenerateToken("user-1", DefaultTier, 0)
+			require.NoError(t, err)
+
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.alg, parsed.Method.Alg())
+			set := PublicKeys()
+			require.Len(t, set.Keys, 1)
+			assert.Equal(t, set.Keys[0].Kid, parsed.Header["kid"])
+			assert.Equal(t, tc.alg, set.Keys[0].Alg)
+
+			id, err := Authenticate(context.Background(), token)
+			require.NoError(t, err)
+			assert.Equal(t, "user-1", id.UserID)
+		})
+	}
+}
+
+func TestJWT_KeyRotation(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, newKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	oldFile, newFile := writeKey(t, oldKey), writeKey(t, newKey)
+
+	configure(t, config.JWT{SigningKeyFile: oldFile})
+	oldToken, err := GenerateToken("user-1", DefaultTier, 0)
+	require.NoError(t, err)
+
+	// The retired key keeps verifying, given only its public half.
+	configure(t, config.JWT{SigningKeyFile: newFile, VerificationKeyFiles: []string{writeKey(t, &oldKey.PublicKey)}})
+	assert.Len(t, PublicKeys().Keys, 2)
+	_, err = Authenticate(context.Background(), oldToken)
+	assert.NoError(t, err)
+	newToken, err := GenerateToken("user-1", DefaultTier, 0)
+	require.NoError(t, err)
+	_, err = Authenticate(context.Background(), newToken)
+	assert.NoError(t, err)
+
+	configure(t, config.JWT{SigningKeyFile: newFile})
+	_, err = Authenticate(context.Background(), oldToken)
+	assert.ErrorIs(t, err, ErrInvalidToken, "a removed key verifies nothing")
+}
+
+func TestJWT_SecretStillVerifiesAfterSwitch(t *testing.T) {
+	configure(t, config.JWT{Secret: "old-secret"})
+	hsToken, err := GenerateToken("user-1", DefaultTier, 0)
+	require.NoError(t, err)
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	configure(t, config.JWT{Secret: "old-secret", SigningKeyFile: writeKey(t, key)})
+	_, err = Authenticate(context.Background(), hsToken)
+	assert.NoError(t, err)
+	token, err := GenerateToken("user-1", DefaultTier, 0)
+	require.NoError(t, err)
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, "EdDSA", parsed.Method.Alg())
+	assert.Len(t, PublicKeys().Keys, 1, "the secret is never published")
+}
+
+func TestJWT_RejectsForgedTokens(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	configure(t, config.JWT{SigningKeyFile: writeKey(t, rsaKey)})
+	kid := PublicKeys().Keys[0].Kid
+	claims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"user_id": "user-1",
+			"iss":     "tracker",
+			"aud":     "tracker-api",
+			"iat":     time.Now().Unix(),
+			"exp":     time.Now().Add(time.Hour).Unix(),
+		}
+	}
+	signRSA := func(c jwt.MapClaims, kid string) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, c)
+		token.Header["kid"] = kid
+		s, err := token.SignedString(rsaKey)
+		require.NoError(t, err)
+		return s
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	for name, token := range map[string]string{
+		// The classic confusion attack: HMAC keyed with the public key.
+		"hs256 with public key": func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims())
+			token.Header["kid"] = kid
+			s, err := token.SignedString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}))
+			require.NoError(t, err)
+			return s
+		}(),
+		"none": func() string {
+			s, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims()).SignedString(jwt.UnsafeAllowNoneSignatureType)
+			require.NoError(t, err)
+			return s
+		}(),
+		"unlisted algorithm": func() string {
+			token := jwt.NewWithClaims(jwt.SigningMethodES256, claims())
+			token.Header["kid"] = kid
+			s, err := token.SignedString(otherKey)
+			require.NoError(t, err)
+			return s
+		}(),
+		"unknown kid": signRSA(claims(), "other"),
+		"wrong issuer": func() string {
+			c := claims()
+			c["iss"] = "someone-else"
+			return signRSA(c, kid)
+		}(),
+		"wrong audience": func() string {
+			c := claims()
+			c["aud"] = "another-api"
+			return signRSA(c, kid)
+		}(),
+		"no expiry": func() string {
+			c := claims()
+			delete(c, "exp")
+			return signRSA(c, kid)
+		}(),
+	} {
+		_, err := Authenticate(context.Background(), token)
+		assert.ErrorIs(t, err, ErrInvalidToken, name)
+	}
+	_, err = Authenticate(context.Background(), signRSA(claims(), kid))
+	assert.NoError(t, err, "the same claims properly signed are accepted")
+}
+
+func TestConfigure_RejectsBadKeys(t *testing.T) {
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	ec, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	assert.ErrorContains(t, Configure(config.JWT{SigningKeyFile: writeKey(t, small)}), "2048 bits")
+	assert.ErrorContains(t, Configure(config.JWT{SigningKeyFile: writeKey(t, ec)}), "unsupported key type")
+	assert.ErrorContains(t, Configure(config.JWT{SigningKeyFile: writeKey(t, pub)}), "must be a private key")
+	assert.Error(t, Configure(config.JWT{SigningKeyFile: filepath.Join(t.TempDir(), "missing.pem")}))
+	assert.Error(t, Configure(config.JWT{}))
+}
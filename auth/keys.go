@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"expense-tracker/config"
+	"expense-tracker/oidc"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSABits is the smallest RSA key accepted for signing or verification.
+const minRSABits = 2048
+
+// key is an asymmetric key tokens are verified with, and signed with if it
+// has a private half.
+type key struct {
+	jwk     oidc.JWK
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// keyring holds everything tokens are signed and verified with. Asymmetric
+// keys are identified by the "kid" header, which is their JWK thumbprint.
+type keyring struct {
+	// secret verifies HS256 tokens, and signs when there is no signer.
+	secret []byte
+	signer *key
+	// keys are the asymmetric verification keys, signer first.
+	keys    []*key
+	methods []string
+}
+
+// loadKeyring reads the keys named in cfg.
+func loadKeyring(cfg config.JWT) (*keyring, error) {
+	kr := &keyring{}
+	if cfg.Secret != "" {
+		kr.secret = []byte(cfg.Secret)
+		kr.methods = append(kr.methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.SigningKeyFile != "" {
+		k, err := readKey(cfg.SigningKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if k.private == nil {
+			return nil, fmt.Errorf("%s: signing key must be a private key", cfg.SigningKeyFile)
+		}
+		kr.signer = k
+		kr.add(k)
+	}
+	for _, path := range cfg.VerificationKeyFiles {
+		k, err := readKey(path)
+		if err != nil {
+			return nil, err
+		}
+		// Only the signing key signs; a retired private key just verifies.
+		k.private = nil
+		kr.add(k)
+	}
+	if kr.secret == nil && kr.signer == nil {
+		return nil, errors.New("no JWT secret or signing key configured")
+	}
+	return kr, nil
+}
+
+func (kr *keyring) add(k *key) {
+	if kr.lookup(k.jwk.Kid) != nil {
+		return
+	}
+	kr.keys = append(kr.keys, k)
+	for _, m := range kr.methods {
+		if m == k.method.Alg() {
+			return
+		}
+	}
+	kr.methods = append(kr.methods, k.method.Alg())
+}
+
+func (kr *keyring) lookup(kid string) *key {
+	for _, k := range kr.keys {
+		if k.jwk.Kid == kid {
+			return k
+		}
+	}
+	return nil
+}
+
+// sign signs claims with the signing key, or the secret if there is none.
+func (kr *keyring) sign(claims jwt.MapClaims) (string, error) {
+	if kr.signer != nil {
+		token := jwt.NewWithClaims(kr.signer.method, claims)
+		token.Header["kid"] = kr.signer.jwk.Kid
+		return token.SignedString(kr.signer.private)
+	}
+	if kr.secret == nil {
+		return "", errors.New("auth is not configured")
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(kr.secret)
+}
+
+// keyFunc picks the verification key for a token. Each key only verifies
+// its own algorithm, so a public key can never be mistaken for an HMAC
+// secret.
+func (kr *keyring) keyFunc(token *jwt.Token) (interface{}, error) {
+	alg := token.Method.Alg()
+	if alg == jwt.SigningMethodHS256.Alg() {
+		if kr.secret == nil {
+			return nil, errors.New("HS256 tokens are not accepted")
+		}
+		return kr.secret, nil
+	}
+	kid, _ := token.Header["kid"].(string)
+	k := kr.lookup(kid)
+	if k == nil {
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	}
+	if k.method.Alg() != alg {
+		return nil, fmt.Errorf("key %q does not sign %s", kid, alg)
+	}
+	return k.public, nil
+}
+
+// readKey reads a PEM private or public key. RSA keys sign with RS256 and
+// Ed25519 keys with EdDSA.
+func readKey(path string) (*key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data", path)
+	}
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	k := &key{}
+	if signer, ok := parsed.(crypto.Signer); ok {
+		k.private, k.public = signer, signer.Public()
+	} else {
+		k.public = parsed
+	}
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("%s: RSA key must have at least %d bits", path, minRSABits)
+		}
+		k.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		k.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("%s: unsupported key type %T; use RSA or Ed25519", path, pub)
+	}
+	if k.jwk, err = oidc.NewJWK("", k.method.Alg(), k.public); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	k.jwk.Kid = k.jwk.Thumbprint()
+	return k, nil
+}
+
+// PublicKeys returns the asymmetric verification keys for other services to
+// check our tokens with. It is empty when tokens are signed with a secret.
+func PublicKeys() oidc.JWKS {
+	set := oidc.JWKS{Keys: []oidc.JWK{}}
+	for _, k := range keys.keys {
+		set.Keys = append(set.Keys, k.jwk)
+	}
+	return set
+}
@@ -1,28 +1,78 @@
 package auth
 
 import (
+	"errors"
+	"expense-tracker/problem"
+	"expense-tracker/tracing"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/codes"
 )
 
+// JWTAuthMiddleware authenticates the bearer token, a session JWT or a
+// personal access token, and stores the caller on the request. Routes that
+// accept access tokens must also use RequireScope or RequireSession.
 func JWTAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid Authorization header"})
-			c.Abort()
+		ctx, span := tracing.Tracer().Start(c.Request.Context(), "auth.JWTAuthMiddleware")
+		tokenString, ok := BearerToken(c.GetHeader("Authorization"))
+		if !ok {
+			span.SetStatus(codes.Error, "missing bearer token")
+			span.End()
+			problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeUnauthorized, "Missing or invalid Authorization header"))
+			return
+		}
+		id, err := Authenticate(ctx, tokenString)
+		if err != nil && !errors.Is(err, ErrInvalidToken) && !errors.Is(err, ErrSessionRevoked) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "session check failed")
+			span.End()
+			problem.Abort(c, err)
 			return
 		}
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		claims, err := ParseToken(tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "invalid token")
+			span.End()
+			problem.Abort(c, &problem.Error{Status: http.StatusUnauthorized, Code: problem.CodeInvalidToken,
+				Detail: "Invalid or expired token", Err: err})
+			return
+		}
+		c.Set("user_id", id.UserID)
+		if id.Tier != "" {
+			c.Set("tier", id.Tier)
+		}
+		c.Request = c.Request.WithContext(ContextWithIdentity(c.Request.Context(), id))
+		span.End()
+		c.Next()
+	}
+}
+
+// RequireScope lets access tokens through only if they were granted read
+// (for GET, HEAD and OPTIONS requests) or write (for any other method).
+// Session tokens always pass. It must run after JWTAuthMiddleware.
+func RequireScope(read, write string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, _ := IdentityFromContext(c.Request.Context())
+		scope := ScopeForMethod(c.Request.Method, read, write)
+		if !id.Allows(scope) {
+			problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeInsufficientScope, "Access token lacks the "+scope+" scope"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSession rejects personal access tokens, keeping account management
+// (passwords, 2FA, the tokens themselves) to interactive sessions. It must
+// run after JWTAuthMiddleware.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if id, _ := IdentityFromContext(c.Request.Context()); id.AccessToken() {
+			problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeSessionRequired, "Access tokens cannot be used here; log in instead"))
 			return
 		}
-		c.Set("user_id", claims["user_id"])
 		c.Next()
 	}
 }
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"expense-tracker/config"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrWeakPassword is returned, wrapped with the reason, for passwords that
+// the policy rejects.
+var ErrWeakPassword = errors.New("password does not meet the policy")
+
+// maxPasswordBytes is bcrypt's input limit; longer passwords would be
+// silently truncated.
+const maxPasswordBytes = 72
+
+//go:embed common-passwords.txt
+var commonPasswords string
+
+// PasswordPolicy decides which passwords users may set.
+type PasswordPolicy struct {
+	MinLength int
+	// breached holds the SHA-1 of every rejected password.
+	breached map[[sha1.Size]byte]struct{}
+}
+
+var passwordPolicy = mustDefaultPolicy()
+
+func mustDefaultPolicy() *PasswordPolicy {
+	p, err := NewPasswordPolicy(config.Default().Password)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// NewPasswordPolicy builds the policy described by cfg, reading its breached
+// password list.
+func NewPasswordPolicy(cfg config.Password) (*PasswordPolicy, error) {
+	p := &PasswordPolicy{MinLength: cfg.MinLength, breached: map[[sha1.Size]byte]struct{}{}}
+	if cfg.BreachedList == "" {
+		return p, p.readBreached(strings.NewReader(commonPasswords))
+	}
+	f, err := os.Open(cfg.BreachedList)
+	if err != nil {
+		return nil, fmt.Errorf("open breached password list: %w", err)
+	}
+	defer f.Close()
+	if err := p.readBreached(f); err != nil {
+		return nil, fmt.Errorf("read breached password list: %w", err)
+	}
+	return p, nil
+}
+
+// readBreached adds one password per line. Lines that are 40 hex digits,
+// optionally followed by ":count", are taken to be SHA-1 hashes.
+func (p *PasswordPolicy) readBreached(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		hash, _, _ := strings.Cut(line, ":")
+		if len(hash) == 2*sha1.Size {
+			var sum [sha1.Size]byte
+			if _, err := hex.Decode(sum[:], []byte(hash)); err == nil {
+				p.breached[sum] = struct{}{}
+				continue
+			}
+		}
+		p.breached[sha1.Sum([]byte(line))] = struct{}{}
+	}
+	return scanner.Err()
+}
+
+// Check returns an error wrapping ErrWeakPassword that says why password is
+// not acceptable, or nil.
+func (p *PasswordPolicy) Check(password string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return fmt.Errorf("%w: must be at least %d characters", ErrWeakPassword, p.MinLength)
+	}
+	if len(password) > maxPasswordBytes {
+		return fmt.Errorf("%w: must be at most %d bytes", ErrWeakPassword, maxPasswordBytes)
+	}
+	for _, candidate := range []string{password, strings.ToLower(password)} {
+		if _, ok := p.breached[sha1.Sum([]byte(candidate))]; ok {
+			return fmt.Errorf("%w: appears in a list of breached passwords", ErrWeakPassword)
+		}
+	}
+	return nil
+}
+
+// ConfigurePasswords replaces the password policy used by CheckPassword.
+func ConfigurePasswords(cfg config.Password) error {
+	p, err := NewPasswordPolicy(cfg)
+	if err != nil {
+		return err
+	}
+	passwordPolicy = p
+	return nil
+}
+
+// CheckPassword applies the configured password policy.
+func CheckPassword(password string) error {
+	return passwordPolicy.Check(password)
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"expense-tracker/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordPolicy_Default(t *testing.T) {
+	p, err := NewPasswordPolicy(config.Default().Password)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, p.Check("short"), ErrWeakPassword)
+	assert.ErrorContains(t, p.Check("short"), "at least 8 characters")
+	assert.ErrorContains(t, p.Check("password123"), "breached")
+	assert.ErrorContains(t, p.Check("PassWord123"), "breached", "the built-in list matches case-insensitively")
+	assert.ErrorContains(t, p.Check(strings.Repeat("x", 73)), "at most 72 bytes")
+	assert.NoError(t, p.Check("correct horse battery"))
+	assert.NoError(t, p.Check("ünïcødé"+"ß"), "length counts characters, not bytes")
+	assert.ErrorIs(t, p.Check("ünïcødé"), ErrWeakPassword)
+}
+
+func TestPasswordPolicy_BreachedFile(t *testing.T) {
+	sum := sha1.Sum([]byte("hunter2hunter2"))
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(path, []byte(
+		"correct horse battery\n"+
+			strings.ToUpper(hex.EncodeToString(sum[:]))+":42\n"+
+			"\n"), 0o600))
+
+	p, err := NewPasswordPolicy(config.Password{MinLength: 10, BreachedList: path})
+	require.NoError(t, err)
+	assert.ErrorContains(t, p.Check("correct horse battery"), "breached")
+	assert.ErrorContains(t, p.Check("hunter2hunter2"), "breached", "SHA-1 lines are matched by hash")
+	assert.NoError(t, p.Check("password123"), "a custom list replaces the built-in one")
+	assert.ErrorContains(t, p.Check("123456789"), "at least 10")
+
+	_, err = NewPasswordPolicy(config.Password{MinLength: 8, BreachedList: filepath.Join(t.TempDir(), "missing")})
+	assert.Error(t, err)
+}
@@ -1,34 +1,141 @@
 package auth
 
 import (
+	"context"
+	"expense-tracker/config"
+	"expense-tracker/metrics"
+	"expense-tracker/problem"
+	"expense-tracker/tracing"
 	"fmt"
+	"math"
 	"net/http"
-	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	libredis "github.com/redis/go-redis/v9"
 	"github.com/ulule/limiter/v3"
 	memory "github.com/ulule/limiter/v3/drivers/store/memory"
+	sredis "github.com/ulule/limiter/v3/drivers/store/redis"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func RateLimitMiddleware() gin.HandlerFunc {
-	// 10 requests per minute
-	rate, _ := limiter.NewRateFromFormatted("10-M")
-	store := memory.NewStore()
-	instance := limiter.New(store, rate)
+// DefaultTier is the tier assumed for users whose token carries no tier claim.
+const DefaultTier = "free"
 
-	return func(c *gin.Context) {
-		userID, exists := c.Get("user_id")
-		identifier := ""
-		if exists {
-			identifier = userID.(string)
-		} else {
-			// fallback to IP if no user_id (should not happen for protected routes)
-			identifier = strings.Split(c.ClientIP(), ":")[0]
+// NewRateLimitStore builds the limiter store selected by cfg.Store.
+func NewRateLimitStore(cfg config.RateLimit) (limiter.Store, error) {
+	opts := limiter.StoreOptions{
+		Prefix:          cfg.Prefix,
+		CleanUpInterval: limiter.DefaultCleanUpInterval,
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = limiter.DefaultPrefix
+	}
+	switch cfg.Store {
+	case "", "memory":
+		return memory.NewStoreWithOptions(opts), nil
+	case "redis":
+		redisOpts, err := libredis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis url: %w", err)
 		}
+		return sredis.NewStoreWithOptions(libredis.NewClient(redisOpts), opts)
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", cfg.Store)
+	}
+}
+
+// RateLimiter applies the configured limits to route groups. All groups
+// share one store; keys are namespaced by group and tier.
+type RateLimiter struct {
+	store limiter.Store
+	// groups maps a group name to its limiters, keyed "anonymous", "default" or "tier:<name>".
+	groups map[string]map[string]*limiter.Limiter
+}
+
+// NewRateLimiter validates every configured rate and wraps the store.
+func NewRateLimiter(store limiter.Store, cfg config.RateLimit) (*RateLimiter, error) {
+	rl := &RateLimiter{store: store, groups: make(map[string]map[string]*limiter.Limiter, len(cfg.Groups))}
+	for name, group := range cfg.Groups {
+		limiters := map[string]*limiter.Limiter{}
+		add := func(key, formatted string) error {
+			if formatted == "" {
+				return nil
+			}
+			rate, err := limiter.NewRateFromFormatted(formatted)
+			if err != nil {
+				return fmt.Errorf("rate limit group %q: %w", name, err)
+			}
+			limiters[key] = limiter.New(store, rate)
+			return nil
+		}
+		if err := add("anonymous", group.Anonymous); err != nil {
+			return nil, err
+		}
+		if err := add("default", group.Default); err != nil {
+			return nil, err
+		}
+		for tier, r := range group.Tiers {
+			if err := add("tier:"+tier, r); err != nil {
+				return nil, err
+			}
+		}
+		rl.groups[name] = limiters
+	}
+	return rl, nil
+}
 
-		context, err := instance.Get(c, identifier)
+// Limit counts one request against the named group. Authenticated callers
+// (non-empty userID) are keyed by user ID and limited by tier; anonymous ones
+// are keyed by clientIP. ok is false when the group has no applicable rate.
+func (rl *RateLimiter) Limit(ctx context.Context, group, userID, tier, clientIP string) (result limiter.Context, ok bool, err error) {
+	limiters := rl.groups[group]
+	var instance *limiter.Limiter
+	var identifier string
+	if userID != "" {
+		if tier == "" {
+			tier = DefaultTier
+		}
+		instance = limiters["tier:"+tier]
+		if instance == nil {
+			instance = limiters["default"]
+		}
+		identifier = group + ":" + tier + ":user:" + userID
+	} else {
+		instance = limiters["anonymous"]
+		identifier = group + ":ip:" + clientIP
+	}
+	if instance == nil {
+		return limiter.Context{}, false, nil
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, "auth.RateLimitMiddleware",
+		trace.WithAttributes(attribute.String("ratelimit.group", group)))
+	defer span.End()
+	result, err = instance.Get(ctx, identifier)
+	if err != nil {
+		span.RecordError(err)
+		return result, true, err
+	}
+	span.SetAttributes(attribute.Bool("ratelimit.reached", result.Reached))
+	if result.Reached {
+		metrics.RateLimitRejections.WithLabelValues(group).Inc()
+	}
+	return result, true, nil
+}
+
+// RateLimitMiddleware limits requests for the named route group. A group
+// without a configured rate is not limited.
+func (rl *RateLimiter) RateLimitMiddleware(group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		context, ok, err := rl.Limit(c.Request.Context(), group, c.GetString("user_id"), c.GetString("tier"), c.ClientIP())
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Rate limiter error"})
+			problem.Abort(c, fmt.Errorf("rate limiter: %w", err))
+			return
+		}
+		if !ok {
+			c.Next()
 			return
 		}
 
@@ -37,9 +144,16 @@ func RateLimitMiddleware() gin.HandlerFunc {
 		c.Header("X-RateLimit-Reset", fmt.Sprintf("%v", context.Reset))
 
 		if context.Reached {
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			c.Header("Retry-After", fmt.Sprintf("%d", RetryAfter(context.Reset)))
+			problem.Abort(c, problem.New(http.StatusTooManyRequests, problem.CodeRateLimited, "Too many requests"))
 			return
 		}
 		c.Next()
 	}
 }
+
+// RetryAfter converts a reset unix timestamp into whole seconds to wait, at least one.
+func RetryAfter(reset int64) int64 {
+	wait := time.Until(time.Unix(reset, 0)).Seconds()
+	return int64(math.Max(1, math.Ceil(wait)))
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"expense-tracker/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/ulule/limiter/v3"
+)
+
+func newLimitedRouter(t *testing.T, store limiter.Store, userID, tier string) *gin.Engine {
+	cfg := config.RateLimit{Groups: map[string]config.GroupLimit{
+		"expenses": {Anonymous: "1-M", Default: "2-M", Tiers: map[string]string{"premium": "3-M"}},
+	}}
+	rl, err := NewRateLimiter(store, cfg)
+	if err != nil {
+		t.Fatalf("NewRateLimiter: %v", err)
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if userID != "" {
+			c.Set("user_id", userID)
+			c.Set("tier", tier)
+		}
+	})
+	r.GET("/", rl.RateLimitMiddleware("expenses"), func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+// allowedRequests counts successful requests before the first 429.
+func allowedRequests(t *testing.T, r *gin.Engine) int {
+	for i := 0; i < 10; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code == http.StatusTooManyRequests {
+			assert.NotEmpty(t, w.Header().Get("Retry-After"))
+			return i
+		}
+	}
+	return 10
+}
+
+func TestRateLimitMiddleware_Tiers(t *testing.T) {
+	store, _ := NewRateLimitStore(config.RateLimit{Store: "memory"})
+	assert.Equal(t, 1, allowedRequests(t, newLimitedRouter(t, store, "", "")))
+	assert.Equal(t, 2, allowedRequests(t, newLimitedRouter(t, store, "u1", "")))
+	assert.Equal(t, 3, allowedRequests(t, newLimitedRouter(t, store, "u2", "premium")))
+}
+
+func TestRateLimitMiddleware_RedisSharedAcrossInstances(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cfg := config.RateLimit{Store: "redis", RedisURL: "redis://" + mr.Addr()}
+	storeA, err := NewRateLimitStore(cfg)
+	if err != nil {
+		t.Fatalf("NewRateLimitStore: %v", err)
+	}
+	storeB, _ := NewRateLimitStore(cfg)
+
+	assert.Equal(t, 2, allowedRequests(t, newLimitedRouter(t, storeA, "u1", "")))
+	// A second replica sees the same counters.
+	assert.Equal(t, 0, allowedRequests(t, newLimitedRouter(t, storeB, "u1", "")))
+}
+
+func TestNewRateLimiter_InvalidRate(t *testing.T) {
+	store, _ := NewRateLimitStore(config.RateLimit{})
+	_, err := NewRateLimiter(store, config.RateLimit{Groups: map[string]config.GroupLimit{"x": {Default: "bogus"}}})
+	assert.Error(t, err)
+}
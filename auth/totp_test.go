@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTPCode_RFC6238Vectors(t *testing.T) {
+	// The SHA-1 vectors from RFC 6238 appendix B, truncated to six digits.
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	for _, tc := range []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	} {
+		code, err := TOTPCode(secret, TOTPStep(time.Unix(tc.unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, tc.code, code, tc.unix)
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	require.NoError(t, err)
+	now := time.Date(2025, 7, 1, 12, 0, 10, 0, time.UTC)
+
+	code, err := TOTPCode(secret, TOTPStep(now.Add(-30*time.Second)))
+	require.NoError(t, err)
+	step, ok := VerifyTOTP(secret, code, now)
+	assert.True(t, ok, "the previous period is accepted for clock drift")
+	assert.Equal(t, TOTPStep(now)-1, step)
+
+	code, _ = TOTPCode(secret, TOTPStep(now.Add(-90*time.Second)))
+	_, ok = VerifyTOTP(secret, code, now)
+	assert.False(t, ok)
+	_, ok = VerifyTOTP(secret, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	u, err := url.Parse(TOTPURI("Expense Tracker", "alice", "JBSWY3DPEHPK3PXP"))
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/Expense Tracker:alice", u.Path)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", u.Query().Get("secret"))
+	assert.Equal(t, "Expense Tracker", u.Query().Get("issuer"))
+}
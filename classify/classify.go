@@ -0,0 +1,154 @@
+// Package classify suggests a label for a short text, such as the category
+// of an expense from its description, with a multinomial naive Bayes model
+// that learns and forgets one example at a time.
+package classify
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Prediction is a label with the model's confidence in it, between 0 and 1.
+// The confidences of all the labels a model knows add up to 1.
+type Prediction struct {
+	Label      string
+	Confidence float64
+}
+
+// smoothing is the pseudo-count added to every token of every label. It is
+// well below the usual 1 so that, with the few words of a description, a
+// telling word outweighs a label that is merely common.
+const smoothing = 0.1
+
+// NaiveBayes is a naive Bayes text classifier with additive smoothing. It is
+// not safe for concurrent use.
+type NaiveBayes struct {
+	examples map[string]int            // examples per label
+	counts   map[string]map[string]int // occurrences of each token per label
+	tokens   map[string]int            // tokens per label
+	vocab    map[string]int            // occurrences of each token
+	n        int
+}
+
+// NewNaiveBayes returns an untrained model.
+func NewNaiveBayes() *NaiveBayes {
+	return &NaiveBayes{
+		examples: map[string]int{},
+		counts:   map[string]map[string]int{},
+		tokens:   map[string]int{},
+		vocab:    map[string]int{},
+	}
+}
+
+// Examples returns how many examples the model has learned.
+func (m *NaiveBayes) Examples() int {
+	return m.n
+}
+
+// Labels returns how many different labels the model has learned.
+func (m *NaiveBayes) Labels() int {
+	return len(m.examples)
+}
+
+// Add learns that text has label.
+func (m *NaiveBayes) Add(text, label string) {
+	m.update(text, label, 1)
+}
+
+// Remove forgets an example added earlier, as when an expense is deleted or
+// recategorized. Removing an example the model does not have is a no-op.
+func (m *NaiveBayes) Remove(text, label string) {
+	if m.examples[label] > 0 {
+		m.update(text, label, -1)
+	}
+}
+
+func (m *NaiveBayes) update(text, label string, delta int) {
+	m.n += delta
+	m.examples[label] += delta
+	counts := m.counts[label]
+	if counts == nil {
+		counts = map[string]int{}
+		m.counts[label] = counts
+	}
+	for _, t := range Tokens(text) {
+		counts[t] += delta
+		m.tokens[label] += delta
+		m.vocab[t] += delta
+		if counts[t] <= 0 {
+			delete(counts, t)
+		}
+		if m.vocab[t] <= 0 {
+			delete(m.vocab, t)
+		}
+	}
+	if m.examples[label] <= 0 {
+		delete(m.examples, label)
+		delete(m.counts, label)
+		delete(m.tokens, label)
+	}
+}
+
+// Predict ranks every known label for text, most likely first. Tokens the
+// model has never seen are ignored; if text has no known token at all there
+// is nothing to go on and Predict returns nil.
+func (m *NaiveBayes) Predict(text string) []Prediction {
+	var known []string
+	for _, t := range Tokens(text) {
+		if m.vocab[t] > 0 {
+			known = append(known, t)
+		}
+	}
+	if len(known) == 0 {
+		return nil
+	}
+
+	pseudoTokens := float64(len(m.vocab)) * smoothing
+	scores := make([]Prediction, 0, len(m.examples))
+	best := math.Inf(-1)
+	for label, examples := range m.examples {
+		score := math.Log(float64(examples) / float64(m.n))
+		denominator := float64(m.tokens[label]) + pseudoTokens
+		for _, t := range known {
+			score += math.Log((float64(m.counts[label][t]) + smoothing) / denominator)
+		}
+		scores = append(scores, Prediction{Label: label, Confidence: score})
+		best = math.Max(best, score)
+	}
+
+	// Normalize the log scores into probabilities, shifting by the best one
+	// so the exponentials cannot underflow to zero together.
+	var sum float64
+	for i := range scores {
+		scores[i].Confidence = math.Exp(scores[i].Confidence - best)
+		sum += scores[i].Confidence
+	}
+	for i := range scores {
+		scores[i].Confidence /= sum
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Confidence != scores[j].Confidence {
+			return scores[i].Confidence > scores[j].Confidence
+		}
+		return scores[i].Label < scores[j].Label
+	})
+	return scores
+}
+
+// Tokens splits text into lowercase words of at least two characters.
+// Numbers are dropped: store and card numbers say nothing about a category.
+func Tokens(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	out := words[:0]
+	for _, w := range words {
+		if len([]rune(w)) < 2 || strings.IndexFunc(w, unicode.IsLetter) < 0 {
+			continue
+		}
+		out = append(out, w)
+	}
+	return out
+}
@@ -0,0 +1,51 @@
+package classify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokens(t *testing.T) {
+	assert.Equal(t, []string{"starbucks", "seattle", "wa", "2nd"}, Tokens("STARBUCKS #1234 Seattle, WA 2nd x"))
+}
+
+func TestNaiveBayes_PredictsFromHistory(t *testing.T) {
+	m := NewNaiveBayes()
+	m.Add("Starbucks Seattle", "coffee")
+	m.Add("STARBUCKS #88", "coffee")
+	m.Add("Blue Bottle coffee", "coffee")
+	m.Add("Uber trip", "transport")
+	m.Add("Lyft ride", "transport")
+	m.Add("Whole Foods market", "groceries")
+
+	got := m.Predict("starbucks 4411")
+	require.Len(t, got, 3)
+	assert.Equal(t, "coffee", got[0].Label)
+	assert.Greater(t, got[0].Confidence, 0.7)
+	var sum float64
+	for _, p := range got {
+		sum += p.Confidence
+	}
+	assert.InDelta(t, 1, sum, 1e-9)
+
+	assert.Nil(t, m.Predict("Netflix"), "no known token")
+	assert.Equal(t, 6, m.Examples())
+	assert.Equal(t, 3, m.Labels())
+}
+
+func TestNaiveBayes_RemoveForgets(t *testing.T) {
+	m := NewNaiveBayes()
+	m.Add("Uber trip", "transport")
+	m.Add("Uber Eats", "food")
+	m.Remove("Uber trip", "transport")
+	m.Remove("Uber trip", "transport")
+
+	got := m.Predict("uber")
+	require.Len(t, got, 1)
+	assert.Equal(t, Prediction{Label: "food", Confidence: 1}, got[0])
+	assert.Nil(t, m.Predict("trip"), "forgotten tokens are unknown again")
+	assert.Equal(t, 1, m.Examples())
+	assert.Equal(t, 1, m.Labels())
+}
@@ -0,0 +1,245 @@
+// Package client is a Go client for the expense tracker REST API described
+// by the Swagger spec in docs/.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"expense-tracker/model"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client calls the API at BaseURL, authenticating with Token when set.
+type Client struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// New returns a client for baseURL, e.g. "http://localhost:8080".
+func New(baseURL, token string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// APIError is returned for any non-2xx response.
+type APIError struct {
+	StatusCode int
+	// Code is the problem's stable error code, e.g. "not_found".
+	Code      string
+	Message   string
+	RequestID string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("api error: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("api error: %d %s", e.StatusCode, e.Message)
+}
+
+// AuthResponse is returned by SignUp and Login. When TwoFactorRequired is
+// set, Token is empty and ChallengeToken must be passed to LoginTwoFactor.
+type AuthResponse struct {
+	User              string `json:"user"`
+	UserID            string `json:"user_id"`
+	Token             string `json:"token"`
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+}
+
+// ListOptions are the filters accepted by GET /api/v1/expenses.
+type ListOptions struct {
+	UserID   string
+	Category string
+	Currency string
+	From     string
+	To       string
+	Limit    int
+	Offset   int
+}
+
+func (o ListOptions) values() url.Values {
+	v := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			v.Set(key, value)
+		}
+	}
+	set("user_id", o.UserID)
+	set("category", o.Category)
+	set("currency", o.Currency)
+	set("from", o.From)
+	set("to", o.To)
+	if o.Limit > 0 {
+		v.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Offset > 0 {
+		v.Set("offset", strconv.Itoa(o.Offset))
+	}
+	return v
+}
+
+// SummaryOptions are the filters accepted by GET /api/v1/expenses/summary.
+type SummaryOptions struct {
+	UserID string
+	From   string
+	To     string
+}
+
+type credentials struct {
+	UserName string `json:"user_name"`
+	Password string `json:"password"`
+}
+
+// SignUp registers a user and returns its token.
+func (c *Client) SignUp(ctx context.Context, userName, password string) (AuthResponse, error) {
+	var resp AuthResponse
+	err := c.do(ctx, http.MethodPost, "/api/v1/signup", nil, credentials{userName, password}, &resp)
+	return resp, err
+}
+
+// Login authenticates and returns a token. It does not modify c.Token.
+func (c *Client) Login(ctx context.Context, userName, password string) (AuthResponse, error) {
+	var resp AuthResponse
+	err := c.do(ctx, http.MethodPost, "/api/v1/login", nil, credentials{userName, password}, &resp)
+	return resp, err
+}
+
+// LoginTwoFactor completes a login that returned TwoFactorRequired with a
+// TOTP or recovery code. It does not modify c.Token.
+func (c *Client) LoginTwoFactor(ctx context.Context, challengeToken, code string) (AuthResponse, error) {
+	var resp AuthResponse
+	body := map[string]string{"challenge_token": challengeToken, "code": code}
+	err := c.do(ctx, http.MethodPost, "/api/v1/login/2fa", nil, body, &resp)
+	return resp, err
+}
+
+// CreateExpense stores a new expense and returns it with its assigned ID.
+func (c *Client) CreateExpense(ctx context.Context, expense model.Expense) (model.Expense, error) {
+	var resp struct {
+		Expense model.Expense `json:"expense"`
+	}
+	err := c.do(ctx, http.MethodPost, "/api/v1/expenses/", nil, expense, &resp)
+	return resp.Expense, err
+}
+
+// GetExpense fetches one expense by ID.
+func (c *Client) GetExpense(ctx context.Context, id string) (model.Expense, error) {
+	var resp struct {
+		Expense model.Expense `json:"with expense"`
+	}
+	err := c.do(ctx, http.MethodGet, "/api/v1/expenses/"+url.PathEscape(id), nil, nil, &resp)
+	return resp.Expense, err
+}
+
+// UpdateExpense replaces the editable fields of an expense.
+func (c *Client) UpdateExpense(ctx context.Context, id string, expense model.Expense) (model.Expense, error) {
+	var resp struct {
+		Expense model.Expense `json:"expense"`
+	}
+	err := c.do(ctx, http.MethodPut, "/api/v1/expenses/"+url.PathEscape(id), nil, expense, &resp)
+	return resp.Expense, err
+}
+
+// DeleteExpense removes an expense.
+func (c *Client) DeleteExpense(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/expenses/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// ListExpenses returns one page of expenses.
+func (c *Client) ListExpenses(ctx context.Context, opts ListOptions) ([]model.Expense, error) {
+	var resp struct {
+		Expenses []model.Expense `json:"expenses"`
+	}
+	err := c.do(ctx, http.MethodGet, "/api/v1/expenses/", opts.values(), nil, &resp)
+	return resp.Expenses, err
+}
+
+// Summary returns the total amount per category.
+func (c *Client) Summary(ctx context.Context, opts SummaryOptions) (map[string]float64, error) {
+	v := ListOptions{UserID: opts.UserID, From: opts.From, To: opts.To}.values()
+	var resp map[string]float64
+	err := c.do(ctx, http.MethodGet, "/api/v1/expenses/summary", v, nil, &resp)
+	return resp, err
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		// Errors are RFC 7807 problem details.
+		var payload struct {
+			Code   string `json:"code"`
+			Detail string `json:"detail"`
+			Errors []struct {
+				Field  string `json:"field"`
+				Reason string `json:"reason"`
+			} `json:"errors"`
+			RequestID string `json:"request_id"`
+		}
+		if json.Unmarshal(data, &payload) == nil {
+			apiErr.Code, apiErr.RequestID = payload.Code, payload.RequestID
+			apiErr.Message = payload.Detail
+			for i, f := range payload.Errors {
+				sep := ", "
+				if i == 0 {
+					sep = ": "
+				}
+				apiErr.Message += sep + f.Field + " " + f.Reason
+			}
+		}
+		return apiErr
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expense-tracker/model"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_RequestsMatchAPI(t *testing.T) {
+	var gotAuth, gotQuery string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/login", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]string{"user_name": "alice", "password": "pw"}, body)
+		json.NewEncoder(w).Encode(map[string]string{"user": "alice", "user_id": "u1", "token": "tok"})
+	})
+	mux.HandleFunc("GET /api/v1/expenses/", func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotQuery = r.URL.RawQuery
+		json.NewEncoder(w).Encode(map[string]interface{}{"expenses": []model.Expense{{Id: "e1", Amount: 3}}})
+	})
+	mux.HandleFunc("GET /api/v1/expenses/{id}", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"Created Expense for user": "u1", "with expense": model.Expense{Id: r.PathValue("id")}})
+	})
+	mux.HandleFunc("GET /api/v1/expenses/summary", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]float64{"food": 12.5})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := New(srv.URL+"/", "")
+	resp, err := c.Login(ctx, "alice", "pw")
+	require.NoError(t, err)
+	assert.Equal(t, AuthResponse{User: "alice", UserID: "u1", Token: "tok"}, resp)
+
+	c.Token = resp.Token
+	expenses, err := c.ListExpenses(ctx, ListOptions{Category: "food", Limit: 5})
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer tok", gotAuth)
+	assert.Equal(t, "category=food&limit=5", gotQuery)
+	require.Len(t, expenses, 1)
+	assert.Equal(t, "e1", expenses[0].Id)
+
+	expense, err := c.GetExpense(ctx, "e2")
+	require.NoError(t, err)
+	assert.Equal(t, "e2", expense.Id)
+
+	summary, err := c.Summary(ctx, SummaryOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"food": 12.5}, summary)
+}
+
+func TestClient_APIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"type":"urn:expense-tracker:problem:invalid_credentials","title":"Unauthorized","status":401,` +
+			`"detail":"Invalid user name or password","code":"invalid_credentials","request_id":"req-1"}`))
+	}))
+	defer srv.Close()
+
+	_, err := New(srv.URL, "").Login(context.Background(), "alice", "bad")
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+	assert.Equal(t, "Invalid user name or password", apiErr.Message)
+	assert.Equal(t, "invalid_credentials", apiErr.Code)
+	assert.Equal(t, "req-1", apiErr.RequestID)
+}
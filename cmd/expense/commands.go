@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"expense-tracker/client"
+	"expense-tracker/model"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+func newFlagSet(a *app, name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	return fs
+}
+
+// parse parses args and maps flag errors, including -h, to errUsage.
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	return nil
+}
+
+func runSignUp(ctx context.Context, a *app, args []string) error {
+	return authenticate(ctx, a, "signup", args, a.client.SignUp)
+}
+
+func runLogin(ctx context.Context, a *app, args []string) error {
+	return authenticate(ctx, a, "login", args, a.client.Login)
+}
+
+func authenticate(ctx context.Context, a *app, name string, args []string,
+	call func(context.Context, string, string) (client.AuthResponse, error)) error {
+	fs := newFlagSet(a, name)
+	user := fs.String("user", "", "User name")
+	password := fs.String("password", "", "Password (prompted for when omitted; also EXPENSE_PASSWORD)")
+	code := fs.String("code", "", "Two-factor code, if enabled (prompted for when omitted)")
+	if err := parse(fs, args); err != nil || *user == "" {
+		return errUsage
+	}
+	if *password == "" {
+		*password = os.Getenv("EXPENSE_PASSWORD")
+	}
+	if *password == "" {
+		var err error
+		if *password, err = promptPassword(a); err != nil {
+			return err
+		}
+	}
+	resp, err := call(ctx, *user, *password)
+	if err != nil {
+		return err
+	}
+	if resp.TwoFactorRequired {
+		if *code == "" {
+			if *code, err = promptLine(a, "Authentication code: "); err != nil {
+				return err
+			}
+		}
+		if resp, err = a.client.LoginTwoFactor(ctx, resp.ChallengeToken, *code); err != nil {
+			return err
+		}
+	}
+	a.config.Token = resp.Token
+	a.config.UserID = resp.UserID
+	a.config.User = resp.User
+	if err := a.saveConfig(); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "Logged in as %s (%s)\n", resp.User, resp.UserID)
+	return nil
+}
+
+func promptPassword(a *app) (string, error) {
+	fmt.Fprint(a.stderr, "Password: ")
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		pw, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(a.stderr)
+		return string(pw), err
+	}
+	return readLine()
+}
+
+// promptLine asks for a value that may be echoed.
+func promptLine(a *app, prompt string) (string, error) {
+	fmt.Fprint(a.stderr, prompt)
+	return readLine()
+}
+
+func readLine() (string, error) {
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func runLogout(_ context.Context, a *app, args []string) error {
+	if len(args) > 0 {
+		return errUsage
+	}
+	a.config.Token, a.config.UserID, a.config.User = "", "", ""
+	return a.saveConfig()
+}
+
+// expenseFlags registers the editable expense fields on fs.
+type expenseFlags struct {
+	amount      float64
+	currency    string
+	category    string
+	description string
+	date        string
+}
+
+func (f *expenseFlags) register(fs *flag.FlagSet, defaultCurrency string) {
+	fs.Float64Var(&f.amount, "amount", 0, "Amount")
+	fs.StringVar(&f.currency, "currency", defaultCurrency, "Currency code")
+	fs.StringVar(&f.category, "category", "", "Category")
+	fs.StringVar(&f.description, "description", "", "Description")
+	fs.StringVar(&f.date, "date", "", "Date (YYYY-MM-DD or RFC 3339)")
+}
+
+func runAdd(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet(a, "add")
+	var f expenseFlags
+	f.register(fs, "USD")
+	if err := parse(fs, args); err != nil || fs.NArg() > 0 || f.amount == 0 || f.category == "" {
+		return errUsage
+	}
+	if err := a.requireLogin(); err != nil {
+		return err
+	}
+	expense := model.Expense{
+		Amount:      f.amount,
+		Currency:    f.currency,
+		Category:    f.category,
+		Description: f.description,
+		TimeStamp:   time.Now().UTC(),
+	}
+	if f.date != "" {
+		ts, err := parseDate(f.date)
+		if err != nil {
+			return err
+		}
+		expense.TimeStamp = ts
+	}
+	created, err := a.client.CreateExpense(ctx, expense)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(a.stdout, created.Id)
+	return nil
+}
+
+// runEdit fetches the expense and changes only the fields given as flags,
+// since the API replaces all editable fields on update.
+func runEdit(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet(a, "edit")
+	var f expenseFlags
+	f.register(fs, "")
+	if err := parse(fs, args); err != nil || fs.NArg() != 1 {
+		return errUsage
+	}
+	if err := a.requireLogin(); err != nil {
+		return err
+	}
+	id := fs.Arg(0)
+	expense, err := a.client.GetExpense(ctx, id)
+	if err != nil {
+		return err
+	}
+	var parseErr error
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "amount":
+			expense.Amount = f.amount
+		case "currency":
+			expense.Currency = f.currency
+		case "category":
+			expense.Category = f.category
+		case "description":
+			expense.Description = f.description
+		case "date":
+			expense.TimeStamp, parseErr = parseDate(f.date)
+		}
+	})
+	if parseErr != nil {
+		return parseErr
+	}
+	if _, err := a.client.UpdateExpense(ctx, id, expense); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "Updated %s\n", id)
+	return nil
+}
+
+func runGet(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet(a, "get")
+	format := fs.String("o", "table", "Output format: table, json or csv")
+	if err := parse(fs, args); err != nil || fs.NArg() != 1 {
+		return errUsage
+	}
+	if err := a.requireLogin(); err != nil {
+		return err
+	}
+	expense, err := a.client.GetExpense(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return writeExpenses(a.stdout, *format, []model.Expense{expense})
+}
+
+func runList(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet(a, "list")
+	var opts client.ListOptions
+	fs.StringVar(&opts.UserID, "user-id", "", "Only expenses of this user")
+	fs.StringVar(&opts.Category, "category", "", "Only this category")
+	fs.StringVar(&opts.Currency, "currency", "", "Only this currency")
+	fs.StringVar(&opts.From, "from", "", "Start date (YYYY-MM-DD)")
+	fs.StringVar(&opts.To, "to", "", "End date (YYYY-MM-DD)")
+	fs.IntVar(&opts.Limit, "limit", 10, "Page size")
+	fs.IntVar(&opts.Offset, "offset", 0, "Number of expenses to skip")
+	all := fs.Bool("all", false, "Fetch every page")
+	format := fs.String("o", "table", "Output format: table, json or csv")
+	if err := parse(fs, args); err != nil || fs.NArg() > 0 || opts.Limit <= 0 {
+		return errUsage
+	}
+	if err := a.requireLogin(); err != nil {
+		return err
+	}
+	var expenses []model.Expense
+	for {
+		page, err := a.client.ListExpenses(ctx, opts)
+		if err != nil {
+			return err
+		}
+		expenses = append(expenses, page...)
+		if !*all || len(page) < opts.Limit {
+			break
+		}
+		opts.Offset += len(page)
+	}
+	return writeExpenses(a.stdout, *format, expenses)
+}
+
+func runDelete(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet(a, "delete")
+	if err := parse(fs, args); err != nil || fs.NArg() == 0 {
+		return errUsage
+	}
+	if err := a.requireLogin(); err != nil {
+		return err
+	}
+	for _, id := range fs.Args() {
+		if err := a.client.DeleteExpense(ctx, id); err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+		fmt.Fprintf(a.stdout, "Deleted %s\n", id)
+	}
+	return nil
+}
+
+func runSummary(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet(a, "summary")
+	var opts client.SummaryOptions
+	fs.StringVar(&opts.UserID, "user-id", "", "Only expenses of this user")
+	fs.StringVar(&opts.From, "from", "", "Start date (YYYY-MM-DD)")
+	fs.StringVar(&opts.To, "to", "", "End date (YYYY-MM-DD)")
+	format := fs.String("o", "table", "Output format: table, json or csv")
+	if err := parse(fs, args); err != nil || fs.NArg() > 0 {
+		return errUsage
+	}
+	if err := a.requireLogin(); err != nil {
+		return err
+	}
+	summary, err := a.client.Summary(ctx, opts)
+	if err != nil {
+		return err
+	}
+	return writeSummary(a.stdout, *format, summary)
+}
+
+func runImport(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet(a, "import")
+	format := fs.String("format", "", "File format: csv or json (default from the file extension)")
+	dryRun := fs.Bool("dry-run", false, "Parse the file and print the expenses without creating them")
+	if err := parse(fs, args); err != nil || fs.NArg() != 1 {
+		return errUsage
+	}
+	path := fs.Arg(0)
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	expenses, err := readExpenses(file, importFormat(path, *format))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if *dryRun {
+		return writeExpenses(a.stdout, "table", expenses)
+	}
+	if err := a.requireLogin(); err != nil {
+		return err
+	}
+	for i, expense := range expenses {
+		if _, err := a.client.CreateExpense(ctx, expense); err != nil {
+			return fmt.Errorf("record %d: %w (%d of %d imported)", i+1, err, i, len(expenses))
+		}
+	}
+	fmt.Fprintf(a.stdout, "Imported %d expenses\n", len(expenses))
+	return nil
+}
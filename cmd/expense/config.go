@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"expense-tracker/client"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// defaultServer is used when neither -server, EXPENSE_SERVER nor the config
+// file name a server.
+const defaultServer = "http://localhost:8080"
+
+// cliConfig is persisted between runs so that login only happens once.
+type cliConfig struct {
+	Server string `json:"server"`
+	Token  string `json:"token,omitempty"`
+	UserID string `json:"user_id,omitempty"`
+	User   string `json:"user,omitempty"`
+}
+
+// app carries everything a command needs.
+type app struct {
+	configPath string
+	config     cliConfig
+	client     *client.Client
+	stdout     io.Writer
+	stderr     io.Writer
+}
+
+// newApp loads the config file and builds an API client. The server is taken
+// from the -server flag, then EXPENSE_SERVER, then the config file.
+func newApp(configPath, server string, stdout, stderr io.Writer) (*app, error) {
+	if configPath == "" {
+		configPath = os.Getenv("EXPENSE_CONFIG")
+	}
+	if configPath == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, err
+		}
+		configPath = filepath.Join(dir, "expense", "config.json")
+	}
+	cfg, err := readConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case server != "":
+		cfg.Server = server
+	case os.Getenv("EXPENSE_SERVER") != "":
+		cfg.Server = os.Getenv("EXPENSE_SERVER")
+	case cfg.Server == "":
+		cfg.Server = defaultServer
+	}
+	// A personal access token in the environment lets scripts skip login.
+	if token := os.Getenv("EXPENSE_TOKEN"); token != "" {
+		cfg.Token = token
+	}
+	return &app{
+		configPath: configPath,
+		config:     cfg,
+		client:     client.New(cfg.Server, cfg.Token),
+		stdout:     stdout,
+		stderr:     stderr,
+	}, nil
+}
+
+func readConfig(path string) (cliConfig, error) {
+	var cfg cliConfig
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// saveConfig writes the config file readable only by the current user since
+// it contains a bearer token.
+func (a *app) saveConfig() error {
+	if err := os.MkdirAll(filepath.Dir(a.configPath), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(a.config, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(a.configPath, append(data, '\n'), 0o600)
+}
+
+// requireLogin fails early with a helpful message instead of a 401.
+func (a *app) requireLogin() error {
+	if a.config.Token == "" {
+		return errors.New(`not logged in; run "expense login" first`)
+	}
+	return nil
+}
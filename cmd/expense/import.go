@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"expense-tracker/model"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// importRecord is one expense in an import file. JSON files may use either
+// "date" or the "TimeStamp" key written by "expense list -o json", so an
+// export can be imported again.
+type importRecord struct {
+	Amount      float64 `json:"amount"`
+	Currency    string  `json:"currency"`
+	Category    string  `json:"category"`
+	Description string  `json:"description"`
+	Date        string  `json:"date"`
+	TimeStamp   string  `json:"timestamp"`
+}
+
+// importFormat returns the explicit format or guesses it from the extension.
+func importFormat(path, format string) string {
+	if format != "" {
+		return strings.ToLower(format)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return "json"
+	}
+	return "csv"
+}
+
+// readExpenses parses a CSV file with a header row naming the columns
+// (amount, category, and optionally currency, description, date) or a JSON
+// array of objects with the same keys.
+func readExpenses(r io.Reader, format string) ([]model.Expense, error) {
+	var records []importRecord
+	switch format {
+	case "json":
+		if err := json.NewDecoder(r).Decode(&records); err != nil {
+			return nil, err
+		}
+	case "csv":
+		var err error
+		if records, err = readCSV(r); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown import format %q (want csv or json)", format)
+	}
+
+	expenses := make([]model.Expense, 0, len(records))
+	for i, rec := range records {
+		expense, err := rec.expense()
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", i+1, err)
+		}
+		expenses = append(expenses, expense)
+	}
+	return expenses, nil
+}
+
+func readCSV(r io.Reader) ([]importRecord, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["amount"]; !ok {
+		return nil, errors.New(`header has no "amount" column`)
+	}
+
+	var records []importRecord
+	for line := 2; ; line++ {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		get := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		amount, err := strconv.ParseFloat(get("amount"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid amount %q", line, get("amount"))
+		}
+		records = append(records, importRecord{
+			Amount:      amount,
+			Currency:    get("currency"),
+			Category:    get("category"),
+			Description: get("description"),
+			Date:        get("date"),
+			TimeStamp:   get("timestamp"),
+		})
+	}
+}
+
+func (rec importRecord) expense() (model.Expense, error) {
+	if rec.Amount == 0 {
+		return model.Expense{}, errors.New("amount is required")
+	}
+	if rec.Category == "" {
+		return model.Expense{}, errors.New("category is required")
+	}
+	expense := model.Expense{
+		Amount:      rec.Amount,
+		Currency:    rec.Currency,
+		Category:    rec.Category,
+		Description: rec.Description,
+		TimeStamp:   time.Now().UTC(),
+	}
+	if expense.Currency == "" {
+		expense.Currency = "USD"
+	}
+	date := rec.Date
+	if date == "" {
+		date = rec.TimeStamp
+	}
+	if date != "" {
+		ts, err := parseDate(date)
+		if err != nil {
+			return model.Expense{}, err
+		}
+		expense.TimeStamp = ts
+	}
+	return expense, nil
+}
+
+// parseDate accepts a plain date, interpreted as midnight UTC, or an RFC 3339
+// timestamp.
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q (want YYYY-MM-DD or RFC 3339)", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadExpenses_CSV(t *testing.T) {
+	in := "Date,Amount,Category,Description\n2024-03-01,12.50,food,lunch\n2024-03-02T10:00:00Z,3,transport,\n"
+	expenses, err := readExpenses(strings.NewReader(in), "csv")
+	require.NoError(t, err)
+	require.Len(t, expenses, 2)
+	assert.Equal(t, 12.5, expenses[0].Amount)
+	assert.Equal(t, "USD", expenses[0].Currency)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), expenses[0].TimeStamp)
+	assert.Equal(t, "transport", expenses[1].Category)
+
+	_, err = readExpenses(strings.NewReader("amount,category\nabc,food\n"), "csv")
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func TestReadExpenses_JSONRoundTrip(t *testing.T) {
+	in := `[{"amount": 4, "category": "food", "date": "2024-03-01"}]`
+	expenses, err := readExpenses(strings.NewReader(in), "json")
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, writeExpenses(&out, "json", expenses))
+	again, err := readExpenses(&out, "json")
+	require.NoError(t, err)
+	assert.Equal(t, expenses, again)
+}
+
+func TestWriteSummary_Formats(t *testing.T) {
+	summary := map[string]float64{"transport": 3, "food": 12.5}
+
+	var table bytes.Buffer
+	require.NoError(t, writeSummary(&table, "table", summary))
+	assert.Equal(t, "CATEGORY   TOTAL\nfood       12.50\ntransport  3.00\nTOTAL      15.50\n", table.String())
+
+	var csv bytes.Buffer
+	require.NoError(t, writeSummary(&csv, "csv", summary))
+	assert.Equal(t, "category,total\nfood,12.50\ntransport,3.00\n", csv.String())
+
+	assert.Error(t, writeSummary(&bytes.Buffer{}, "xml", summary))
+}
@@ -0,0 +1,102 @@
+// Command expense is a command-line client for the expense tracker API.
+//
+// Usage:
+//
+//	expense [-server URL] <command> [flags]
+//
+// Run "expense help" for the list of commands.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// command is one CLI subcommand.
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(ctx context.Context, app *app, args []string) error
+}
+
+var commands = []command{
+	{"signup", "signup -user NAME [-password PW]", "Create an account and store its token", runSignUp},
+	{"login", "login -user NAME [-password PW] [-code CODE]", "Log in and store the token", runLogin},
+	{"logout", "logout", "Forget the stored token", runLogout},
+	{"add", "add -amount N -category C [-currency USD] [-description D] [-date DATE]", "Create an expense", runAdd},
+	{"edit", "edit [flags] ID", "Change fields of an expense", runEdit},
+	{"get", "get [-o table|json|csv] ID", "Show one expense", runGet},
+	{"list", "list [filters] [-o table|json|csv]", "List expenses", runList},
+	{"delete", "delete ID...", "Delete expenses", runDelete},
+	{"summary", "summary [-from DATE] [-to DATE] [-o table|json|csv]", "Show totals per category", runSummary},
+	{"import", "import [-format csv|json] [-dry-run] FILE", "Create expenses from a CSV or JSON file", runImport},
+}
+
+// errUsage is returned by commands when their arguments are invalid; the
+// flag package has already printed the details.
+var errUsage = errors.New("usage")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("expense", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	server := fs.String("server", "", "API base URL (default from config file or EXPENSE_SERVER)")
+	configPath := fs.String("config", "", "Path of the CLI config file (default from EXPENSE_CONFIG)")
+	fs.Usage = func() { usage(stderr) }
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 || fs.Arg(0) == "help" {
+		usage(stdout)
+		return 0
+	}
+
+	app, err := newApp(*configPath, *server, stdout, stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "expense: %v\n", err)
+		return 1
+	}
+	name := fs.Arg(0)
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		err := cmd.run(ctx, app, fs.Args()[1:])
+		switch {
+		case err == nil:
+			return 0
+		case errors.Is(err, errUsage):
+			fmt.Fprintf(stderr, "usage: expense %s\n", cmd.usage)
+			return 2
+		default:
+			fmt.Fprintf(stderr, "expense %s: %v\n", name, err)
+			return 1
+		}
+	}
+	fmt.Fprintf(stderr, "expense: unknown command %q\n", name)
+	usage(stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: expense [-server URL] [-config FILE] <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "expense <command> -h" for the flags of a command.`)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"expense-tracker/model"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var expenseColumns = []string{"id", "date", "amount", "currency", "category", "description"}
+
+func expenseRow(e model.Expense) []string {
+	date := ""
+	if !e.TimeStamp.IsZero() {
+		date = e.TimeStamp.Format(time.DateOnly)
+	}
+	return []string{e.Id, date, strconv.FormatFloat(e.Amount, 'f', 2, 64), e.Currency, e.Category, e.Description}
+}
+
+// writeExpenses prints expenses as an aligned table, a JSON array or CSV
+// with a header row.
+func writeExpenses(w io.Writer, format string, expenses []model.Expense) error {
+	if expenses == nil {
+		expenses = []model.Expense{}
+	}
+	rows := make([][]string, len(expenses))
+	for i, e := range expenses {
+		rows[i] = expenseRow(e)
+	}
+	return write(w, format, expenses, expenseColumns, rows)
+}
+
+// writeSummary prints the per-category totals sorted by category, with a
+// final total row in table output.
+func writeSummary(w io.Writer, format string, summary map[string]float64) error {
+	names := make([]string, 0, len(summary))
+	for name := range summary {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	rows := make([][]string, 0, len(names)+1)
+	var total float64
+	for _, name := range names {
+		total += summary[name]
+		rows = append(rows, []string{name, strconv.FormatFloat(summary[name], 'f', 2, 64)})
+	}
+	if format == "table" {
+		rows = append(rows, []string{"TOTAL", strconv.FormatFloat(total, 'f', 2, 64)})
+	}
+	return write(w, format, summary, []string{"category", "total"}, rows)
+}
+
+func write(w io.Writer, format string, value interface{}, header []string, rows [][]string) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(value)
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(rows); err != nil {
+			return err
+		}
+		return cw.Error()
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		writeTabbed(tw, upper(header))
+		for _, row := range rows {
+			writeTabbed(tw, row)
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("unknown output format %q (want table, json or csv)", format)
+}
+
+func writeTabbed(w io.Writer, cells []string) {
+	for i, cell := range cells {
+		if i > 0 {
+			fmt.Fprint(w, "\t")
+		}
+		fmt.Fprint(w, cell)
+	}
+	fmt.Fprintln(w)
+}
+
+func upper(header []string) []string {
+	out := make([]string, len(header))
+	for i, h := range header {
+		out[i] = strings.ToUpper(h)
+	}
+	return out
+}
@@ -0,0 +1,35 @@
+// Command mock-oidc runs the oidctest provider for trying single sign-on
+// locally. Every authorization request signs in the user given by flags.
+//
+//	go run ./cmd/mock-oidc -addr :9999 -client-id tracker -user alice
+//	OIDC_ISSUER=http://localhost:9999 OIDC_CLIENT_ID=tracker \
+//	OIDC_REDIRECT_URL=http://localhost:8080/api/v1/auth/oidc/callback go run .
+package main
+
+import (
+	"expense-tracker/oidc/oidctest"
+	"flag"
+	"log"
+	"net/http"
+)
+
+func main() {
+	addr := flag.String("addr", ":9999", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9999", "issuer URL; must match how the service reaches this server")
+	clientID := flag.String("client-id", "tracker", "accepted client ID")
+	clientSecret := flag.String("client-secret", "", "required client secret (empty accepts public clients)")
+	subject := flag.String("sub", "mock-user-1", "subject of the signed-in user")
+	user := flag.String("user", "mockuser", "preferred_username of the signed-in user")
+	email := flag.String("email", "mockuser@example.com", "email of the signed-in user")
+	flag.Parse()
+
+	p := oidctest.NewProvider(*issuer, *clientID, *clientSecret)
+	p.SignInAs(map[string]interface{}{
+		"sub":                *subject,
+		"preferred_username": *user,
+		"email":              *email,
+		"email_verified":     true,
+	})
+	log.Printf("mock OIDC provider for %s listening on %s", *issuer, *addr)
+	log.Fatal(http.ListenAndServe(*addr, p))
+}
@@ -0,0 +1,557 @@
+// Package config loads the service configuration. Values are resolved in
+// increasing order of precedence: built-in defaults, the config file (YAML or
+// TOML), environment variables and finally command-line flags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	toml "github.com/pelletier/go-toml/v2"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the complete service configuration.
+type Config struct {
+	Server    Server    `yaml:"server" toml:"server"`
+	Database  Database  `yaml:"database" toml:"database"`
+	JWT       JWT       `yaml:"jwt" toml:"jwt"`
+	RateLimit RateLimit `yaml:"rate_limit" toml:"rate_limit"`
+	Log       Log       `yaml:"log" toml:"log"`
+	Tracing   Tracing   `yaml:"tracing" toml:"tracing"`
+	GraphQL   GraphQL   `yaml:"graphql" toml:"graphql"`
+	Password  Password  `yaml:"password" toml:"password"`
+	Admin     Admin     `yaml:"admin" toml:"admin"`
+	OIDC      OIDC      `yaml:"oidc" toml:"oidc"`
+	Login     Login     `yaml:"login" toml:"login"`
+	Currency  Currency  `yaml:"currency" toml:"currency"`
+}
+
+// Server configures the HTTP listener.
+type Server struct {
+	Addr string `yaml:"addr" toml:"addr"`
+	// GRPCAddr is the gRPC listen address; empty disables the gRPC server.
+	GRPCAddr        string   `yaml:"grpc_addr" toml:"grpc_addr"`
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// DrainDelay is how long /readyz reports failure before the listener
+	// stops, giving load balancers time to stop sending traffic.
+	DrainDelay         Duration `yaml:"drain_delay" toml:"drain_delay"`
+	HealthCheckTimeout Duration `yaml:"health_check_timeout" toml:"health_check_timeout"`
+}
+
+// Database configures the SQL connection.
+type Database struct {
+	// Driver is postgres or sqlite.
+	Driver string `yaml:"driver" toml:"driver"`
+	// URL is a PostgreSQL DSN, or a SQLite file path such as expense.db or
+	// ":memory:".
+	URL string `yaml:"url" toml:"url"`
+}
+
+// JWT configures token signing.
+type JWT struct {
+	// Secret signs tokens with HS256. With a signing key set as well, it is
+	// only used to verify tokens issued before the switch.
+	Secret string   `yaml:"secret" toml:"secret"`
+	TTL    Duration `yaml:"ttl" toml:"ttl"`
+	// SigningKeyFile is a PEM RSA or Ed25519 private key; tokens are then
+	// signed with RS256 or EdDSA and published at /.well-known/jwks.json.
+	SigningKeyFile string `yaml:"signing_key_file" toml:"signing_key_file"`
+	// VerificationKeyFiles are PEM keys that no longer sign but whose
+	// tokens are still accepted, for rotating the signing key.
+	VerificationKeyFiles []string `yaml:"verification_key_files" toml:"verification_key_files"`
+	Issuer               string   `yaml:"issuer" toml:"issuer"`
+	Audience             string   `yaml:"audience" toml:"audience"`
+}
+
+// GroupLimit holds the limits applied to one route group. Rates use the
+// limiter format, e.g. "10-M" for ten requests per minute.
+type GroupLimit struct {
+	// Anonymous applies to requests without an authenticated user and is keyed by client IP.
+	Anonymous string `yaml:"anonymous" toml:"anonymous"`
+	// Default applies to authenticated users whose tier has no explicit entry.
+	Default string `yaml:"default" toml:"default"`
+	// Tiers maps a user tier (e.g. "free", "premium") to its rate.
+	Tiers map[string]string `yaml:"tiers" toml:"tiers"`
+}
+
+// RateLimit describes the rate limit store and the per-group limits.
+type RateLimit struct {
+	// Store is either "memory" (per process) or "redis" (shared across replicas).
+	Store    string                `yaml:"store" toml:"store"`
+	RedisURL string                `yaml:"redis_url" toml:"redis_url"`
+	Prefix   string                `yaml:"prefix" toml:"prefix"`
+	Groups   map[string]GroupLimit `yaml:"groups" toml:"groups"`
+}
+
+// Currency configures the exchange rates used to report amounts recorded in
+// different currencies in a single one.
+type Currency struct {
+	// Base is the currency the rates are quoted in.
+	Base string `yaml:"base" toml:"base"`
+	// Rates maps a currency code to the value of one unit of it in Base,
+	// e.g. EUR: 1.08 with a USD base.
+	Rates map[string]float64 `yaml:"rates" toml:"rates"`
+}
+
+// Log configures the structured logger.
+type Log struct {
+	Level string `yaml:"level" toml:"level"`
+}
+
+// Tracing configures OpenTelemetry span export.
+type Tracing struct {
+	// Exporter is "none", "stdout" or "otlp" (OTLP over HTTP).
+	Exporter string `yaml:"exporter" toml:"exporter"`
+	// Endpoint is the OTLP collector URL, e.g. http://localhost:4318.
+	Endpoint    string  `yaml:"endpoint" toml:"endpoint"`
+	ServiceName string  `yaml:"service_name" toml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+}
+
+// GraphQL limits the cost of queries accepted on /graphql.
+type GraphQL struct {
+	MaxDepth      int `yaml:"max_depth" toml:"max_depth"`
+	MaxComplexity int `yaml:"max_complexity" toml:"max_complexity"`
+}
+
+// Password configures the password policy and the password reset flow.
+type Password struct {
+	MinLength int `yaml:"min_length" toml:"min_length"`
+	// BreachedList is a file of breached passwords, one per line, either in
+	// plain text or as SHA-1 hex in the Have I Been Pwned format (a trailing
+	// ":count" is ignored). Empty uses the built-in list of common passwords.
+	BreachedList string   `yaml:"breached_list" toml:"breached_list"`
+	ResetTTL     Duration `yaml:"reset_ttl" toml:"reset_ttl"`
+	// Notifier delivers reset tokens: "log" writes them to the service log,
+	// "file" appends them as JSON lines to NotifierFile.
+	Notifier     string `yaml:"notifier" toml:"notifier"`
+	NotifierFile string `yaml:"notifier_file" toml:"notifier_file"`
+}
+
+// Admin names the users allowed to call the /api/v1/admin endpoints.
+type Admin struct {
+	Users []string `yaml:"users" toml:"users"`
+}
+
+// OIDC configures single sign-on with an OpenID Connect provider. It is
+// disabled while Issuer is empty.
+type OIDC struct {
+	// Issuer is the provider's issuer URL; its discovery document is read
+	// from Issuer + "/.well-known/openid-configuration".
+	Issuer       string `yaml:"issuer" toml:"issuer"`
+	ClientID     string `yaml:"client_id" toml:"client_id"`
+	ClientSecret string `yaml:"client_secret" toml:"client_secret"`
+	// RedirectURL is this service's callback, ending in
+	// /api/v1/auth/oidc/callback, as registered with the provider.
+	RedirectURL string   `yaml:"redirect_url" toml:"redirect_url"`
+	Scopes      []string `yaml:"scopes" toml:"scopes"`
+	// UsernameClaim names the ID token claim new users are named after;
+	// "email" and then the subject are used when it is missing.
+	UsernameClaim string `yaml:"username_claim" toml:"username_claim"`
+	// AutoProvision creates an account on first sign-in. Without it only
+	// identities linked to an existing account can sign in.
+	AutoProvision bool `yaml:"auto_provision" toml:"auto_provision"`
+}
+
+// Enabled reports whether OIDC sign-in is configured.
+func (o OIDC) Enabled() bool {
+	return o.Issuer != ""
+}
+
+// Login configures brute-force protection for password and 2FA logins. After
+// a few consecutive failures for a user name each attempt must wait longer,
+// and every LockoutThreshold failures lock the name out, for twice as long
+// each time up to MaxLockout.
+type Login struct {
+	LockoutThreshold int      `yaml:"lockout_threshold" toml:"lockout_threshold"`
+	LockoutDuration  Duration `yaml:"lockout_duration" toml:"lockout_duration"`
+	MaxLockout       Duration `yaml:"max_lockout" toml:"max_lockout"`
+}
+
+// Duration is a time.Duration that decodes from strings such as "24h".
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler for YAML and TOML decoding.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Default returns the configuration used when nothing else is set. The JWT
+// secret is deliberately left empty so Validate forces it to be provided.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Addr:               ":8080",
+			GRPCAddr:           ":9090",
+			ShutdownTimeout:    Duration{5 * time.Second},
+			HealthCheckTimeout: Duration{2 * time.Second},
+		},
+		Database: Database{
+			Driver: "postgres",
+			URL:    "user=user password=password dbname=expense_tracker host=localhost port=5432 sslmode=disable",
+		},
+		JWT: JWT{
+			TTL:      Duration{24 * time.Hour},
+			Issuer:   "expense-tracker",
+			Audience: "expense-tracker",
+		},
+		RateLimit: RateLimit{
+			Store:  "memory",
+			Prefix: "expense-tracker-ratelimit",
+			Groups: map[string]GroupLimit{
+				"expenses": {Anonymous: "10-M", Default: "10-M"},
+				"auth":     {Anonymous: "20-M", Default: "20-M"},
+			},
+		},
+		Log: Log{Level: "info"},
+		Tracing: Tracing{
+			Exporter:    "none",
+			ServiceName: "expense-tracker",
+			SampleRatio: 1,
+		},
+		GraphQL: GraphQL{MaxDepth: 6, MaxComplexity: 1000},
+		Password: Password{
+			MinLength: 8,
+			ResetTTL:  Duration{time.Hour},
+			Notifier:  "log",
+		},
+		Login: Login{
+			LockoutThreshold: 5,
+			LockoutDuration:  Duration{15 * time.Minute},
+			MaxLockout:       Duration{24 * time.Hour},
+		},
+		Currency: Currency{Base: "USD"},
+		OIDC: OIDC{
+			Scopes:        []string{"openid", "profile", "email"},
+			UsernameClaim: "preferred_username",
+			AutoProvision: true,
+		},
+	}
+}
+
+// binding ties one setting to its environment variable and flag.
+type binding struct {
+	env   string
+	flag  string
+	usage string
+	set   func(*Config, string) error
+}
+
+func stringSetter(field func(*Config) *string) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		*field(c) = v
+		return nil
+	}
+}
+
+func durationSetter(field func(*Config) *Duration) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		return field(c).UnmarshalText([]byte(v))
+	}
+}
+
+func intSetter(field func(*Config) *int) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+func listSetter(field func(*Config) *[]string) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		var list []string
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*field(c) = list
+		return nil
+	}
+}
+
+// rateSetter parses comma-separated CODE=rate pairs, e.g. "EUR=1.08,GBP=1.27".
+func rateSetter(field func(*Config) *map[string]float64) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		rates := map[string]float64{}
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			code, rate, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("rate %q is not CODE=rate", item)
+			}
+			f, err := strconv.ParseFloat(strings.TrimSpace(rate), 64)
+			if err != nil {
+				return fmt.Errorf("rate %q: %w", item, err)
+			}
+			rates[strings.ToUpper(strings.TrimSpace(code))] = f
+		}
+		*field(c) = rates
+		return nil
+	}
+}
+
+func boolSetter(field func(*Config) *bool) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = b
+		return nil
+	}
+}
+
+func floatSetter(field func(*Config) *float64) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+		*field(c) = f
+		return nil
+	}
+}
+
+var bindings = []binding{
+	{"HTTP_ADDR", "addr", "HTTP listen address", stringSetter(func(c *Config) *string { return &c.Server.Addr })},
+	{"GRPC_ADDR", "grpc-addr", "gRPC listen address (empty disables gRPC)", stringSetter(func(c *Config) *string { return &c.Server.GRPCAddr })},
+	{"SHUTDOWN_TIMEOUT", "shutdown-timeout", "graceful shutdown timeout", durationSetter(func(c *Config) *Duration { return &c.Server.ShutdownTimeout })},
+	{"DRAIN_DELAY", "drain-delay", "time readiness fails before shutdown starts", durationSetter(func(c *Config) *Duration { return &c.Server.DrainDelay })},
+	{"DATABASE_DRIVER", "database-driver", "database driver: postgres or sqlite", stringSetter(func(c *Config) *string { return &c.Database.Driver })},
+	{"DATABASE_URL", "database-url", "PostgreSQL DSN or SQLite file", stringSetter(func(c *Config) *string { return &c.Database.URL })},
+	{"JWT_SECRET", "jwt-secret", "secret used to sign JWTs", stringSetter(func(c *Config) *string { return &c.JWT.Secret })},
+	{"JWT_TTL", "jwt-ttl", "lifetime of issued JWTs", durationSetter(func(c *Config) *Duration { return &c.JWT.TTL })},
+	{"JWT_SIGNING_KEY_FILE", "jwt-signing-key-file", "PEM RSA or Ed25519 private key that signs JWTs", stringSetter(func(c *Config) *string { return &c.JWT.SigningKeyFile })},
+	{"JWT_VERIFICATION_KEY_FILES", "jwt-verification-key-files", "comma-separated PEM keys of retired signing keys", listSetter(func(c *Config) *[]string { return &c.JWT.VerificationKeyFiles })},
+	{"JWT_ISSUER", "jwt-issuer", "iss claim of issued JWTs", stringSetter(func(c *Config) *string { return &c.JWT.Issuer })},
+	{"JWT_AUDIENCE", "jwt-audience", "aud claim of issued JWTs", stringSetter(func(c *Config) *string { return &c.JWT.Audience })},
+	{"RATE_LIMIT_STORE", "rate-limit-store", "rate limit store: memory or redis", stringSetter(func(c *Config) *string { return &c.RateLimit.Store })},
+	{"REDIS_URL", "redis-url", "Redis URL for the rate limit store", stringSetter(func(c *Config) *string { return &c.RateLimit.RedisURL })},
+	{"LOG_LEVEL", "log-level", "log level: debug, info, warn or error", stringSetter(func(c *Config) *string { return &c.Log.Level })},
+	{"TRACING_EXPORTER", "tracing-exporter", "trace exporter: none, stdout or otlp", stringSetter(func(c *Config) *string { return &c.Tracing.Exporter })},
+	{"OTEL_EXPORTER_OTLP_ENDPOINT", "tracing-endpoint", "OTLP collector URL", stringSetter(func(c *Config) *string { return &c.Tracing.Endpoint })},
+	{"OTEL_SERVICE_NAME", "tracing-service-name", "service name reported in traces", stringSetter(func(c *Config) *string { return &c.Tracing.ServiceName })},
+	{"TRACING_SAMPLE_RATIO", "tracing-sample-ratio", "fraction of new traces to sample", floatSetter(func(c *Config) *float64 { return &c.Tracing.SampleRatio })},
+	{"PASSWORD_MIN_LENGTH", "password-min-length", "minimum password length", intSetter(func(c *Config) *int { return &c.Password.MinLength })},
+	{"BREACHED_PASSWORDS_FILE", "breached-passwords-file", "file of breached passwords to reject", stringSetter(func(c *Config) *string { return &c.Password.BreachedList })},
+	{"PASSWORD_RESET_TTL", "password-reset-ttl", "lifetime of password reset tokens", durationSetter(func(c *Config) *Duration { return &c.Password.ResetTTL })},
+	{"RESET_NOTIFIER", "reset-notifier", "reset token delivery: log or file", stringSetter(func(c *Config) *string { return &c.Password.Notifier })},
+	{"RESET_NOTIFIER_FILE", "reset-notifier-file", "file the file notifier appends to", stringSetter(func(c *Config) *string { return &c.Password.NotifierFile })},
+	{"ADMIN_USERS", "admin-users", "comma-separated user names with admin access", listSetter(func(c *Config) *[]string { return &c.Admin.Users })},
+	{"OIDC_ISSUER", "oidc-issuer", "OpenID Connect issuer URL (empty disables SSO)", stringSetter(func(c *Config) *string { return &c.OIDC.Issuer })},
+	{"OIDC_CLIENT_ID", "oidc-client-id", "OpenID Connect client ID", stringSetter(func(c *Config) *string { return &c.OIDC.ClientID })},
+	{"OIDC_CLIENT_SECRET", "oidc-client-secret", "OpenID Connect client secret", stringSetter(func(c *Config) *string { return &c.OIDC.ClientSecret })},
+	{"OIDC_REDIRECT_URL", "oidc-redirect-url", "OpenID Connect callback URL", stringSetter(func(c *Config) *string { return &c.OIDC.RedirectURL })},
+	{"OIDC_AUTO_PROVISION", "oidc-auto-provision", "create accounts on first OIDC sign-in", boolSetter(func(c *Config) *bool { return &c.OIDC.AutoProvision })},
+	{"LOGIN_LOCKOUT_THRESHOLD", "login-lockout-threshold", "failed logins before a user name is locked", intSetter(func(c *Config) *int { return &c.Login.LockoutThreshold })},
+	{"LOGIN_LOCKOUT_DURATION", "login-lockout-duration", "length of the first login lockout", durationSetter(func(c *Config) *Duration { return &c.Login.LockoutDuration })},
+	{"CURRENCY_BASE", "currency-base", "currency the exchange rates are quoted in", stringSetter(func(c *Config) *string { return &c.Currency.Base })},
+	{"CURRENCY_RATES", "currency-rates", "comma-separated CODE=rate exchange rates against the base currency", rateSetter(func(c *Config) *map[string]float64 { return &c.Currency.Rates })},
+}
+
+// Load resolves the configuration from the optional file named by -config or
+// CONFIG_FILE, the environment and args (typically os.Args[1:]), then validates it.
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("expense-tracker", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file")
+	flagValues := make(map[string]*string, len(bindings))
+	for _, b := range bindings {
+		flagValues[b.flag] = fs.String(b.flag, "", b.usage+" (env "+b.env+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+	if *configFile != "" {
+		if err := loadFile(&cfg, *configFile); err != nil {
+			return nil, err
+		}
+	}
+	for _, b := range bindings {
+		if v, ok := os.LookupEnv(b.env); ok && v != "" {
+			if err := b.set(&cfg, v); err != nil {
+				return nil, fmt.Errorf("env %s: %w", b.env, err)
+			}
+		}
+	}
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		for _, b := range bindings {
+			if b.flag == f.Name && flagErr == nil {
+				if err := b.set(&cfg, *flagValues[b.flag]); err != nil {
+					flagErr = fmt.Errorf("flag -%s: %w", b.flag, err)
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, flagErr
+	}
+
+	cfg.normalize()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, cfg)
+	case ".toml":
+		err = toml.Unmarshal(data, cfg)
+	default:
+		return fmt.Errorf("unsupported config file type %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// normalize puts settings that may be written several ways into the one
+// form the rest of the service compares against: currency codes are upper
+// case whichever source they came from.
+func (c *Config) normalize() {
+	c.Currency.Base = strings.ToUpper(strings.TrimSpace(c.Currency.Base))
+	if len(c.Currency.Rates) > 0 {
+		rates := make(map[string]float64, len(c.Currency.Rates))
+		for code, rate := range c.Currency.Rates {
+			rates[strings.ToUpper(strings.TrimSpace(code))] = rate
+		}
+		c.Currency.Rates = rates
+	}
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Server.Addr == "" {
+		errs = append(errs, errors.New("server.addr must not be empty"))
+	}
+	if c.Server.ShutdownTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("server.shutdown_timeout must be positive"))
+	}
+	if c.Server.DrainDelay.Duration < 0 {
+		errs = append(errs, errors.New("server.drain_delay must not be negative"))
+	}
+	if c.Server.HealthCheckTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("server.health_check_timeout must be positive"))
+	}
+	switch c.Database.Driver {
+	case "postgres", "sqlite":
+	default:
+		errs = append(errs, fmt.Errorf("database.driver %q must be postgres or sqlite", c.Database.Driver))
+	}
+	if c.Database.URL == "" {
+		errs = append(errs, errors.New("database.url must not be empty"))
+	}
+	if c.JWT.Secret == "" && c.JWT.SigningKeyFile == "" {
+		errs = append(errs, errors.New("jwt.secret must not be empty unless jwt.signing_key_file is set"))
+	}
+	if c.JWT.Issuer == "" || c.JWT.Audience == "" {
+		errs = append(errs, errors.New("jwt.issuer and jwt.audience must not be empty"))
+	}
+	if c.JWT.TTL.Duration <= 0 {
+		errs = append(errs, errors.New("jwt.ttl must be positive"))
+	}
+	if _, err := log.ParseLevel(c.Log.Level); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: %w", err))
+	}
+	switch c.RateLimit.Store {
+	case "memory":
+	case "redis":
+		if c.RateLimit.RedisURL == "" {
+			errs = append(errs, errors.New("rate_limit.redis_url is required for the redis store"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("rate_limit.store %q must be memory or redis", c.RateLimit.Store))
+	}
+	switch c.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter %q must be none, stdout or otlp", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing.sample_ratio must be between 0 and 1"))
+	}
+	if c.GraphQL.MaxDepth <= 0 || c.GraphQL.MaxComplexity <= 0 {
+		errs = append(errs, errors.New("graphql.max_depth and graphql.max_complexity must be positive"))
+	}
+	// bcrypt ignores everything after 72 bytes.
+	if c.Password.MinLength < 1 || c.Password.MinLength > 72 {
+		errs = append(errs, errors.New("password.min_length must be between 1 and 72"))
+	}
+	if c.Password.ResetTTL.Duration <= 0 {
+		errs = append(errs, errors.New("password.reset_ttl must be positive"))
+	}
+	switch c.Password.Notifier {
+	case "log":
+	case "file":
+		if c.Password.NotifierFile == "" {
+			errs = append(errs, errors.New("password.notifier_file is required for the file notifier"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("password.notifier %q must be log or file", c.Password.Notifier))
+	}
+	if c.Login.LockoutThreshold < 1 {
+		errs = append(errs, errors.New("login.lockout_threshold must be positive"))
+	}
+	if c.Login.LockoutDuration.Duration <= 0 || c.Login.MaxLockout.Duration < c.Login.LockoutDuration.Duration {
+		errs = append(errs, errors.New("login.lockout_duration must be positive and at most login.max_lockout"))
+	}
+	if len(c.Currency.Base) != 3 {
+		errs = append(errs, fmt.Errorf("currency.base %q must be a three-letter currency code", c.Currency.Base))
+	}
+	for code, rate := range c.Currency.Rates {
+		if len(code) != 3 || rate <= 0 {
+			errs = append(errs, fmt.Errorf("currency.rates: %s must be a three-letter code with a positive rate", code))
+		}
+	}
+	if c.OIDC.Enabled() {
+		if c.OIDC.ClientID == "" || c.OIDC.RedirectURL == "" {
+			errs = append(errs, errors.New("oidc.client_id and oidc.redirect_url are required when oidc.issuer is set"))
+		}
+		if !slices.Contains(c.OIDC.Scopes, "openid") {
+			errs = append(errs, errors.New("oidc.scopes must include openid"))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
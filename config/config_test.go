@@ -0,0 +1,130 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_Precedence(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+server:
+  addr: ":9000"
+jwt:
+  secret: from-file
+  ttl: 1h
+rate_limit:
+  groups:
+    expenses:
+      default: 5-M
+`), 0o600))
+
+	t.Setenv("JWT_SECRET", "from-env")
+	t.Setenv("HTTP_ADDR", ":9100")
+
+	cfg, err := Load([]string{"-config", path, "-addr", ":9200"})
+	require.NoError(t, err)
+	assert.Equal(t, ":9200", cfg.Server.Addr, "flag beats env and file")
+	assert.Equal(t, "from-env", cfg.JWT.Secret, "env beats file")
+	assert.Equal(t, time.Hour, cfg.JWT.TTL.Duration, "file beats default")
+	assert.Equal(t, "5-M", cfg.RateLimit.Groups["expenses"].Default)
+	assert.Equal(t, 5*time.Second, cfg.Server.ShutdownTimeout.Duration, "default kept")
+}
+
+func TestLoad_TOML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+[jwt]
+secret = "toml-secret"
+ttl = "2h"
+`), 0o600))
+	t.Setenv("JWT_SECRET", "")
+
+	cfg, err := Load([]string{"-config", path})
+	require.NoError(t, err)
+	assert.Equal(t, "toml-secret", cfg.JWT.Secret)
+	assert.Equal(t, 2*time.Hour, cfg.JWT.TTL.Duration)
+}
+
+func TestLoad_RejectsEmptySecret(t *testing.T) {
+	t.Setenv("JWT_SECRET", "")
+	_, err := Load(nil)
+	assert.ErrorContains(t, err, "jwt.secret")
+}
+
+func TestLoad_PasswordSettings(t *testing.T) {
+	t.Setenv("JWT_SECRET", "s")
+	t.Setenv("PASSWORD_MIN_LENGTH", "12")
+	t.Setenv("RESET_NOTIFIER", "file")
+
+	_, err := Load(nil)
+	assert.ErrorContains(t, err, "password.notifier_file")
+
+	cfg, err := Load([]string{"-reset-notifier-file", "/tmp/resets.jsonl", "-password-reset-ttl", "15m"})
+	require.NoError(t, err)
+	assert.Equal(t, 12, cfg.Password.MinLength)
+	assert.Equal(t, 15*time.Minute, cfg.Password.ResetTTL.Duration)
+
+	t.Setenv("PASSWORD_MIN_LENGTH", "100")
+	_, err = Load([]string{"-reset-notifier-file", "/tmp/resets.jsonl"})
+	assert.ErrorContains(t, err, "password.min_length")
+}
+
+func TestLoad_OIDCSettings(t *testing.T) {
+	t.Setenv("JWT_SECRET", "s")
+	t.Setenv("OIDC_ISSUER", "https://idp.example.com")
+
+	_, err := Load(nil)
+	assert.ErrorContains(t, err, "oidc.client_id")
+
+	cfg, err := Load([]string{"-oidc-client-id", "tracker", "-oidc-redirect-url", "http://localhost:8080/api/v1/auth/oidc/callback", "-oidc-auto-provision=false"})
+	require.NoError(t, err)
+	assert.True(t, cfg.OIDC.Enabled())
+	assert.Equal(t, "tracker", cfg.OIDC.ClientID)
+	assert.False(t, cfg.OIDC.AutoProvision)
+	assert.Equal(t, []string{"openid", "profile", "email"}, cfg.OIDC.Scopes)
+}
+
+func TestLoad_JWTSigningKeys(t *testing.T) {
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("JWT_SIGNING_KEY_FILE", "/etc/tracker/jwt.pem")
+	t.Setenv("JWT_VERIFICATION_KEY_FILES", "/etc/tracker/old.pem, /etc/tracker/older.pem")
+
+	cfg, err := Load(nil)
+	require.NoError(t, err, "a signing key replaces the secret")
+	assert.Equal(t, "/etc/tracker/jwt.pem", cfg.JWT.SigningKeyFile)
+	assert.Equal(t, []string{"/etc/tracker/old.pem", "/etc/tracker/older.pem"}, cfg.JWT.VerificationKeyFiles)
+	assert.Equal(t, "expense-tracker", cfg.JWT.Issuer)
+
+	_, err = Load([]string{"-jwt-audience", ""})
+	assert.ErrorContains(t, err, "jwt.audience")
+}
+
+func TestLoad_CurrencyRates(t *testing.T) {
+	t.Setenv("JWT_SECRET", "s")
+	t.Setenv("CURRENCY_RATES", "eur=1.08, GBP=1.27")
+
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "USD", cfg.Currency.Base)
+	assert.Equal(t, map[string]float64{"EUR": 1.08, "GBP": 1.27}, cfg.Currency.Rates)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("currency:\n  base: usd\n  rates:\n    eur: 1.08\n"), 0o600))
+	t.Setenv("CURRENCY_RATES", "")
+	cfg, err = Load([]string{"-config", path})
+	require.NoError(t, err)
+	assert.Equal(t, "USD", cfg.Currency.Base)
+	assert.Equal(t, map[string]float64{"EUR": 1.08}, cfg.Currency.Rates, "file codes are upper-cased like env ones")
+
+	_, err = Load([]string{"-currency-rates", "EUR=0"})
+	assert.ErrorContains(t, err, "currency.rates")
+	_, err = Load([]string{"-currency-rates", "EUR"})
+	assert.ErrorContains(t, err, "CODE=rate")
+}
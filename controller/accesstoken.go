@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"errors"
+	"expense-tracker/logging"
+	"expense-tracker/problem"
+	"expense-tracker/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateAccessToken godoc
+// @Summary      Create a personal access token
+// @Description  Issue a named token for scripts, usable as a bearer token with the given scopes (expenses:read, expenses:write, households:read, households:write) until it expires or is revoked. The token is only returned here.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        token  body      object  true  "Name, scopes and optional RFC 3339 expiry"  example({"name":"backup script","scopes":["expenses:read"],"expires_at":"2026-01-01T00:00:00Z"})
+// @Success      201    {object}  map[string]interface{}
+// @Failure      400    {object}  problem.Problem
+// @Failure      500    {object}  problem.Problem
+// @Router       /api/v1/auth/tokens [post]
+// @Security     BearerAuth
+func CreateAccessToken(c *gin.Context) {
+	var req struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fail(c, errInvalidPayload)
+		return
+	}
+	logger := logging.FromContext(c)
+	token, secret, err := service.CreateAccessToken(c.Request.Context(), c.GetString("user_id"), req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	logger.WithField("token_id", token.Id).Info("Created access token")
+	c.JSON(http.StatusCreated, gin.H{"access_token": token, "token": secret})
+}
+
+// ListAccessTokens godoc
+// @Summary      List personal access tokens
+// @Description  List the caller's access tokens with their scopes, expiry and last use. Token values are never returned.
+// @Tags         users
+// @Produce      json
+// @Success      200  {object}  []model.AccessToken
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/auth/tokens [get]
+// @Security     BearerAuth
+func ListAccessTokens(c *gin.Context) {
+	tokens, err := service.ListAccessTokens(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"access_tokens": tokens})
+}
+
+// RevokeAccessToken godoc
+// @Summary      Revoke a personal access token
+// @Description  Delete one of the caller's access tokens; it stops working immediately
+// @Tags         users
+// @Produce      json
+// @Param        id   path      string  true  "Access token ID"
+// @Success      200  {object}  map[string]string
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/auth/tokens/{id} [delete]
+// @Security     BearerAuth
+func RevokeAccessToken(c *gin.Context) {
+	logger := logging.FromContext(c)
+	err := service.RevokeAccessToken(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if errors.Is(err, service.ErrNotFound) {
+		fail(c, problem.NotFound("Access token not found"))
+		return
+	}
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	logger.WithField("token_id", c.Param("id")).Info("Revoked access token")
+	c.JSON(http.StatusOK, gin.H{"message": "Access token revoked"})
+}
@@ -0,0 +1,125 @@
+package controller_test
+
+import (
+	"context"
+	"expense-tracker/auth"
+	"expense-tracker/model"
+	"expense-tracker/service"
+	"expense-tracker/testutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createAccessToken issues a token through the API and returns it with its
+// metadata.
+func createAccessToken(t *testing.T, r http.Handler, session string, body interface{}) (model.AccessToken, string) {
+	t.Helper()
+	w := testutil.Do(t, r, http.MethodPost, "/api/v1/auth/tokens", session, body)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var resp struct {
+		AccessToken model.AccessToken `json:"access_token"`
+		Token       string
+	}
+	testutil.Decode(t, w, &resp)
+	return resp.AccessToken, resp.Token
+}
+
+func TestAccessTokens_Scopes(t *testing.T) {
+	r := testutil.Router(t)
+	alice := testutil.User().Create(t)
+	session := testutil.Token(t, alice)
+
+	meta, reader := createAccessToken(t, r, session, map[string]interface{}{
+		"name": "backup", "scopes": []string{auth.ScopeExpensesRead},
+	})
+	assert.Equal(t, "backup", meta.Name)
+	assert.Equal(t, []string{auth.ScopeExpensesRead}, meta.Scopes)
+	assert.Contains(t, reader, meta.Prefix)
+	assert.Nil(t, meta.LastUsedAt)
+
+	expense := map[string]interface{}{"amount": 5, "currency": "USD", "category": "food", "timeStamp": "2025-07-01T12:00:00Z"}
+	w := testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/", reader, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/expenses/", reader, expense)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/households/", reader, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	_, writer := createAccessToken(t, r, session, map[string]interface{}{
+		"name": "importer", "scopes": []string{auth.ScopeExpensesWrite},
+	})
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/expenses/", writer, expense)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	// Account management needs a real session.
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/auth/tokens", reader, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/auth/tokens", writer, map[string]interface{}{
+		"name": "escalate", "scopes": auth.Scopes,
+	})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/auth/tokens", session, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), reader)
+	var list struct {
+		AccessTokens []model.AccessToken `json:"access_tokens"`
+	}
+	testutil.Decode(t, w, &list)
+	require.Len(t, list.AccessTokens, 2)
+	assert.Equal(t, "backup", list.AccessTokens[0].Name)
+	assert.NotNil(t, list.AccessTokens[0].LastUsedAt)
+}
+
+func TestAccessTokens_RevokeAndExpiry(t *testing.T) {
+	r := testutil.Router(t)
+	alice := testutil.User().Create(t)
+	bob := testutil.User().Create(t)
+	session := testutil.Token(t, alice)
+
+	meta, token := createAccessToken(t, r, session, map[string]interface{}{
+		"name": "ci", "scopes": []string{auth.ScopeExpensesRead},
+	})
+	path := "/api/v1/auth/tokens/" + meta.Id
+	w := testutil.Do(t, r, http.MethodDelete, path, testutil.Token(t, bob), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code, "only the owner can revoke a token")
+	w = testutil.Do(t, r, http.MethodDelete, path, session, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/", token, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	_, token = createAccessToken(t, r, session, map[string]interface{}{
+		"name": "short", "scopes": []string{auth.ScopeExpensesRead}, "expires_at": time.Now().Add(time.Hour),
+	})
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/", token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	tokens, err := service.AccessTokens.List(context.Background(), alice.UserId)
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	past := time.Now().Add(-time.Minute)
+	tokens[0].ExpiresAt = &past
+	require.NoError(t, service.AccessTokens.Delete(context.Background(), alice.UserId, tokens[0].Id))
+	require.NoError(t, service.AccessTokens.Create(context.Background(), tokens[0]))
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/", token, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAccessTokens_Validation(t *testing.T) {
+	r := testutil.Router(t)
+	session := testutil.Token(t, testutil.User().Create(t))
+	for name, body := range map[string]interface{}{
+		"no name":       map[string]interface{}{"scopes": []string{auth.ScopeExpensesRead}},
+		"no scopes":     map[string]interface{}{"name": "x"},
+		"unknown scope": map[string]interface{}{"name": "x", "scopes": []string{"admin"}},
+		"expired":       map[string]interface{}{"name": "x", "scopes": []string{auth.ScopeExpensesRead}, "expires_at": time.Now().Add(-time.Hour)},
+	} {
+		w := testutil.Do(t, r, http.MethodPost, "/api/v1/auth/tokens", session, body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+	}
+	w := testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/", auth.AccessTokenPrefix+"unknown", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
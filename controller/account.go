@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"expense-tracker/logging"
+	"expense-tracker/problem"
+	"expense-tracker/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// CreateAccount godoc
+// @Summary      Create an account
+// @Description  Create an account or wallet (checking, savings, credit_card, cash or other) with an opening balance. The currency defaults to USD.
+// @Tags         accounts
+// @Accept       json
+// @Produce      json
+// @Param        account  body      service.AccountInput  true  "Account data"
+// @Success      201      {object}  model.Account
+// @Failure      400      {object}  problem.Problem
+// @Failure      403      {object}  problem.Problem
+// @Failure      422      {object}  problem.Problem
+// @Router       /api/v1/accounts [post]
+// @Security     BearerAuth
+func CreateAccount(c *gin.Context) {
+	logger := logging.FromContext(c)
+
+	var input service.AccountInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Warnf("Unable to bind JSON: %v", err)
+		fail(c, errInvalidPayload)
+		return
+	}
+
+	account, err := service.CreateAccount(c.Request.Context(), c.GetString("user_id"), input)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	logger.WithFields(log.Fields{
+		"account_id":   account.Id,
+		"household_id": account.HouseholdId,
+	}).Info("Created account")
+	c.JSON(http.StatusCreated, gin.H{"account": account})
+}
+
+// ListAccounts godoc
+// @Summary      List accounts
+// @Description  List your personal accounts, or a household's, with their current balances
+// @Tags         accounts
+// @Produce      json
+// @Param        household_id  query     string  false  "Household ID (omit for personal accounts)"
+// @Success      200           {object}  []service.AccountBalance
+// @Failure      403           {object}  problem.Problem
+// @Router       /api/v1/accounts [get]
+// @Security     BearerAuth
+func ListAccounts(c *gin.Context) {
+	accounts, err := service.ListAccounts(c.Request.Context(), c.GetString("user_id"), c.Query("household_id"))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"accounts": accounts})
+}
+
+// GetAccount godoc
+// @Summary      Get an account balance
+// @Description  Get an account with its balance now, or at the end of the given time
+// @Tags         accounts
+// @Produce      json
+// @Param        id   path      string  true   "Account ID"
+// @Param        at   query     string  false  "Date (YYYY-MM-DD, end of day) or RFC 3339 time; default now"
+// @Success      200  {object}  service.AccountBalance
+// @Failure      400  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Router       /api/v1/accounts/{id} [get]
+// @Security     BearerAuth
+func GetAccount(c *gin.Context) {
+	var at time.Time
+	if s := c.Query("at"); s != "" {
+		var err error
+		if at, err = parseBalanceTime(s); err != nil {
+			fail(c, problem.BadRequest("at must be YYYY-MM-DD or an RFC 3339 time"))
+			return
+		}
+	}
+	balance, err := service.AccountBalanceAt(c.Request.Context(), c.GetString("user_id"), c.Param("id"), at)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, balance)
+}
+
+// parseBalanceTime reads a date as the end of that day, so the balance
+// includes everything recorded on it.
+func parseBalanceTime(s string) (time.Time, error) {
+	if d, err := time.Parse(time.DateOnly, s); err == nil {
+		return d.Add(24*time.Hour - time.Nanosecond), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// UpdateAccount godoc
+// @Summary      Update an account
+// @Description  Rename an account or change its kind or opening balance. The currency cannot change.
+// @Tags         accounts
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                true  "Account ID"
+// @Param        account  body      service.AccountInput  true  "Account data"
+// @Success      200      {object}  model.Account
+// @Failure      400      {object}  problem.Problem
+// @Failure      403      {object}  problem.Problem
+// @Failure      404      {object}  problem.Problem
+// @Failure      422      {object}  problem.Problem
+// @Router       /api/v1/accounts/{id} [put]
+// @Security     BearerAuth
+func UpdateAccount(c *gin.Context) {
+	logger := logging.FromContext(c)
+	id := c.Param("id")
+
+	var input service.AccountInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Warnf("Unable to bind JSON: %v", err)
+		fail(c, errInvalidPayload)
+		return
+	}
+
+	account, err := service.UpdateAccount(c.Request.Context(), c.GetString("user_id"), id, input)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	logger.WithField("account_id", id).Info("Updated account")
+	c.JSON(http.StatusOK, gin.H{"account": account})
+}
+
+// GetAccountHistory godoc
+// @Summary      Account history
+// @Description  List the income, expenses and transfers of an account with the running balance after each
+// @Tags         accounts
+// @Produce      json
+// @Param        id    path      string  true   "Account ID"
+// @Param        from  query     string  false  "Start date (YYYY-MM-DD)"
+// @Param        to    query     string  false  "End date (YYYY-MM-DD)"
+// @Success      200   {object}  service.AccountHistory
+// @Failure      400   {object}  problem.Problem
+// @Failure      403   {object}  problem.Problem
+// @Failure      404   {object}  problem.Problem
+// @Router       /api/v1/accounts/{id}/history [get]
+// @Security     BearerAuth
+func GetAccountHistory(c *gin.Context) {
+	filter := service.ExpenseFilter{From: c.Query("from"), To: c.Query("to")}
+	history, err := service.GetAccountHistory(c.Request.Context(), c.GetString("user_id"), c.Param("id"), filter)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
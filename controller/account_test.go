@@ -0,0 +1,168 @@
+package controller_test
+
+import (
+	"expense-tracker/config"
+	"expense-tracker/service"
+	"expense-tracker/testutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createAccount creates an account through the API and returns its ID.
+func createAccount(t *testing.T, r http.Handler, token string, body map[string]interface{}) string {
+	t.Helper()
+	w := testutil.Do(t, r, http.MethodPost, "/api/v1/accounts/", token, body)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created struct{ Account struct{ Id string } }
+	testutil.Decode(t, w, &created)
+	return created.Account.Id
+}
+
+func TestAccounts_RunningBalances(t *testing.T) {
+	r := testutil.Router(t)
+	token := testutil.Token(t, testutil.User().Create(t))
+	checking := createAccount(t, r, token, map[string]interface{}{"name": "Checking", "opening_balance": 1000})
+	savings := createAccount(t, r, token, map[string]interface{}{"name": "Savings", "kind": "savings"})
+
+	w := testutil.Do(t, r, http.MethodPost, "/api/v1/incomes/", token, map[string]interface{}{
+		"amount": 2500, "category": "salary", "account_id": checking, "timestamp": "2026-03-01T09:00:00Z"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/expenses/", token, map[string]interface{}{
+		"amount": 49.5, "category": "food", "account_id": checking, "timestamp": "2026-03-05T12:00:00Z"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/transfers/", token, map[string]interface{}{
+		"from_account_id": checking, "to_account_id": savings, "amount": 200, "timestamp": "2026-03-10T08:00:00Z"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var list struct{ Accounts []service.AccountBalance }
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/accounts/", token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	testutil.Decode(t, w, &list)
+	require.Len(t, list.Accounts, 2)
+	assert.Equal(t, "Checking", list.Accounts[0].Account.Name)
+	assert.Equal(t, 3250.5, list.Accounts[0].Balance)
+	assert.Equal(t, 200.0, list.Accounts[1].Balance)
+
+	var balance service.AccountBalance
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/accounts/"+checking+"?at=2026-03-05", token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	testutil.Decode(t, w, &balance)
+	assert.Equal(t, 3450.5, balance.Balance, "a date includes the whole day")
+
+	var history service.AccountHistory
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/accounts/"+checking+"/history?from=2026-03-02", token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	testutil.Decode(t, w, &history)
+	assert.Equal(t, 3500.0, history.OpeningBalance)
+	assert.Equal(t, 3250.5, history.ClosingBalance)
+	require.Len(t, history.Entries, 2)
+	assert.Equal(t, service.EntryExpense, history.Entries[0].Type)
+	assert.Equal(t, -49.5, history.Entries[0].Amount)
+	assert.Equal(t, service.EntryTransferOut, history.Entries[1].Type)
+
+	var summary map[string]float64
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/summary", token, nil)
+	testutil.Decode(t, w, &summary)
+	assert.Equal(t, map[string]float64{"food": 49.5}, summary, "transfers are not spending")
+}
+
+func TestAccounts_BalanceOfALargeAccount(t *testing.T) {
+	r := testutil.Router(t)
+	user := testutil.User().Create(t)
+	token := testutil.Token(t, user)
+	checking := createAccount(t, r, token, map[string]interface{}{"name": "Checking", "opening_balance": 100})
+	// More movements than the ledger reads at a time, several to a timestamp.
+	for i := range 1100 {
+		at := testutil.BaseTime.Add(time.Duration(i/3) * time.Minute)
+		if i%4 == 0 {
+			testutil.Income().Owner(user).Account(checking).Amount(3).At(at).Create(t)
+		} else {
+			testutil.Expense().Owner(user).Account(checking).Amount(1).At(at).Create(t)
+		}
+	}
+
+	var balance service.AccountBalance
+	w := testutil.Do(t, r, http.MethodGet, "/api/v1/accounts/"+checking, token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	testutil.Decode(t, w, &balance)
+	assert.Equal(t, 100.0+275*3-825, balance.Balance)
+
+	var history service.AccountHistory
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/accounts/"+checking+"/history", token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	testutil.Decode(t, w, &history)
+	assert.Len(t, history.Entries, 1100)
+	assert.Equal(t, balance.Balance, history.ClosingBalance)
+}
+
+func TestAccounts_LinkRules(t *testing.T) {
+	r := testutil.Router(t)
+	aliceToken := testutil.Token(t, testutil.User().Create(t))
+	bobToken := testutil.Token(t, testutil.User().Create(t))
+	euros := createAccount(t, r, aliceToken, map[string]interface{}{"name": "Wallet", "kind": "cash", "currency": "EUR"})
+
+	w := testutil.Do(t, r, http.MethodPost, "/api/v1/expenses/", aliceToken, map[string]interface{}{
+		"amount": 5, "category": "coffee", "account_id": euros})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created struct{ Expense struct{ Currency string } }
+	testutil.Decode(t, w, &created)
+	assert.Equal(t, "EUR", created.Expense.Currency, "the account currency is the default")
+
+	var body struct {
+		Errors []struct{ Field, Reason string }
+	}
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/expenses/", aliceToken, map[string]interface{}{
+		"amount": 5, "currency": "USD", "category": "coffee", "account_id": euros})
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	testutil.Decode(t, w, &body)
+	assert.Equal(t, "currency", body.Errors[0].Field)
+
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/expenses/", bobToken, map[string]interface{}{
+		"amount": 5, "currency": "EUR", "category": "coffee", "account_id": euros})
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	testutil.Decode(t, w, &body)
+	assert.Equal(t, "account_id", body.Errors[0].Field)
+
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/accounts/"+euros, bobToken, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code, "personal accounts are private")
+
+	w = testutil.Do(t, r, http.MethodPut, "/api/v1/accounts/"+euros, aliceToken, map[string]interface{}{
+		"name": "Wallet", "kind": "cash", "currency": "USD"})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestTransfers_ConvertBetweenCurrencies(t *testing.T) {
+	testutil.ConfigureService(t, func(c *service.Config) {
+		c.ExchangeRates = config.Currency{Base: "USD", Rates: map[string]float64{"EUR": 1.25}}
+	})
+
+	r := testutil.Router(t)
+	token := testutil.Token(t, testutil.User().Create(t))
+	dollars := createAccount(t, r, token, map[string]interface{}{"name": "Checking", "opening_balance": 500})
+	euros := createAccount(t, r, token, map[string]interface{}{"name": "Travel", "currency": "EUR"})
+
+	w := testutil.Do(t, r, http.MethodPost, "/api/v1/transfers/", token, map[string]interface{}{
+		"from_account_id": dollars, "to_account_id": euros, "amount": 100})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created struct{ Transfer struct{ Id string } }
+	testutil.Decode(t, w, &created)
+
+	var balance service.AccountBalance
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/accounts/"+euros, token, nil)
+	testutil.Decode(t, w, &balance)
+	assert.Equal(t, 80.0, balance.Balance)
+
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/transfers/", token, map[string]interface{}{
+		"from_account_id": dollars, "to_account_id": dollars, "amount": 1})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	w = testutil.Do(t, r, http.MethodDelete, "/api/v1/transfers/"+created.Transfer.Id, token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/accounts/"+dollars, token, nil)
+	testutil.Decode(t, w, &balance)
+	assert.Equal(t, 500.0, balance.Balance)
+}
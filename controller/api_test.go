@@ -0,0 +1,213 @@
+package controller_test
+
+import (
+	"expense-tracker/config"
+	"expense-tracker/model"
+	"expense-tracker/problem"
+	"expense-tracker/testutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPI_SignUpAndLogin(t *testing.T) {
+	r := testutil.Router(t)
+	creds := map[string]string{"user_name": "carol", "password": "s3cret-passphrase"}
+
+	w := testutil.Do(t, r, http.MethodPost, "/api/v1/signup", "", creds)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/login", "", creds)
+	require.Equal(t, http.StatusOK, w.Code)
+	var login map[string]string
+	testutil.Decode(t, w, &login)
+	assert.NotEmpty(t, login["token"])
+
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/login", "", map[string]string{"user_name": "carol", "password": "nope"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/login", "", map[string]string{"user_name": "dave", "password": "x"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAPI_ExpenseLifecycle(t *testing.T) {
+	r := testutil.Router(t)
+	alice := testutil.User().Name("alice").Create(t)
+	token := testutil.Token(t, alice)
+
+	w := testutil.Do(t, r, http.MethodPost, "/api/v1/expenses/", token,
+		map[string]interface{}{"amount": 20, "currency": "USD", "category": "food", "description": "pizza", "timeStamp": "2025-07-02T18:00:00Z"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Expense struct{ Id, User_id string }
+	}
+	testutil.Decode(t, w, &created)
+	assert.Equal(t, alice.UserId, created.Expense.User_id)
+	id := created.Expense.Id
+
+	w = testutil.Do(t, r, http.MethodPut, "/api/v1/expenses/"+id, token,
+		map[string]interface{}{"amount": 25, "currency": "USD", "category": "food", "description": "pizza", "timeStamp": "2025-07-02T18:00:00Z"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = testutil.Do(t, r, http.MethodPut, "/api/v1/expenses/missing", token, map[string]interface{}{"amount": 1, "category": "food"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/"+id, token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"Amount":25`)
+
+	w = testutil.Do(t, r, http.MethodDelete, "/api/v1/expenses/"+id, token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/"+id, token, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAPI_ExpenseValidation(t *testing.T) {
+	r := testutil.Router(t)
+	token := testutil.Token(t, testutil.User().Create(t))
+
+	w := testutil.Do(t, r, http.MethodPost, "/api/v1/expenses/", token, map[string]interface{}{
+		"amount": -3.5, "currency": "dollars", "category": " ",
+		"description": strings.Repeat("x", 257), "timestamp": "2099-01-01T00:00:00Z",
+	})
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	var resp struct {
+		Code   string
+		Errors []struct{ Field, Reason string }
+	}
+	testutil.Decode(t, w, &resp)
+	assert.Equal(t, "validation_failed", resp.Code)
+	fields := map[string]string{}
+	for _, f := range resp.Errors {
+		fields[f.Field] = f.Reason
+	}
+	assert.Equal(t, map[string]string{
+		"amount":      "must be greater than 0",
+		"currency":    "must be an ISO 4217 currency code",
+		"category":    "is required",
+		"description": "must be at most 256 characters",
+		"timestamp":   "is too far in the future",
+	}, fields)
+
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/expenses/", token, map[string]interface{}{"amount": 1000.5, "currency": "jpy", "category": "food"})
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "more decimal places than the currency allows")
+
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/expenses/", token, map[string]interface{}{"amount": 1000, "currency": "jpy", "category": "food"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created struct{ Expense model.Expense }
+	testutil.Decode(t, w, &created)
+	assert.Equal(t, "JPY", created.Expense.Currency)
+	assert.WithinDuration(t, time.Now(), created.Expense.TimeStamp, time.Minute, "the timestamp defaults to now")
+
+	w = testutil.Do(t, r, http.MethodPut, "/api/v1/expenses/"+created.Expense.Id, token, map[string]interface{}{"amount": 0, "category": "food"})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestAPI_ListAndSummary(t *testing.T) {
+	r := testutil.Router(t)
+	alice := testutil.User().Create(t)
+	bob := testutil.User().Create(t)
+	testutil.Expense().Owner(alice).Amount(10).Create(t)
+	testutil.Expense().Owner(alice).Amount(5).Category("transport").At(testutil.BaseTime.AddDate(0, 1, 0)).Create(t)
+	testutil.Expense().Owner(bob).Amount(99).Create(t)
+	token := testutil.Token(t, alice)
+
+	var list struct{ Expenses []struct{ Amount float64 } }
+	w := testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/?user_id="+alice.UserId+"&to=2025-07-31", token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	testutil.Decode(t, w, &list)
+	require.Len(t, list.Expenses, 1)
+	assert.Equal(t, 10.0, list.Expenses[0].Amount)
+
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/?limit=1&offset=1", token, nil)
+	testutil.Decode(t, w, &list)
+	require.Len(t, list.Expenses, 1, "personal lists default to the caller")
+	assert.Equal(t, 5.0, list.Expenses[0].Amount)
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/?offset=2", token, nil)
+	testutil.Decode(t, w, &list)
+	assert.Empty(t, list.Expenses)
+
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/?user_id="+bob.UserId, token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/summary?user_id="+bob.UserId, token, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	var summary map[string]float64
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/summary?user_id="+alice.UserId, token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	testutil.Decode(t, w, &summary)
+	assert.Equal(t, map[string]float64{"food": 10, "transport": 5}, summary)
+
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/summary?from=soon", token, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAPI_RequiresValidToken(t *testing.T) {
+	r := testutil.Router(t)
+	assert.Equal(t, http.StatusUnauthorized, testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/", "", nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/", "garbage", nil).Code)
+}
+
+func TestAPI_ProblemDetails(t *testing.T) {
+	r := testutil.Router(t)
+	token := testutil.Token(t, testutil.User().Create(t))
+	var p problem.Problem
+
+	for _, tc := range []struct {
+		method, path, token string
+		status              int
+		code                problem.Code
+	}{
+		{http.MethodGet, "/api/v1/expenses/", "", http.StatusUnauthorized, problem.CodeUnauthorized},
+		{http.MethodGet, "/api/v1/expenses/", "garbage", http.StatusUnauthorized, problem.CodeInvalidToken},
+		{http.MethodGet, "/api/v1/expenses/missing", token, http.StatusNotFound, problem.CodeNotFound},
+		{http.MethodGet, "/api/v1/expenses/summary?group_by=day", token, http.StatusBadRequest, problem.CodeBadRequest},
+		{http.MethodGet, "/api/v1/nowhere", token, http.StatusNotFound, problem.CodeNotFound},
+		{http.MethodPatch, "/api/v1/login", "", http.StatusMethodNotAllowed, problem.CodeBadRequest},
+	} {
+		w := testutil.Do(t, r, tc.method, tc.path, tc.token, nil)
+		assert.Equal(t, tc.status, w.Code, tc.path)
+		assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"), tc.path)
+		testutil.Decode(t, w, &p)
+		assert.Equal(t, tc.code, p.Code, tc.path)
+		assert.Equal(t, tc.status, p.Status, tc.path)
+		assert.Equal(t, w.Header().Get("X-Request-ID"), p.RequestID, tc.path)
+	}
+	// The store's own error text stays out of the response.
+	w := testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/missing", token, nil)
+	testutil.Decode(t, w, &p)
+	assert.Equal(t, "Expense not found", p.Detail)
+	assert.Equal(t, "/api/v1/expenses/missing", p.Instance)
+}
+
+func TestAPI_JWKS(t *testing.T) {
+	r := testutil.Router(t)
+	w := testutil.Do(t, r, http.MethodGet, "/.well-known/jwks.json", "", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"keys":[]}`, w.Body.String(), "the HMAC secret is never published")
+}
+
+func TestAPI_RateLimited(t *testing.T) {
+	r := testutil.RouterWithLimits(t, config.GroupLimit{Anonymous: "1-M", Default: "1-M"})
+	token := testutil.Token(t, testutil.User().Create(t))
+
+	assert.Equal(t, http.StatusOK, testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/", token, nil).Code)
+	w := testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/", token, nil)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+}
+
+func TestAPI_GraphQL(t *testing.T) {
+	r := testutil.Router(t)
+	alice := testutil.User().Name("alice").Create(t)
+	testutil.Expense().Owner(alice).Amount(3).At(time.Date(2025, 7, 4, 0, 0, 0, 0, time.UTC)).Create(t)
+
+	w := testutil.Do(t, r, http.MethodPost, "/graphql", testutil.Token(t, alice),
+		map[string]string{"query": "{ expenses { amount user { userName } } }"})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":{"expenses":[{"amount":3,"user":{"userName":"alice"}}]}}`, w.Body.String())
+}
@@ -1,51 +1,56 @@
 package controller
 
 import (
-	"expense-tracker/model"
-	"expense-tracker/postgresql"
+	"errors"
+	"expense-tracker/logging"
+	"expense-tracker/problem"
+	"expense-tracker/service"
 	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 )
 
 // CreateExpense godoc
 // @Summary      Create an expense
-// @Description  Create a new expense record
+// @Description  Create a new expense record. The currency defaults to USD and the timestamp to now.
 // @Tags         expenses
 // @Accept       json
 // @Produce      json
-// @Param        expense  body      model.Expense  true  "Expense data"
+// @Param        expense  body      service.ExpenseInput  true  "Expense data"
 // @Success      201      {object}  model.Expense
-// @Failure      400      {object}  map[string]string
-// @Failure      500      {object}  map[string]string
+// @Failure      400      {object}  problem.Problem
+// @Failure      403      {object}  problem.Problem
+// @Failure      422      {object}  problem.Problem
+// @Failure      500      {object}  problem.Problem
 // @Router       /api/v1/expenses [post]
 // @Security     BearerAuth
 func CreateExpense(c *gin.Context) {
+	logger := logging.FromContext(c)
 
-	var expense model.Expense
+	var input service.ExpenseInput
 
-	if err := c.ShouldBindJSON(&expense); err != nil {
-		log.Errorf("Unable to bind JSON, %v: ", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Warnf("Unable to bind JSON: %v", err)
+		fail(c, errInvalidPayload)
 		return
 	}
-	expense.Id = uuid.New().String()
-	expense.User_id = uuid.New().String()
 
 	// Save to DB
-	if err := postgresql.DB.Create(&expense).Error; err != nil {
-		log.Errorf("Failed to create expense: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create expense"})
+	expense, err := service.CreateExpense(c.Request.Context(), c.GetString("user_id"), input)
+	if errors.Is(err, service.ErrInvalidArgument) {
+		fail(c, problem.BadRequest("The expense can only be attributed to a household member"))
 		return
 	}
-	log.WithFields(log.Fields{
-		"user_id":    expense.User_id,
-		"expense_id": expense.Id,
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	logger.WithFields(log.Fields{
+		"expense_user_id": expense.User_id,
+		"expense_id":      expense.Id,
 	}).Info("Created expense")
-	log.Infof("Created Expense for user: %v with expense id: %v", expense.User_id, expense.Id)
 	c.JSON(http.StatusCreated, gin.H{"expense": expense})
 
 }
@@ -57,11 +62,13 @@ func CreateExpense(c *gin.Context) {
 // @Produce      json
 // @Param        id   path      string  true  "Expense ID"
 // @Success      200  {object}  model.Expense
-// @Failure      400  {object}  map[string]string
-// @Failure      404  {object}  map[string]string
+// @Failure      400  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
 // @Router       /api/v1/expenses/{id} [get]
 // @Security     BearerAuth
 func GetExpenseById(c *gin.Context) {
+	logger := logging.FromContext(c)
 
 	// var expID struct {
 	// 	expID string
@@ -74,19 +81,20 @@ func GetExpenseById(c *gin.Context) {
 	// 	return
 	// }
 
-	var expense model.Expense
-
 	id := c.Param("id")
 
-	log.Infof("Fetching expense with ID: %s", id)
-
-	if err := postgresql.DB.Where("Id=?", id).First(&expense).Error; err != nil {
-		log.Errorf("Failed to fetch expense from DB: expense ID=%s, Error=%v", id, err)
-		c.JSON(http.StatusBadRequest, gin.H{"Failed to fetch expense from DB": id, "expense ID": err})
+	logger.WithField("expense_id", id).Debug("Fetching expense")
 
+	expense, err := service.GetExpense(c.Request.Context(), id)
+	if errors.Is(err, service.ErrNotFound) {
+		fail(c, problem.NotFound("Expense not found"))
 		return
 	}
-	log.Infof("Created Expense for user: %v with expense id: %v", expense.User_id, expense.Id)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	logger.WithField("expense_id", expense.Id).Info("Fetched expense")
 	c.JSON(http.StatusOK, gin.H{"Created Expense for user": expense.User_id, "with expense": expense})
 
 }
@@ -97,49 +105,41 @@ func GetExpenseById(c *gin.Context) {
 // @Tags         expenses
 // @Accept       json
 // @Produce      json
-// @Param        id      path      string         true  "Expense ID"
-// @Param        expense body      model.Expense  true  "Expense data"
+// @Param        id      path      string                true  "Expense ID"
+// @Param        expense body      service.ExpenseInput  true  "Expense data"
 // @Success      200     {object}  model.Expense
-// @Failure      400     {object}  map[string]string
-// @Failure      404     {object}  map[string]string
+// @Failure      400     {object}  problem.Problem
+// @Failure      403     {object}  problem.Problem
+// @Failure      404     {object}  problem.Problem
+// @Failure      422     {object}  problem.Problem
 // @Router       /api/v1/expenses/{id} [put]
 // @Security     BearerAuth
 func UpdateExpense(c *gin.Context) {
+	logger := logging.FromContext(c)
 	id := c.Param("id")
 	if id == "" {
-		log.Error("Missing expense ID in request")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing expense ID"})
+		fail(c, problem.BadRequest("Missing expense ID"))
 		return
 	}
 
-	var updateData model.Expense
+	var updateData service.ExpenseInput
 	if err := c.ShouldBindJSON(&updateData); err != nil {
-		log.Errorf("Unable to bind JSON: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		logger.Warnf("Unable to bind JSON: %v", err)
+		fail(c, errInvalidPayload)
 		return
 	}
 
-	var expense model.Expense
-	if err := postgresql.DB.Where("id = ?", id).First(&expense).Error; err != nil {
-		log.Errorf("Expense not found: %v", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Expense not found"})
+	expense, err := service.UpdateExpense(c.Request.Context(), id, updateData)
+	if errors.Is(err, service.ErrNotFound) {
+		fail(c, problem.NotFound("Expense not found"))
 		return
 	}
-
-	// Update fields (except Id and User_id)
-	expense.Amount = updateData.Amount
-	expense.Currency = updateData.Currency
-	expense.Category = updateData.Category
-	expense.Description = updateData.Description
-	expense.TimeStamp = updateData.TimeStamp
-
-	if err := postgresql.DB.Save(&expense).Error; err != nil {
-		log.Errorf("Failed to update expense: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update expense"})
+	if err != nil {
+		fail(c, err)
 		return
 	}
 
-	log.Infof("Updated Expense with id: %v", id)
+	logger.WithField("expense_id", id).Info("Updated expense")
 	c.JSON(http.StatusOK, gin.H{"message": "Expense updated", "expense": expense})
 }
 
@@ -150,25 +150,25 @@ func UpdateExpense(c *gin.Context) {
 // @Produce      json
 // @Param        id   path      string  true  "Expense ID"
 // @Success      200  {object}  map[string]string
-// @Failure      400  {object}  map[string]string
-// @Failure      404  {object}  map[string]string
+// @Failure      400  {object}  problem.Problem
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
 // @Router       /api/v1/expenses/{id} [delete]
 // @Security     BearerAuth
 func DeleteExpense(c *gin.Context) {
+	logger := logging.FromContext(c)
 	id := c.Param("id")
 	if id == "" {
-		log.Error("Missing expense ID in request")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing expense ID"})
+		fail(c, problem.BadRequest("Missing expense ID"))
 		return
 	}
 
-	if err := postgresql.DB.Delete(&model.Expense{}, "id = ?", id).Error; err != nil {
-		log.Errorf("Failed to delete expense: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete expense"})
+	if err := service.DeleteExpense(c.Request.Context(), id); err != nil {
+		fail(c, err)
 		return
 	}
 
-	log.Infof("Deleted Expense with id: %v", id)
+	logger.WithField("expense_id", id).Info("Deleted expense")
 	c.JSON(http.StatusOK, gin.H{"message": "Expense deleted"})
 }
 
@@ -177,49 +177,45 @@ func DeleteExpense(c *gin.Context) {
 // @Description  List expenses with optional filters
 // @Tags         expenses
 // @Produce      json
-// @Param        user_id   query     string  false  "User ID"
-// @Param        category  query     string  false  "Category"
-// @Param        currency  query     string  false  "Currency"
-// @Param        from      query     string  false  "Start date (YYYY-MM-DD)"
-// @Param        to        query     string  false  "End date (YYYY-MM-DD)"
-// @Success      200       {object}  []model.Expense
-// @Failure      500       {object}  map[string]string
+// @Param        user_id       query     string  false  "User ID"
+// @Param        household_id  query     string  false  "Household ID (omit for personal expenses)"
+// @Param        category      query     string  false  "Category"
+// @Param        currency      query     string  false  "Currency"
+// @Param        account_id    query     string  false  "Account ID"
+// @Param        from          query     string  false  "Start date (YYYY-MM-DD)"
+// @Param        to            query     string  false  "End date (YYYY-MM-DD)"
+// @Success      200           {object}  []model.Expense
+// @Failure      400           {object}  problem.Problem
+// @Failure      403           {object}  problem.Problem
+// @Failure      500           {object}  problem.Problem
 // @Router       /api/v1/expenses [get]
 // @Security     BearerAuth
 func ListExpensesWithFilters(c *gin.Context) {
-	var expenses []model.Expense
-	query := postgresql.DB
-
-	// Filters: user_id, category, currency, from, to
-	if userID := c.Query("user_id"); userID != "" {
-		query = query.Where("user_id = ?", userID)
+	// Filters: user_id, household_id, category, currency, from, to
+	filter := service.ExpenseFilter{
+		UserID:      c.Query("user_id"),
+		HouseholdID: c.Query("household_id"),
+		Category:    c.Query("category"),
+		Currency:    c.Query("currency"),
+		AccountID:   c.Query("account_id"),
+		From:        c.Query("from"),
+		To:          c.Query("to"),
+		Limit:       service.DefaultPageSize,
 	}
-	if category := c.Query("category"); category != "" {
-		query = query.Where("category = ?", category)
-	}
-	if currency := c.Query("currency"); currency != "" {
-		query = query.Where("currency = ?", currency)
-	}
-	if from := c.Query("from"); from != "" {
-		query = query.Where("time_stamp >= ?", from)
-	}
-	if to := c.Query("to"); to != "" {
-		query = query.Where("time_stamp <= ?", to)
-	}
-
-	limit := 10
-	offset := 0
 	if l := c.Query("limit"); l != "" {
-		fmt.Sscanf(l, "%d", &limit)
+		fmt.Sscanf(l, "%d", &filter.Limit)
 	}
 	if o := c.Query("offset"); o != "" {
-		fmt.Sscanf(o, "%d", &offset)
+		fmt.Sscanf(o, "%d", &filter.Offset)
 	}
-	query = query.Limit(limit).Offset(offset)
 
-	if err := query.Find(&expenses).Error; err != nil {
-		log.Errorf("Failed to list expenses: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list expenses"})
+	expenses, err := service.ListExpenses(c.Request.Context(), filter)
+	if errors.Is(err, service.ErrInvalidArgument) {
+		fail(c, problem.BadRequest("Invalid date filter"))
+		return
+	}
+	if err != nil {
+		fail(c, err)
 		return
 	}
 
@@ -228,45 +224,84 @@ func ListExpensesWithFilters(c *gin.Context) {
 
 // Summary godoc
 // @Summary      Get expense summary
-// @Description  Get summary of expenses by category, with optional filters
+// @Description  Get summary of expenses by category (or by member with group_by=member), with optional filters
 // @Tags         expenses
 // @Produce      json
-// @Param        user_id   query     string  false  "User ID"
-// @Param        from      query     string  false  "Start date (YYYY-MM-DD)"
-// @Param        to        query     string  false  "End date (YYYY-MM-DD)"
-// @Success      200       {object}  map[string]float64
-// @Failure      500       {object}  map[string]string
+// @Param        user_id       query     string  false  "User ID"
+// @Param        household_id  query     string  false  "Household ID (omit for personal expenses)"
+// @Param        group_by      query     string  false  "category (default) or member"
+// @Param        from          query     string  false  "Start date (YYYY-MM-DD)"
+// @Param        to            query     string  false  "End date (YYYY-MM-DD)"
+// @Success      200           {object}  map[string]float64
+// @Failure      400           {object}  problem.Problem
+// @Failure      403           {object}  problem.Problem
+// @Failure      500           {object}  problem.Problem
 // @Router       /api/v1/expenses/summary [get]
 // @Security     BearerAuth
 func Summary(c *gin.Context) {
-	type Result struct {
-		Category string
-		Total    float64
+	// Optional filters
+	filter := service.ExpenseFilter{
+		UserID:      c.Query("user_id"),
+		HouseholdID: c.Query("household_id"),
+		From:        c.Query("from"),
+		To:          c.Query("to"),
 	}
-	var results []Result
-	query := postgresql.DB.Model(&model.Expense{})
 
-	// Optional filters
-	if userID := c.Query("user_id"); userID != "" {
-		query = query.Where("user_id = ?", userID)
+	summarize := service.SummarizeExpenses
+	switch c.DefaultQuery("group_by", "category") {
+	case "category":
+	case "member":
+		summarize = service.SummarizeByMember
+	default:
+		fail(c, problem.BadRequest("group_by must be category or member"))
+		return
 	}
-	if from := c.Query("from"); from != "" {
-		query = query.Where("time_stamp >= ?", from)
+
+	summary, err := summarize(c.Request.Context(), filter)
+	if errors.Is(err, service.ErrInvalidArgument) {
+		fail(c, problem.BadRequest("Invalid date filter"))
+		return
 	}
-	if to := c.Query("to"); to != "" {
-		query = query.Where("time_stamp <= ?", to)
+	if err != nil {
+		fail(c, err)
+		return
 	}
 
-	if err := query.Select("category, SUM(amount) as total").Group("category").Scan(&results).Error; err != nil {
-		log.Errorf("Failed to summarize expenses: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to summarize expenses"})
+	c.JSON(http.StatusOK, summary)
+}
+
+// maxSuggestions bounds the limit of SuggestCategory.
+const maxSuggestions = 10
+
+// SuggestCategory godoc
+// @Summary      Suggest a category
+// @Description  Rank the categories you have used by how well they fit a description, learned from the descriptions of your personal expenses. Confidences are between 0 and 1; the list is empty when the description shares no word with your history.
+// @Tags         expenses
+// @Produce      json
+// @Param        description  query     string  true   "Expense description"
+// @Param        limit        query     int     false  "Maximum number of suggestions (default 3, at most 10)"
+// @Success      200          {object}  []service.CategorySuggestion
+// @Failure      400          {object}  problem.Problem
+// @Router       /api/v1/expenses/suggest-category [get]
+// @Security     BearerAuth
+func SuggestCategory(c *gin.Context) {
+	description := c.Query("description")
+	if description == "" {
+		fail(c, problem.BadRequest("description is required"))
 		return
 	}
-
-	summary := make(map[string]float64)
-	for _, r := range results {
-		summary[r.Category] = r.Total
+	limit := 3
+	if l := c.Query("limit"); l != "" {
+		if _, err := fmt.Sscanf(l, "%d", &limit); err != nil || limit < 1 || limit > maxSuggestions {
+			fail(c, problem.BadRequest(fmt.Sprintf("limit must be between 1 and %d", maxSuggestions)))
+			return
+		}
 	}
 
-	c.JSON(http.StatusOK, summary)
+	suggestions, err := service.SuggestCategory(c.Request.Context(), c.GetString("user_id"), description, limit)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
 }
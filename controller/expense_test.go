@@ -13,14 +13,18 @@ func TestCreateExpense_BadRequest(t *testing.T) {
 	router := gin.Default()
 	router.POST("/api/v1/expenses", CreateExpense)
 
-	reqBody := []byte(`{}`) // Invalid payload
-	req, _ := http.NewRequest("POST", "/api/v1/expenses", bytes.NewBuffer(reqBody))
-	req.Header.Set("Content-Type", "application/json")
-	w := httptest.NewRecorder()
+	for body, want := range map[string]int{
+		`{"amount":`: http.StatusBadRequest,          // Malformed JSON
+		`{}`:         http.StatusUnprocessableEntity, // Missing fields
+	} {
+		req, _ := http.NewRequest("POST", "/api/v1/expenses", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
 
-	router.ServeHTTP(w, req)
+		router.ServeHTTP(w, req)
 
-	if w.Code != http.StatusBadRequest {
-		t.Errorf("Expected 400, got %d", w.Code)
+		if w.Code != want {
+			t.Errorf("%s: expected %d, got %d", body, want, w.Code)
+		}
 	}
 }
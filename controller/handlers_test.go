@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"expense-tracker/auth"
+	"expense-tracker/config"
+	"expense-tracker/postgresql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// forEachBackend runs fn against an empty SQLite database and, when
+// TEST_DATABASE_URL is set, an emptied PostgreSQL database.
+func forEachBackend(t *testing.T, fn func(t *testing.T)) {
+	backends := []config.Database{{Driver: "sqlite", URL: ":memory:"}}
+	if url := os.Getenv("TEST_DATABASE_URL"); url != "" {
+		backends = append(backends, config.Database{Driver: "postgres", URL: url})
+	}
+	for _, backend := range backends {
+		t.Run(backend.Driver, func(t *testing.T) {
+			db, err := postgresql.Open(backend)
+			require.NoError(t, err)
+			require.NoError(t, db.Exec("DELETE FROM expenses").Error)
+			require.NoError(t, db.Exec("DELETE FROM users").Error)
+
+			previous := postgresql.DB
+			postgresql.DB = db
+			t.Cleanup(func() {
+				postgresql.DB = previous
+				if sqlDB, err := db.DB(); err == nil {
+					sqlDB.Close()
+				}
+			})
+			fn(t)
+		})
+	}
+}
+
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	auth.Configure(config.JWT{Secret: "test-secret", TTL: config.Duration{Duration: time.Hour}})
+	r := gin.New()
+	r.POST("/api/v1/signup", SignUp)
+	r.POST("/api/v1/login", Login)
+	expenses := r.Group("/api/v1/expenses", auth.JWTAuthMiddleware())
+	expenses.POST("/", CreateExpense)
+	expenses.GET("/:id", GetExpenseById)
+	expenses.PUT("/:id", UpdateExpense)
+	expenses.DELETE("/:id", DeleteExpense)
+	expenses.GET("/", ListExpensesWithFilters)
+	expenses.GET("/summary", Summary)
+	return r
+}
+
+func do(t *testing.T, r http.Handler, method, path, token string, body interface{}, out interface{}) int {
+	t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if out != nil {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), out), w.Body.String())
+	}
+	return w.Code
+}
+
+func TestUserHandlers(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		r := newTestRouter()
+		creds := map[string]string{"user_name": "alice", "password": "s3cret-passphrase"}
+
+		var signup map[string]string
+		require.Equal(t, http.StatusCreated, do(t, r, http.MethodPost, "/api/v1/signup", "", creds, &signup))
+		assert.NotEmpty(t, signup["token"])
+		assert.Equal(t, http.StatusInternalServerError, do(t, r, http.MethodPost, "/api/v1/signup", "", creds, nil))
+
+		var login map[string]string
+		require.Equal(t, http.StatusOK, do(t, r, http.MethodPost, "/api/v1/login", "", creds, &login))
+		assert.Equal(t, signup["user_id"], login["user_id"])
+
+		bad := map[string]string{"user_name": "alice", "password": "wrong"}
+		assert.Equal(t, http.StatusUnauthorized, do(t, r, http.MethodPost, "/api/v1/login", "", bad, nil))
+	})
+}
+
+func TestExpenseHandlers(t *testing.T) {
+	forEachBackend(t, func(t *testing.T) {
+		r := newTestRouter()
+		var signup map[string]string
+		require.Equal(t, http.StatusCreated, do(t, r, http.MethodPost, "/api/v1/signup", "",
+			map[string]string{"user_name": "bob", "password": "s3cret-passphrase"}, &signup))
+		token := signup["token"]
+
+		create := func(amount float64, category, ts string) string {
+			var resp struct{ Expense struct{ Id string } }
+			body := map[string]interface{}{"amount": amount, "currency": "USD", "category": category, "description": "d", "timeStamp": ts}
+			require.Equal(t, http.StatusCreated, do(t, r, http.MethodPost, "/api/v1/expenses/", token, body, &resp))
+			return resp.Expense.Id
+		}
+		lunch := create(12.5, "food", "2025-07-01T12:00:00Z")
+		create(7.5, "food", "2025-07-15T09:30:00+02:00")
+		create(30, "transport", "2025-08-02T08:00:00Z")
+
+		var got map[string]json.RawMessage
+		require.Equal(t, http.StatusOK, do(t, r, http.MethodGet, "/api/v1/expenses/"+lunch, token, nil, &got))
+		assert.Contains(t, string(got["with expense"]), `"Amount":12.5`)
+
+		var list struct{ Expenses []struct{ Id string } }
+		require.Equal(t, http.StatusOK, do(t, r, http.MethodGet, "/api/v1/expenses/?from=2025-07-01&to=2025-08-01", token, nil, &list))
+		assert.Len(t, list.Expenses, 2)
+		assert.Equal(t, lunch, list.Expenses[0].Id)
+
+		require.Equal(t, http.StatusOK, do(t, r, http.MethodGet, "/api/v1/expenses/?from=2025-07-15T07:30:00Z", token, nil, &list))
+		assert.Len(t, list.Expenses, 2, "RFC 3339 bounds are compared in UTC")
+
+		assert.Equal(t, http.StatusBadRequest, do(t, r, http.MethodGet, "/api/v1/expenses/?from=July", token, nil, nil))
+
+		var summary map[string]float64
+		require.Equal(t, http.StatusOK, do(t, r, http.MethodGet, "/api/v1/expenses/summary?user_id="+signup["user_id"], token, nil, &summary))
+		assert.Equal(t, map[string]float64{"food": 20, "transport": 30}, summary)
+
+		update := map[string]interface{}{"amount": 15, "currency": "EUR", "category": "food", "description": "dinner", "timeStamp": "2025-07-01T19:00:00Z"}
+		assert.Equal(t, http.StatusOK, do(t, r, http.MethodPut, "/api/v1/expenses/"+lunch, token, update, nil))
+		summary = nil
+		require.Equal(t, http.StatusOK, do(t, r, http.MethodGet, "/api/v1/expenses/summary?from=2025-07-01&to=2025-07-31", token, nil, &summary))
+		assert.Equal(t, map[string]float64{"food": 22.5}, summary)
+
+		assert.Equal(t, http.StatusOK, do(t, r, http.MethodDelete, "/api/v1/expenses/"+lunch, token, nil, nil))
+		assert.Equal(t, http.StatusNotFound, do(t, r, http.MethodGet, "/api/v1/expenses/"+lunch, token, nil, nil))
+		assert.Equal(t, http.StatusUnauthorized, do(t, r, http.MethodGet, "/api/v1/expenses/", "", nil, nil))
+	})
+}
@@ -0,0 +1,200 @@
+package controller
+
+import (
+	"expense-tracker/logging"
+	"expense-tracker/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// CreateHousehold godoc
+// @Summary      Create a household
+// @Description  Create a shared ledger owned by the current user
+// @Tags         households
+// @Accept       json
+// @Produce      json
+// @Param        household  body      object  true  "Household"  example({"name":"Flat 3B"})
+// @Success      201        {object}  model.Household
+// @Failure      400        {object}  problem.Problem
+// @Failure      500        {object}  problem.Problem
+// @Router       /api/v1/households [post]
+// @Security     BearerAuth
+func CreateHousehold(c *gin.Context) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fail(c, errInvalidPayload)
+		return
+	}
+	household, err := service.CreateHousehold(c.Request.Context(), c.GetString("user_id"), req.Name)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	logging.FromContext(c).WithField("household_id", household.Id).Info("Created household")
+	c.JSON(http.StatusCreated, gin.H{"household": household})
+}
+
+// ListHouseholds godoc
+// @Summary      List households
+// @Description  List the households the current user belongs to, with their role in each
+// @Tags         households
+// @Produce      json
+// @Success      200  {object}  map[string]interface{}
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/households [get]
+// @Security     BearerAuth
+func ListHouseholds(c *gin.Context) {
+	memberships, err := service.ListHouseholds(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	households := make([]gin.H, 0, len(memberships))
+	for _, m := range memberships {
+		households = append(households, gin.H{"household": m.Household, "role": m.Role})
+	}
+	c.JSON(http.StatusOK, gin.H{"households": households})
+}
+
+// GetHousehold godoc
+// @Summary      Get a household
+// @Description  Get a household and its members; any member may call this
+// @Tags         households
+// @Produce      json
+// @Param        id   path      string  true  "Household ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/households/{id} [get]
+// @Security     BearerAuth
+func GetHousehold(c *gin.Context) {
+	household, members, err := service.GetHousehold(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"household": household, "members": members})
+}
+
+// CreateHouseholdInvite godoc
+// @Summary      Invite to a household
+// @Description  Create a single-use invite code that adds its holder as an editor or viewer (owner only)
+// @Tags         households
+// @Accept       json
+// @Produce      json
+// @Param        id      path      string  true  "Household ID"
+// @Param        invite  body      object  true  "Role for the invitee"  example({"role":"editor"})
+// @Success      201     {object}  model.HouseholdInvite
+// @Failure      400     {object}  problem.Problem
+// @Failure      403     {object}  problem.Problem
+// @Failure      500     {object}  problem.Problem
+// @Router       /api/v1/households/{id}/invites [post]
+// @Security     BearerAuth
+func CreateHouseholdInvite(c *gin.Context) {
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fail(c, errInvalidPayload)
+		return
+	}
+	invite, err := service.CreateInvite(c.Request.Context(), c.GetString("user_id"), c.Param("id"), req.Role)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"invite": invite})
+}
+
+// JoinHousehold godoc
+// @Summary      Join a household
+// @Description  Redeem an invite code; the code is consumed
+// @Tags         households
+// @Accept       json
+// @Produce      json
+// @Param        invite  body      object  true  "Invite code"  example({"code":"ABCDE23456"})
+// @Success      200     {object}  model.HouseholdMember
+// @Failure      400     {object}  problem.Problem
+// @Failure      404     {object}  problem.Problem
+// @Failure      409     {object}  problem.Problem
+// @Failure      500     {object}  problem.Problem
+// @Router       /api/v1/households/join [post]
+// @Security     BearerAuth
+func JoinHousehold(c *gin.Context) {
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fail(c, errInvalidPayload)
+		return
+	}
+	member, err := service.JoinHousehold(c.Request.Context(), c.GetString("user_id"), req.Code)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	logging.FromContext(c).WithFields(log.Fields{
+		"household_id": member.HouseholdId,
+		"role":         member.Role,
+	}).Info("Joined household")
+	c.JSON(http.StatusOK, gin.H{"member": member})
+}
+
+// UpdateHouseholdMember godoc
+// @Summary      Change a member's role
+// @Description  Make a member an editor or viewer (owner only)
+// @Tags         households
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string  true  "Household ID"
+// @Param        user_id  path      string  true  "Member user ID"
+// @Param        member   body      object  true  "New role"  example({"role":"viewer"})
+// @Success      200      {object}  model.HouseholdMember
+// @Failure      400      {object}  problem.Problem
+// @Failure      403      {object}  problem.Problem
+// @Failure      404      {object}  problem.Problem
+// @Failure      500      {object}  problem.Problem
+// @Router       /api/v1/households/{id}/members/{user_id} [put]
+// @Security     BearerAuth
+func UpdateHouseholdMember(c *gin.Context) {
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fail(c, errInvalidPayload)
+		return
+	}
+	member, err := service.SetMemberRole(c.Request.Context(), c.GetString("user_id"), c.Param("id"), c.Param("user_id"), req.Role)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"member": member})
+}
+
+// RemoveHouseholdMember godoc
+// @Summary      Remove a member
+// @Description  The owner may remove any other member; any other member may remove themselves to leave
+// @Tags         households
+// @Produce      json
+// @Param        id       path      string  true  "Household ID"
+// @Param        user_id  path      string  true  "Member user ID"
+// @Success      200      {object}  map[string]string
+// @Failure      403      {object}  problem.Problem
+// @Failure      404      {object}  problem.Problem
+// @Failure      500      {object}  problem.Problem
+// @Router       /api/v1/households/{id}/members/{user_id} [delete]
+// @Security     BearerAuth
+func RemoveHouseholdMember(c *gin.Context) {
+	err := service.RemoveMember(c.Request.Context(), c.GetString("user_id"), c.Param("id"), c.Param("user_id"))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed"})
+}
@@ -0,0 +1,205 @@
+package controller_test
+
+import (
+	"expense-tracker/service"
+	"expense-tracker/testutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHouseholds_InviteAndJoin(t *testing.T) {
+	r := testutil.Router(t)
+	alice := testutil.User().Create(t)
+	bob := testutil.User().Create(t)
+	aliceToken, bobToken := testutil.Token(t, alice), testutil.Token(t, bob)
+
+	w := testutil.Do(t, r, http.MethodPost, "/api/v1/households/", aliceToken, map[string]string{"name": "Flat 3B"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Household struct{ Id, Name, OwnerId string }
+	}
+	testutil.Decode(t, w, &created)
+	assert.Equal(t, alice.UserId, created.Household.OwnerId)
+	base := "/api/v1/households/" + created.Household.Id
+
+	w = testutil.Do(t, r, http.MethodGet, base, bobToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = testutil.Do(t, r, http.MethodPost, base+"/invites", bobToken, map[string]string{"role": "editor"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = testutil.Do(t, r, http.MethodPost, base+"/invites", aliceToken, map[string]string{"role": "owner"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = testutil.Do(t, r, http.MethodPost, base+"/invites", aliceToken, map[string]string{"role": "editor"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var invite struct{ Invite struct{ Code string } }
+	testutil.Decode(t, w, &invite)
+	require.Len(t, invite.Invite.Code, 10)
+
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/households/join", bobToken, map[string]string{"code": invite.Invite.Code})
+	require.Equal(t, http.StatusOK, w.Code)
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/households/join", bobToken, map[string]string{"code": invite.Invite.Code})
+	assert.Equal(t, http.StatusNotFound, w.Code, "invite codes are single use")
+
+	w = testutil.Do(t, r, http.MethodPost, base+"/invites", aliceToken, map[string]string{"role": "viewer"})
+	testutil.Decode(t, w, &invite)
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/households/join", bobToken, map[string]string{"code": invite.Invite.Code})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var list struct {
+		Households []struct {
+			Household struct{ Name string }
+			Role      string
+		}
+	}
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/households/", bobToken, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	testutil.Decode(t, w, &list)
+	require.Len(t, list.Households, 1)
+	assert.Equal(t, "Flat 3B", list.Households[0].Household.Name)
+	assert.Equal(t, service.RoleEditor, list.Households[0].Role)
+
+	var detail struct {
+		Members []struct{ UserId, Role string }
+	}
+	w = testutil.Do(t, r, http.MethodGet, base, bobToken, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	testutil.Decode(t, w, &detail)
+	assert.Len(t, detail.Members, 2)
+}
+
+func TestHouseholds_MemberManagement(t *testing.T) {
+	r := testutil.Router(t)
+	alice := testutil.User().Create(t)
+	bob := testutil.User().Create(t)
+	carol := testutil.User().Create(t)
+	h := testutil.Household(alice).Member(bob, service.RoleEditor).Member(carol, service.RoleViewer).Create(t)
+	aliceToken, bobToken, carolToken := testutil.Token(t, alice), testutil.Token(t, bob), testutil.Token(t, carol)
+	base := "/api/v1/households/" + h.Id + "/members/"
+
+	w := testutil.Do(t, r, http.MethodPut, base+carol.UserId, bobToken, map[string]string{"role": "editor"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = testutil.Do(t, r, http.MethodPut, base+alice.UserId, aliceToken, map[string]string{"role": "viewer"})
+	assert.Equal(t, http.StatusForbidden, w.Code, "the owner cannot demote themselves")
+	w = testutil.Do(t, r, http.MethodPut, base+"nobody", aliceToken, map[string]string{"role": "viewer"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = testutil.Do(t, r, http.MethodPut, base+bob.UserId, aliceToken, map[string]string{"role": "viewer"})
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/expenses/", bobToken,
+		map[string]interface{}{"household_id": h.Id, "amount": 5, "currency": "USD", "category": "food"})
+	assert.Equal(t, http.StatusForbidden, w.Code, "viewers cannot record expenses")
+
+	w = testutil.Do(t, r, http.MethodDelete, base+bob.UserId, carolToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = testutil.Do(t, r, http.MethodDelete, base+alice.UserId, aliceToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code, "the owner cannot leave")
+	w = testutil.Do(t, r, http.MethodDelete, base+carol.UserId, carolToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = testutil.Do(t, r, http.MethodDelete, base+bob.UserId, aliceToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/households/"+h.Id, bobToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestHouseholds_SharedLedger(t *testing.T) {
+	r := testutil.Router(t)
+	alice := testutil.User().Create(t)
+	bob := testutil.User().Create(t)
+	carol := testutil.User().Create(t)
+	mallory := testutil.User().Create(t)
+	h := testutil.Household(alice).Member(bob, service.RoleEditor).Member(carol, service.RoleViewer).Create(t)
+	testutil.Expense().Owner(alice).In(h).Amount(30).Category("groceries").Create(t)
+	testutil.Expense().Owner(alice).Amount(7).Category("groceries").Create(t)
+	aliceToken, bobToken := testutil.Token(t, alice), testutil.Token(t, bob)
+	carolToken, malloryToken := testutil.Token(t, carol), testutil.Token(t, mallory)
+
+	// An editor can record an expense and attribute it to another member.
+	w := testutil.Do(t, r, http.MethodPost, "/api/v1/expenses/", bobToken,
+		map[string]interface{}{"household_id": h.Id, "amount": 12, "currency": "USD", "category": "groceries"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/expenses/", bobToken,
+		map[string]interface{}{"household_id": h.Id, "user_id": carol.UserId, "amount": 8, "currency": "USD", "category": "utilities"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created struct{ Expense struct{ Id string } }
+	testutil.Decode(t, w, &created)
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/expenses/", bobToken,
+		map[string]interface{}{"household_id": h.Id, "user_id": mallory.UserId, "amount": 1, "currency": "USD", "category": "food"})
+	assert.Equal(t, http.StatusBadRequest, w.Code, "expenses can only be attributed to members")
+
+	// Viewers can read the ledger but not change it.
+	var list struct{ Expenses []struct{ Amount float64 } }
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/?household_id="+h.Id, carolToken, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	testutil.Decode(t, w, &list)
+	assert.Len(t, list.Expenses, 3)
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/"+created.Expense.Id, carolToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = testutil.Do(t, r, http.MethodPut, "/api/v1/expenses/"+created.Expense.Id, carolToken,
+		map[string]interface{}{"amount": 80, "currency": "USD", "category": "utilities"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = testutil.Do(t, r, http.MethodDelete, "/api/v1/expenses/"+created.Expense.Id, carolToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Outsiders see nothing: a household expense does not exist for them,
+	// as another user's personal one does not.
+	for _, path := range []string{
+		"/api/v1/expenses/?household_id=" + h.Id,
+		"/api/v1/expenses/summary?household_id=" + h.Id,
+	} {
+		w = testutil.Do(t, r, http.MethodGet, path, malloryToken, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code, path)
+	}
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/"+created.Expense.Id, malloryToken, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = testutil.Do(t, r, http.MethodPut, "/api/v1/expenses/"+created.Expense.Id, malloryToken,
+		map[string]interface{}{"amount": 80, "currency": "USD", "category": "utilities"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = testutil.Do(t, r, http.MethodDelete, "/api/v1/expenses/"+created.Expense.Id, malloryToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code, "deleting it is a no-op, as for a missing expense")
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/"+created.Expense.Id, aliceToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Personal expenses stay out of the household ledger and vice versa.
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/?user_id="+alice.UserId, aliceToken, nil)
+	testutil.Decode(t, w, &list)
+	require.Len(t, list.Expenses, 1)
+	assert.Equal(t, 7.0, list.Expenses[0].Amount)
+
+	var summary map[string]float64
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/summary?household_id="+h.Id, carolToken, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	testutil.Decode(t, w, &summary)
+	assert.Equal(t, map[string]float64{"groceries": 42, "utilities": 8}, summary)
+
+	summary = nil
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/summary?group_by=member&household_id="+h.Id, aliceToken, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	testutil.Decode(t, w, &summary)
+	assert.Equal(t, map[string]float64{alice.UserId: 30, bob.UserId: 12, carol.UserId: 8}, summary)
+
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/summary?group_by=day", aliceToken, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHouseholds_PersonalExpensesStayPrivate(t *testing.T) {
+	r := testutil.Router(t)
+	alice := testutil.User().Create(t)
+	bobToken := testutil.Token(t, testutil.User().Create(t))
+	expense := testutil.Expense().Owner(alice).Amount(12).Create(t)
+	path := "/api/v1/expenses/" + expense.Id
+
+	w := testutil.Do(t, r, http.MethodGet, path, bobToken, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = testutil.Do(t, r, http.MethodPut, path, bobToken, map[string]interface{}{"amount": 1, "category": "food"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = testutil.Do(t, r, http.MethodDelete, path, bobToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = testutil.Do(t, r, http.MethodGet, path, testutil.Token(t, alice), nil)
+	require.Equal(t, http.StatusOK, w.Code, "a stranger's delete is a no-op")
+	assert.Contains(t, w.Body.String(), `"Amount":12`)
+}
@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"errors"
+	"expense-tracker/logging"
+	"expense-tracker/problem"
+	"expense-tracker/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxStatementSize bounds uploaded bank statements.
+const maxStatementSize = 10 << 20
+
+// ImportStatement godoc
+// @Summary      Import a bank statement
+// @Description  Import an OFX/QFX, QIF or CAMT.053 statement. Debits become expenses and credits income; transactions whose bank ID was imported before are skipped. The report lists what was new, duplicate or rejected.
+// @Tags         imports
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file          formData  file    true   "Statement file"
+// @Param        format        formData  string  false  "ofx, qif or camt053 (default: detected)"
+// @Param        household_id  formData  string  false  "Household ID (omit for personal records)"
+// @Param        account_id    formData  string  false  "Account the statement belongs to"
+// @Param        category      formData  string  false  "Category of imported expenses (default uncategorized)"
+// @Param        currency      formData  string  false  "Currency for statements that name none (QIF)"
+// @Success      200           {object}  service.ImportReport
+// @Failure      400           {object}  problem.Problem
+// @Failure      403           {object}  problem.Problem
+// @Failure      413           {object}  problem.Problem
+// @Failure      422           {object}  problem.Problem
+// @Router       /api/v1/imports [post]
+// @Security     BearerAuth
+func ImportStatement(c *gin.Context) {
+	logger := logging.FromContext(c)
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxStatementSize)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		logger.Warnf("Unable to read statement upload: %v", err)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			fail(c, problem.New(http.StatusRequestEntityTooLarge, problem.CodeTooLarge, "Statement files are limited to 10 MB"))
+			return
+		}
+		fail(c, problem.BadRequest("A statement file is required in the file field"))
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	defer file.Close()
+
+	report, err := service.ImportStatement(c.Request.Context(), c.GetString("user_id"), file, service.ImportOptions{
+		Format:      c.PostForm("format"),
+		HouseholdID: c.PostForm("household_id"),
+		AccountID:   c.PostForm("account_id"),
+		Category:    c.PostForm("category"),
+		Currency:    c.PostForm("currency"),
+	})
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	logger.WithFields(log.Fields{
+		"file":       header.Filename,
+		"format":     report.Format,
+		"new":        len(report.New),
+		"duplicates": len(report.Duplicates),
+		"rejected":   len(report.Rejected),
+	}).Info("Imported statement")
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,131 @@
+package controller_test
+
+import (
+	"expense-tracker/service"
+	"expense-tracker/testutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const statementOFX = `OFXHEADER:100
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><CURDEF>USD
+<BANKACCTFROM><ACCTID>4411</BANKACCTFROM><BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20260305<TRNAMT>-42.17<FITID>F1<NAME>CORNER GROCERY<MEMO>POS 0305</STMTTRN>
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20260315<TRNAMT>2500.00<FITID>F2<NAME>ACME PAYROLL</STMTTRN>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20260316<TRNAMT>-1.005<FITID>F3<NAME>ROUNDING</STMTTRN>
+</BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>`
+
+func TestImport_DeduplicatesAcrossImports(t *testing.T) {
+	r := testutil.Router(t)
+	user := testutil.User().Create(t)
+	token := testutil.Token(t, user)
+
+	w := testutil.Upload(t, r, "/api/v1/imports/", token, "march.ofx", []byte(statementOFX), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var report service.ImportReport
+	testutil.Decode(t, w, &report)
+	assert.Equal(t, "ofx", report.Format)
+	require.Len(t, report.New, 2)
+	assert.Equal(t, service.EntryExpense, report.New[0].Type)
+	assert.Equal(t, service.EntryIncome, report.New[1].Type)
+	assert.Empty(t, report.Duplicates)
+	require.Len(t, report.Rejected, 1)
+	assert.Equal(t, 3, report.Rejected[0].Index)
+	assert.Contains(t, report.Rejected[0].Reason, "amount")
+
+	var list struct {
+		Expenses []struct{ Category, Description, Payee, Memo, External_id string }
+	}
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/", token, nil)
+	testutil.Decode(t, w, &list)
+	require.Len(t, list.Expenses, 1)
+	assert.Equal(t, service.ImportCategory, list.Expenses[0].Category)
+	assert.Equal(t, "CORNER GROCERY", list.Expenses[0].Description)
+	assert.Equal(t, "POS 0305", list.Expenses[0].Memo)
+	assert.Equal(t, "ofx:4411:F1", list.Expenses[0].External_id)
+
+	w = testutil.Upload(t, r, "/api/v1/imports/", token, "march-again.ofx", []byte(statementOFX),
+		map[string]string{"category": "groceries"})
+	require.Equal(t, http.StatusOK, w.Code)
+	testutil.Decode(t, w, &report)
+	assert.Empty(t, report.New)
+	assert.Len(t, report.Duplicates, 2)
+
+	other := testutil.Token(t, testutil.User().Create(t))
+	w = testutil.Upload(t, r, "/api/v1/imports/", other, "march.ofx", []byte(statementOFX), nil)
+	testutil.Decode(t, w, &report)
+	assert.Len(t, report.New, 2, "duplicates are per ledger")
+}
+
+func TestImport_ConcurrentImportsOfOneStatement(t *testing.T) {
+	r := testutil.Router(t)
+	token := testutil.Token(t, testutil.User().Create(t))
+
+	var wg sync.WaitGroup
+	reports := make([]service.ImportReport, 6)
+	for i := range reports {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := testutil.Upload(t, r, "/api/v1/imports/", token, "march.ofx", []byte(statementOFX), nil)
+			if assert.Equal(t, http.StatusOK, w.Code, w.Body.String()) {
+				testutil.Decode(t, w, &reports[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	var created, duplicates int
+	for _, report := range reports {
+		created += len(report.New)
+		duplicates += len(report.Duplicates)
+	}
+	assert.Equal(t, 2, created, "each transaction is created once")
+	assert.Equal(t, 2*len(reports)-2, duplicates)
+
+	var list struct{ Expenses []struct{ Id string } }
+	w := testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/", token, nil)
+	testutil.Decode(t, w, &list)
+	assert.Len(t, list.Expenses, 1)
+}
+
+func TestImport_IntoAccount(t *testing.T) {
+	r := testutil.Router(t)
+	token := testutil.Token(t, testutil.User().Create(t))
+	account := createAccount(t, r, token, map[string]interface{}{"name": "Current", "currency": "GBP", "opening_balance": 100})
+
+	qif := "!Type:Bank\nD03/01/2026\nT-20.00\nPTrain\n^\nD03/02/2026\nT5.00\nPRefund\n^\n"
+	w := testutil.Upload(t, r, "/api/v1/imports/", token, "current.qif", []byte(qif),
+		map[string]string{"account_id": account})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var report service.ImportReport
+	testutil.Decode(t, w, &report)
+	require.Len(t, report.New, 2)
+	assert.Equal(t, "GBP", report.New[0].Currency, "QIF takes the account currency")
+
+	var balance service.AccountBalance
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/accounts/"+account, token, nil)
+	testutil.Decode(t, w, &balance)
+	assert.Equal(t, 85.0, balance.Balance)
+}
+
+func TestImport_RejectsUnreadableFiles(t *testing.T) {
+	r := testutil.Router(t)
+	token := testutil.Token(t, testutil.User().Create(t))
+
+	w := testutil.Upload(t, r, "/api/v1/imports/", token, "notes.txt", []byte("hello"), nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "unknown statement format")
+
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/imports/", token, map[string]string{})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	big := strings.Repeat("x", 11<<20)
+	w = testutil.Upload(t, r, "/api/v1/imports/", token, "big.ofx", []byte(big), nil)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"errors"
+	"expense-tracker/logging"
+	"expense-tracker/problem"
+	"expense-tracker/service"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// CreateIncome godoc
+// @Summary      Record income
+// @Description  Record money coming in: salary, a refund or other income. The currency defaults to USD and the timestamp to now.
+// @Tags         income
+// @Accept       json
+// @Produce      json
+// @Param        income  body      service.IncomeInput  true  "Income data"
+// @Success      201     {object}  model.Income
+// @Failure      400     {object}  problem.Problem
+// @Failure      403     {object}  problem.Problem
+// @Failure      422     {object}  problem.Problem
+// @Failure      500     {object}  problem.Problem
+// @Router       /api/v1/incomes [post]
+// @Security     BearerAuth
+func CreateIncome(c *gin.Context) {
+	logger := logging.FromContext(c)
+
+	var input service.IncomeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Warnf("Unable to bind JSON: %v", err)
+		fail(c, errInvalidPayload)
+		return
+	}
+
+	income, err := service.CreateIncome(c.Request.Context(), c.GetString("user_id"), input)
+	if errors.Is(err, service.ErrInvalidArgument) {
+		fail(c, problem.BadRequest("The income can only be attributed to a household member"))
+		return
+	}
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	logger.WithFields(log.Fields{
+		"income_user_id": income.User_id,
+		"income_id":      income.Id,
+	}).Info("Created income")
+	c.JSON(http.StatusCreated, gin.H{"income": income})
+}
+
+// GetIncome godoc
+// @Summary      Get income by ID
+// @Description  Get a single income record by its ID
+// @Tags         income
+// @Produce      json
+// @Param        id   path      string  true  "Income ID"
+// @Success      200  {object}  model.Income
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Router       /api/v1/incomes/{id} [get]
+// @Security     BearerAuth
+func GetIncome(c *gin.Context) {
+	income, err := service.GetIncome(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, service.ErrNotFound) {
+		fail(c, problem.NotFound("Income not found"))
+		return
+	}
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"income": income})
+}
+
+// UpdateIncome godoc
+// @Summary      Update income
+// @Description  Update an existing income record by ID
+// @Tags         income
+// @Accept       json
+// @Produce      json
+// @Param        id      path      string               true  "Income ID"
+// @Param        income  body      service.IncomeInput  true  "Income data"
+// @Success      200     {object}  model.Income
+// @Failure      400     {object}  problem.Problem
+// @Failure      403     {object}  problem.Problem
+// @Failure      404     {object}  problem.Problem
+// @Failure      422     {object}  problem.Problem
+// @Router       /api/v1/incomes/{id} [put]
+// @Security     BearerAuth
+func UpdateIncome(c *gin.Context) {
+	logger := logging.FromContext(c)
+	id := c.Param("id")
+
+	var input service.IncomeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Warnf("Unable to bind JSON: %v", err)
+		fail(c, errInvalidPayload)
+		return
+	}
+
+	income, err := service.UpdateIncome(c.Request.Context(), id, input)
+	if errors.Is(err, service.ErrNotFound) {
+		fail(c, problem.NotFound("Income not found"))
+		return
+	}
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	logger.WithField("income_id", id).Info("Updated income")
+	c.JSON(http.StatusOK, gin.H{"message": "Income updated", "income": income})
+}
+
+// DeleteIncome godoc
+// @Summary      Delete income
+// @Description  Delete an income record by ID
+// @Tags         income
+// @Produce      json
+// @Param        id   path      string  true  "Income ID"
+// @Success      200  {object}  map[string]string
+// @Failure      403  {object}  problem.Problem
+// @Router       /api/v1/incomes/{id} [delete]
+// @Security     BearerAuth
+func DeleteIncome(c *gin.Context) {
+	id := c.Param("id")
+	if err := service.DeleteIncome(c.Request.Context(), id); err != nil {
+		fail(c, err)
+		return
+	}
+
+	logging.FromContext(c).WithField("income_id", id).Info("Deleted income")
+	c.JSON(http.StatusOK, gin.H{"message": "Income deleted"})
+}
+
+// ListIncome godoc
+// @Summary      List income
+// @Description  List income with optional filters, oldest first
+// @Tags         income
+// @Produce      json
+// @Param        user_id       query     string  false  "User ID"
+// @Param        household_id  query     string  false  "Household ID (omit for personal income)"
+// @Param        category      query     string  false  "salary, refund or other"
+// @Param        currency      query     string  false  "Currency"
+// @Param        account_id    query     string  false  "Account ID"
+// @Param        from          query     string  false  "Start date (YYYY-MM-DD)"
+// @Param        to            query     string  false  "End date (YYYY-MM-DD)"
+// @Success      200           {object}  []model.Income
+// @Failure      400           {object}  problem.Problem
+// @Failure      403           {object}  problem.Problem
+// @Router       /api/v1/incomes [get]
+// @Security     BearerAuth
+func ListIncome(c *gin.Context) {
+	filter := service.ExpenseFilter{
+		UserID:      c.Query("user_id"),
+		HouseholdID: c.Query("household_id"),
+		Category:    c.Query("category"),
+		Currency:    c.Query("currency"),
+		AccountID:   c.Query("account_id"),
+		From:        c.Query("from"),
+		To:          c.Query("to"),
+		Limit:       service.DefaultPageSize,
+	}
+	if l := c.Query("limit"); l != "" {
+		fmt.Sscanf(l, "%d", &filter.Limit)
+	}
+	if o := c.Query("offset"); o != "" {
+		fmt.Sscanf(o, "%d", &filter.Offset)
+	}
+
+	incomes, err := service.ListIncome(c.Request.Context(), filter)
+	if errors.Is(err, service.ErrInvalidArgument) {
+		fail(c, problem.BadRequest("Invalid date filter"))
+		return
+	}
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"incomes": incomes})
+}
@@ -0,0 +1,208 @@
+package controller_test
+
+import (
+	"expense-tracker/config"
+	"expense-tracker/model"
+	"expense-tracker/service"
+	"expense-tracker/testutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncome_CRUD(t *testing.T) {
+	r := testutil.Router(t)
+	user := testutil.User().Create(t)
+	token := testutil.Token(t, user)
+
+	w := testutil.Do(t, r, http.MethodPost, "/api/v1/incomes/", token,
+		map[string]interface{}{"amount": 2500, "category": "Salary", "description": "October pay"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created struct {
+		Income struct{ Id, User_id, Currency, Category string }
+	}
+	testutil.Decode(t, w, &created)
+	assert.Equal(t, user.UserId, created.Income.User_id)
+	assert.Equal(t, "USD", created.Income.Currency)
+	assert.Equal(t, service.IncomeSalary, created.Income.Category)
+	path := "/api/v1/incomes/" + created.Income.Id
+
+	w = testutil.Do(t, r, http.MethodPut, path, token, map[string]interface{}{"amount": 40, "category": "refund"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var list struct {
+		Incomes []struct {
+			Amount   float64
+			Category string
+		}
+	}
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/incomes/?category=refund", token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	testutil.Decode(t, w, &list)
+	require.Len(t, list.Incomes, 1)
+	assert.Equal(t, 40.0, list.Incomes[0].Amount)
+
+	w = testutil.Do(t, r, http.MethodDelete, path, token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	w = testutil.Do(t, r, http.MethodGet, path, token, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestIncome_RejectsUnknownCategory(t *testing.T) {
+	r := testutil.Router(t)
+	token := testutil.Token(t, testutil.User().Create(t))
+
+	w := testutil.Do(t, r, http.MethodPost, "/api/v1/incomes/", token, map[string]interface{}{"amount": 10, "category": "food"})
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var body struct {
+		Errors []struct{ Field, Reason string }
+	}
+	testutil.Decode(t, w, &body)
+	require.Len(t, body.Errors, 1)
+	assert.Equal(t, "category", body.Errors[0].Field)
+}
+
+func TestCashFlow_PerMonthAndCategory(t *testing.T) {
+	r := testutil.Router(t)
+	user := testutil.User().Create(t)
+	token := testutil.Token(t, user)
+	october := time.Date(2026, 10, 3, 12, 0, 0, 0, time.UTC)
+	november := october.AddDate(0, 1, 0)
+
+	testutil.Income().Owner(user).Amount(2000).At(october).Create(t)
+	testutil.Income().Owner(user).Amount(25.5).Category(service.IncomeRefund).At(october).Create(t)
+	testutil.Expense().Owner(user).Amount(300).Category("rent").At(october).Create(t)
+	testutil.Expense().Owner(user).Amount(20).Category("refund").At(october).Create(t)
+	testutil.Expense().Owner(user).Amount(80).Category("food").At(november).Create(t)
+	testutil.Expense().Owner(user).Amount(10).Currency("EUR").Category("food").At(november).Create(t)
+
+	w := testutil.Do(t, r, http.MethodGet, "/api/v1/reports/cashflow?user_id="+user.UserId, token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var report service.CashFlowReport
+	testutil.Decode(t, w, &report)
+	assert.Empty(t, report.Currency)
+	require.Len(t, report.Months, 3, "November is split by currency")
+
+	oct := report.Months[0]
+	assert.Equal(t, "2026-10", oct.Month)
+	assert.Equal(t, 2025.5, oct.Income)
+	assert.Equal(t, 320.0, oct.Expenses)
+	assert.Equal(t, 1705.5, oct.Net)
+	assert.Equal(t, []service.CategoryCashFlow{
+		{Category: "refund", Income: 25.5, Expenses: 20, Net: 5.5},
+		{Category: "rent", Expenses: 300, Net: -300},
+		{Category: "salary", Income: 2000, Net: 2000},
+	}, oct.Categories)
+
+	assert.Equal(t, "2026-11", report.Months[1].Month)
+	assert.Equal(t, "EUR", report.Months[1].Currency)
+	assert.Equal(t, "USD", report.Months[2].Currency)
+	assert.Equal(t, -80.0, report.Months[2].Net)
+}
+
+func TestCashFlow_NormalizesCurrency(t *testing.T) {
+	testutil.ConfigureService(t, func(c *service.Config) {
+		c.ExchangeRates = config.Currency{Base: "USD", Rates: map[string]float64{"EUR": 1.1}}
+	})
+
+	r := testutil.Router(t)
+	user := testutil.User().Create(t)
+	token := testutil.Token(t, user)
+	testutil.Income().Owner(user).Amount(110).Create(t)
+	testutil.Expense().Owner(user).Amount(50).Currency("EUR").Create(t)
+
+	w := testutil.Do(t, r, http.MethodGet, "/api/v1/reports/cashflow?currency=eur", token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var report service.CashFlowReport
+	testutil.Decode(t, w, &report)
+	assert.Equal(t, "EUR", report.Currency)
+	require.Len(t, report.Months, 1)
+	assert.Equal(t, 100.0, report.Months[0].Income)
+	assert.Equal(t, 50.0, report.Months[0].Expenses)
+	assert.Equal(t, 50.0, report.Months[0].Net)
+
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/reports/cashflow?currency=GBP", token, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "no exchange rate for GBP")
+}
+
+func TestIncome_PersonalRecordsStayPrivate(t *testing.T) {
+	r := testutil.Router(t)
+	alice := testutil.User().Create(t)
+	bob := testutil.User().Create(t)
+	aliceToken, bobToken := testutil.Token(t, alice), testutil.Token(t, bob)
+	salary := testutil.Income().Owner(alice).Amount(2000).Create(t)
+	testutil.Income().Owner(bob).Amount(50).Create(t)
+	testutil.Expense().Owner(bob).Amount(7).Create(t)
+	path := "/api/v1/incomes/" + salary.Id
+
+	w := testutil.Do(t, r, http.MethodGet, path, bobToken, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = testutil.Do(t, r, http.MethodPut, path, bobToken, map[string]interface{}{"amount": 1, "category": "salary"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = testutil.Do(t, r, http.MethodDelete, path, bobToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = testutil.Do(t, r, http.MethodGet, path, aliceToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code, "a stranger's delete is a no-op")
+
+	var list struct{ Incomes []model.Income }
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/incomes/", aliceToken, nil)
+	testutil.Decode(t, w, &list)
+	require.Len(t, list.Incomes, 1, "without user_id only the caller's income")
+	assert.Equal(t, salary.Id, list.Incomes[0].Id)
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/incomes/?user_id="+bob.UserId, aliceToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	var report service.CashFlowReport
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/reports/cashflow", aliceToken, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	testutil.Decode(t, w, &report)
+	require.Len(t, report.Months, 1)
+	assert.Equal(t, 2000.0, report.Months[0].Net, "bob's records are not counted")
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/reports/cashflow?user_id="+bob.UserId, aliceToken, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestIncome_HouseholdIncomeHiddenFromOutsiders(t *testing.T) {
+	r := testutil.Router(t)
+	alice, bob, mallory := testutil.User().Create(t), testutil.User().Create(t), testutil.User().Create(t)
+	h := testutil.Household(alice).Member(bob, service.RoleViewer).Create(t)
+	income := testutil.Income().Owner(alice).In(h).Create(t)
+	update := map[string]interface{}{"amount": 50, "currency": "USD", "category": "refund"}
+
+	w := testutil.Do(t, r, http.MethodGet, "/api/v1/incomes/"+income.Id, testutil.Token(t, bob), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = testutil.Do(t, r, http.MethodPut, "/api/v1/incomes/"+income.Id, testutil.Token(t, bob), update)
+	assert.Equal(t, http.StatusForbidden, w.Code, "viewers see it but cannot change it")
+
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/incomes/"+income.Id, testutil.Token(t, mallory), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = testutil.Do(t, r, http.MethodPut, "/api/v1/incomes/"+income.Id, testutil.Token(t, mallory), update)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCashFlow_CountsEveryRowPastOneBatch(t *testing.T) {
+	r := testutil.Router(t)
+	user := testutil.User().Create(t)
+	october := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	// More rows than a report reads at a time, several to a timestamp.
+	for i := range 1200 {
+		at := october.Add(time.Duration(i/4) * time.Minute)
+		if i%2 == 0 {
+			testutil.Income().Owner(user).Amount(1).At(at).Create(t)
+		} else {
+			testutil.Expense().Owner(user).Amount(1).Category("food").At(at).Create(t)
+		}
+	}
+
+	w := testutil.Do(t, r, http.MethodGet, "/api/v1/reports/cashflow", testutil.Token(t, user), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var report service.CashFlowReport
+	testutil.Decode(t, w, &report)
+	require.Len(t, report.Months, 1)
+	assert.Equal(t, 600.0, report.Months[0].Income)
+	assert.Equal(t, 600.0, report.Months[0].Expenses)
+}
@@ -0,0 +1,21 @@
+package controller
+
+import (
+	"expense-tracker/auth"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS godoc
+// @Summary      Token verification keys
+// @Description  The public keys our JWTs are signed with, as a JSON Web Key Set, so other services can verify them. Empty when tokens are signed with a shared secret.
+// @Tags         users
+// @Produce      json
+// @Success      200  {object}  oidc.JWKS
+// @Router       /.well-known/jwks.json [get]
+func JWKS(c *gin.Context) {
+	// Verifiers may cache the set; they refetch it on an unknown key ID.
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, auth.PublicKeys())
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"errors"
+	"expense-tracker/logging"
+	"expense-tracker/problem"
+	"expense-tracker/service"
+	"expense-tracker/store"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListLoginFailures godoc
+// @Summary      List failed logins (admin)
+// @Description  List recent failed password and two-factor logins, newest first, with the client IP and reason (unknown_user, bad_password or bad_code)
+// @Tags         admin
+// @Produce      json
+// @Param        user_name  query     string  false  "Only this user name"
+// @Param        since      query     string  false  "Only attempts after this RFC 3339 time"
+// @Param        limit      query     int     false  "Maximum number of attempts (default 100, at most 500)"
+// @Success      200        {object}  []model.LoginAttempt
+// @Failure      400        {object}  problem.Problem
+// @Failure      403        {object}  problem.Problem
+// @Failure      500        {object}  problem.Problem
+// @Router       /api/v1/admin/login-failures [get]
+// @Security     BearerAuth
+func ListLoginFailures(c *gin.Context) {
+	logger := logging.FromContext(c)
+	filter := store.LoginAttemptFilter{UserName: c.Query("user_name")}
+	if since := c.Query("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			fail(c, problem.BadRequest("since must be an RFC 3339 time"))
+			return
+		}
+		filter.Since = t
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			fail(c, problem.BadRequest("limit must be a number"))
+			return
+		}
+		filter.Limit = n
+	}
+	attempts, err := service.ListLoginFailures(c.Request.Context(), c.GetString("user_id"), filter)
+	if errors.Is(err, service.ErrForbidden) {
+		logger.Warn("Admin access denied")
+		fail(c, errAdminRequired)
+		return
+	}
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"login_failures": attempts})
+}
+
+// UnlockLogin godoc
+// @Summary      Lift a login lockout (admin)
+// @Description  Clear the failed login count and any lockout for a user name
+// @Tags         admin
+// @Produce      json
+// @Param        user_name  path      string  true  "User name"
+// @Success      200        {object}  map[string]string
+// @Failure      403        {object}  problem.Problem
+// @Failure      500        {object}  problem.Problem
+// @Router       /api/v1/admin/lockouts/{user_name} [delete]
+// @Security     BearerAuth
+func UnlockLogin(c *gin.Context) {
+	logger := logging.FromContext(c).WithField("target_user_name", c.Param("user_name"))
+	err := service.UnlockLogin(c.Request.Context(), c.GetString("user_id"), c.Param("user_name"))
+	if errors.Is(err, service.ErrForbidden) {
+		logger.Warn("Admin access denied")
+		fail(c, errAdminRequired)
+		return
+	}
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	logger.Info("Admin lifted login lockout")
+	c.JSON(http.StatusOK, gin.H{"message": "Lockout lifted"})
+}
@@ -0,0 +1,134 @@
+package controller_test
+
+import (
+	"expense-tracker/config"
+	"expense-tracker/model"
+	"expense-tracker/service"
+	"expense-tracker/testutil"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useLockout applies a brute-force policy for the duration of the test.
+func useLockout(t *testing.T, threshold int) {
+	t.Helper()
+	testutil.ConfigureService(t, func(c *service.Config) {
+		c.Lockout = config.Login{
+			LockoutThreshold: threshold,
+			LockoutDuration:  config.Duration{Duration: time.Minute},
+			MaxLockout:       config.Duration{Duration: time.Hour},
+		}
+	})
+}
+
+func TestLogin_UniformErrors(t *testing.T) {
+	r := testutil.Router(t)
+	alice := testutil.User().Create(t)
+
+	unknown := login(t, r, "nobody", testutil.DefaultPassword)
+	wrong := login(t, r, alice.UserName, "wrong-password")
+	assert.Equal(t, http.StatusUnauthorized, unknown.Code)
+	assert.Equal(t, unknown.Code, wrong.Code)
+	var a, b map[string]interface{}
+	testutil.Decode(t, unknown, &a)
+	testutil.Decode(t, wrong, &b)
+	delete(a, "request_id")
+	delete(b, "request_id")
+	assert.Equal(t, a, b)
+}
+
+func TestLogin_LocksOutAfterRepeatedFailures(t *testing.T) {
+	r := testutil.Router(t)
+	useLockout(t, 3)
+	alice := testutil.User().Create(t)
+
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, http.StatusUnauthorized, login(t, r, alice.UserName, "wrong-password").Code)
+	}
+	// A success before the threshold clears the count.
+	require.Equal(t, http.StatusOK, login(t, r, alice.UserName, testutil.DefaultPassword).Code)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusUnauthorized, login(t, r, alice.UserName, "wrong-password").Code)
+	}
+
+	w := login(t, r, alice.UserName, testutil.DefaultPassword)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "even the right password is refused while locked")
+	retry, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.InDelta(t, 60, retry, 2)
+	w = login(t, r, "ALICE-"+alice.UserName, testutil.DefaultPassword)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "other names are unaffected")
+}
+
+func TestLogin_ThrottlesUnknownNamesAlike(t *testing.T) {
+	r := testutil.Router(t)
+	useLockout(t, 10)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusUnauthorized, login(t, r, "ghost", "guess").Code)
+	}
+	// After the first few failures each attempt must wait.
+	w := login(t, r, "Ghost", "guess")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+}
+
+func TestLogin_SecondFactorFailuresCount(t *testing.T) {
+	r := testutil.Router(t)
+	useLockout(t, 2)
+	alice := testutil.User().Create(t)
+	secret, _ := enroll(t, r, testutil.Token(t, alice))
+
+	w := login(t, r, alice.UserName, testutil.DefaultPassword)
+	var challenge map[string]interface{}
+	testutil.Decode(t, w, &challenge)
+	second := func(code string) int {
+		return testutil.Do(t, r, http.MethodPost, "/api/v1/login/2fa", "",
+			map[string]interface{}{"challenge_token": challenge["challenge_token"], "code": code}).Code
+	}
+	assert.Equal(t, http.StatusUnauthorized, second("000000x"))
+	assert.Equal(t, http.StatusUnauthorized, second("000000y"))
+	assert.Equal(t, http.StatusTooManyRequests, second(totp(t, secret, 0)))
+}
+
+func TestAdmin_LoginFailures(t *testing.T) {
+	r := testutil.Router(t)
+	useLockout(t, 3)
+	admin := testutil.User().Name("root-admin").Create(t)
+	alice := testutil.User().Create(t)
+	testutil.ConfigureService(t, func(c *service.Config) { c.AdminUsers = []string{admin.UserName} })
+
+	for i := 0; i < 3; i++ {
+		login(t, r, alice.UserName, "wrong-password")
+	}
+	login(t, r, "ghost", "guess")
+
+	w := testutil.Do(t, r, http.MethodGet, "/api/v1/admin/login-failures", testutil.Token(t, alice), nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/admin/login-failures?limit=2", testutil.Token(t, admin), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		LoginFailures []model.LoginAttempt `json:"login_failures"`
+	}
+	testutil.Decode(t, w, &resp)
+	require.Len(t, resp.LoginFailures, 2)
+	assert.Equal(t, "ghost", resp.LoginFailures[0].UserName)
+	assert.Equal(t, service.FailureUnknownUser, resp.LoginFailures[0].Reason)
+	assert.Equal(t, alice.UserId, resp.LoginFailures[1].UserId)
+	assert.Equal(t, service.FailureBadPassword, resp.LoginFailures[1].Reason)
+	assert.NotEmpty(t, resp.LoginFailures[1].ClientIP)
+
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/admin/login-failures?user_name="+alice.UserName, testutil.Token(t, admin), nil)
+	testutil.Decode(t, w, &resp)
+	assert.Len(t, resp.LoginFailures, 3)
+
+	require.Equal(t, http.StatusTooManyRequests, login(t, r, alice.UserName, testutil.DefaultPassword).Code)
+	w = testutil.Do(t, r, http.MethodDelete, "/api/v1/admin/lockouts/"+alice.UserName, testutil.Token(t, admin), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusOK, login(t, r, alice.UserName, testutil.DefaultPassword).Code)
+}
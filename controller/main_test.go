@@ -0,0 +1,18 @@
+package controller_test
+
+import (
+	"expense-tracker/testutil"
+	"os"
+	"testing"
+)
+
+// TestMain runs the suite twice: on the in-memory stores, then on the SQL
+// stores over SQLite, so the routes are checked against both.
+func TestMain(m *testing.M) {
+	code := m.Run()
+	testutil.Backend = testutil.BackendSQLite
+	if sqlCode := m.Run(); sqlCode != 0 {
+		code = sqlCode
+	}
+	os.Exit(code)
+}
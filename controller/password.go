@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"errors"
+	"expense-tracker/logging"
+	"expense-tracker/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChangePassword godoc
+// @Summary      Change password
+// @Description  Change the current user's password. Every other session is signed out; the returned token replaces the caller's.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        password  body      object  true  "Current and new password"  example({"current_password":"mypassword","new_password":"correct horse battery"})
+// @Success      200       {object}  map[string]string
+// @Failure      400       {object}  problem.Problem
+// @Failure      401       {object}  problem.Problem
+// @Failure      500       {object}  problem.Problem
+// @Router       /api/v1/auth/password [post]
+// @Security     BearerAuth
+func ChangePassword(c *gin.Context) {
+	var req struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.CurrentPassword == "" || req.NewPassword == "" {
+		fail(c, errInvalidPayload)
+		return
+	}
+	logger := logging.FromContext(c)
+	token, err := service.ChangePassword(c.Request.Context(), c.GetString("user_id"), req.CurrentPassword, req.NewPassword)
+	if errors.Is(err, service.ErrInvalidPassword) {
+		logger.Warn("Password change failed: invalid current password")
+	}
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	logger.Info("Password changed")
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed", "token": token})
+}
+
+// ForgotPassword godoc
+// @Summary      Request a password reset
+// @Description  Send a single-use reset token to the user through the configured notifier. The response is the same whether or not the user exists.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        user  body      object  true  "User name"  example({"user_name":"alice"})
+// @Success      202   {object}  map[string]string
+// @Failure      400   {object}  problem.Problem
+// @Failure      500   {object}  problem.Problem
+// @Router       /api/v1/auth/password/forgot [post]
+func ForgotPassword(c *gin.Context) {
+	var req struct {
+		UserName string `json:"user_name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.UserName == "" {
+		fail(c, errInvalidPayload)
+		return
+	}
+	if err := service.RequestPasswordReset(c.Request.Context(), req.UserName); err != nil {
+		fail(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the account exists, a reset token has been sent"})
+}
+
+// ResetPassword godoc
+// @Summary      Reset password
+// @Description  Set a new password with a reset token. The token is single use and every session of the user is signed out.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        reset  body      object  true  "Reset token and new password"  example({"token":"...","new_password":"correct horse battery"})
+// @Success      200    {object}  map[string]string
+// @Failure      400    {object}  problem.Problem
+// @Failure      500    {object}  problem.Problem
+// @Router       /api/v1/auth/password/reset [post]
+func ResetPassword(c *gin.Context) {
+	var req struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Token == "" || req.NewPassword == "" {
+		fail(c, errInvalidPayload)
+		return
+	}
+	logger := logging.FromContext(c)
+	err := service.ResetPassword(c.Request.Context(), req.Token, req.NewPassword)
+	if errors.Is(err, service.ErrInvalidResetToken) {
+		logger.Warn("Password reset failed: invalid token")
+	}
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	logger.Info("Password reset")
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset"})
+}
@@ -0,0 +1,112 @@
+package controller_test
+
+import (
+	"expense-tracker/notify"
+	"expense-tracker/testutil"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const newPassword = "correct horse battery"
+
+func TestPassword_SignUpPolicy(t *testing.T) {
+	r := testutil.Router(t)
+
+	for _, pw := range []string{"short", "password123"} {
+		w := testutil.Do(t, r, http.MethodPost, "/api/v1/signup", "", map[string]string{"user_name": "erin", "password": pw})
+		assert.Equal(t, http.StatusBadRequest, w.Code, pw)
+		assert.Contains(t, w.Body.String(), "password does not meet the policy", pw)
+	}
+}
+
+func TestPassword_ChangeRevokesOtherSessions(t *testing.T) {
+	r := testutil.Router(t)
+	alice := testutil.User().Create(t)
+	other := testutil.Token(t, alice)
+
+	w := testutil.Do(t, r, http.MethodPost, "/api/v1/auth/password", other,
+		map[string]string{"current_password": "wrong", "new_password": newPassword})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/auth/password", other,
+		map[string]string{"current_password": testutil.DefaultPassword, "new_password": "qwerty123"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/auth/password", other,
+		map[string]string{"current_password": testutil.DefaultPassword, "new_password": newPassword})
+	require.Equal(t, http.StatusOK, w.Code)
+	var changed map[string]string
+	testutil.Decode(t, w, &changed)
+	require.NotEmpty(t, changed["token"])
+
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/", other, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "tokens issued before the change are revoked")
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/", changed["token"], nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/login", "", map[string]string{"user_name": alice.UserName, "password": newPassword})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestPassword_ResetFlow(t *testing.T) {
+	r := testutil.Router(t)
+	alice := testutil.User().Create(t)
+	session := testutil.Token(t, alice)
+	sent := testutil.Notifications(t)
+
+	w := testutil.Do(t, r, http.MethodPost, "/api/v1/auth/password/forgot", "", map[string]string{"user_name": "nobody"})
+	assert.Equal(t, http.StatusAccepted, w.Code, "unknown users get the same answer")
+	assert.Empty(t, sent.Messages())
+
+	for range 2 {
+		w = testutil.Do(t, r, http.MethodPost, "/api/v1/auth/password/forgot", "", map[string]string{"user_name": alice.UserName})
+		require.Equal(t, http.StatusAccepted, w.Code)
+	}
+	messages := sent.Messages()
+	require.Len(t, messages, 2)
+	assert.Equal(t, notify.KindPasswordReset, messages[1].Kind)
+	assert.Equal(t, alice.UserId, messages[1].UserID)
+	stale, token := messages[0].Token, messages[1].Token
+
+	reset := func(token, pw string) int {
+		return testutil.Do(t, r, http.MethodPost, "/api/v1/auth/password/reset", "",
+			map[string]string{"token": token, "new_password": pw}).Code
+	}
+	assert.Equal(t, http.StatusBadRequest, reset(stale, newPassword), "a newer request replaces older tokens")
+	assert.Equal(t, http.StatusBadRequest, reset(token, "short"))
+	assert.Equal(t, http.StatusOK, reset(token, newPassword), "a rejected password does not use up the token")
+	assert.Equal(t, http.StatusBadRequest, reset(token, newPassword+"!"), "tokens are single use")
+
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/", session, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/login", "", map[string]string{"user_name": alice.UserName, "password": newPassword})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestPassword_ResetTokenRedeemedOnceUnderRace(t *testing.T) {
+	r := testutil.Router(t)
+	alice := testutil.User().Create(t)
+	w := testutil.Do(t, r, http.MethodPost, "/api/v1/auth/password/forgot", "", map[string]string{"user_name": alice.UserName})
+	require.Equal(t, http.StatusAccepted, w.Code)
+	token := testutil.Notifications(t).Messages()[0].Token
+
+	var wg sync.WaitGroup
+	var succeeded atomic.Int32
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := testutil.Do(t, r, http.MethodPost, "/api/v1/auth/password/reset", "",
+				map[string]string{"token": token, "new_password": newPassword})
+			if w.Code == http.StatusOK {
+				succeeded.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), succeeded.Load())
+}
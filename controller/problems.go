@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"errors"
+	"expense-tracker/problem"
+	"expense-tracker/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errInvalidPayload answers request bodies that do not parse.
+var errInvalidPayload = problem.BadRequest("Invalid request payload")
+
+// errAdminRequired answers admin routes called by other users.
+var errAdminRequired = problem.New(http.StatusForbidden, problem.CodeAdminRequired, "Admin access required")
+
+// serviceProblems maps service errors onto problems, so that every handler
+// answers the same failure the same way. Errors marked public carry a
+// reason written for clients, which becomes the detail.
+var serviceProblems = []struct {
+	err    error
+	status int
+	code   problem.Code
+	detail string
+	public bool
+}{
+	{err: service.ErrNotFound, status: http.StatusNotFound, code: problem.CodeNotFound, detail: "Not found"},
+	{err: service.ErrInvalidArgument, status: http.StatusBadRequest, code: problem.CodeBadRequest, public: true},
+	{err: service.ErrForbidden, status: http.StatusForbidden, code: problem.CodeForbidden, detail: "Not allowed to access these records"},
+	{err: service.ErrAlreadyMember, status: http.StatusConflict, code: problem.CodeAlreadyMember, detail: "Already a member of this household"},
+	{err: service.ErrInvalidCredentials, status: http.StatusUnauthorized, code: problem.CodeInvalidCredentials, detail: "Invalid user name or password"},
+	{err: service.ErrInvalidPassword, status: http.StatusUnauthorized, code: problem.CodeInvalidPassword, detail: "Invalid password"},
+	{err: service.ErrWeakPassword, status: http.StatusBadRequest, code: problem.CodeWeakPassword, public: true},
+	{err: service.ErrInvalidCode, status: http.StatusUnauthorized, code: problem.CodeInvalidCode, detail: "Invalid authentication code"},
+	{err: service.ErrInvalidResetToken, status: http.StatusBadRequest, code: problem.CodeInvalidResetToken, detail: "Invalid or expired reset token"},
+	{err: service.ErrTwoFactorEnabled, status: http.StatusConflict, code: problem.CodeTwoFactorEnabled, detail: "Two-factor authentication is already enabled"},
+	{err: service.ErrTwoFactorNotEnabled, status: http.StatusBadRequest, code: problem.CodeTwoFactorNotEnabled, detail: "Two-factor authentication is not enabled"},
+	{err: service.ErrSSODisabled, status: http.StatusNotFound, code: problem.CodeSSODisabled, detail: "Single sign-on is not configured"},
+	{err: service.ErrSSOFailed, status: http.StatusUnauthorized, code: problem.CodeSSOFailed, detail: "Single sign-on failed"},
+	{err: service.ErrNoAccount, status: http.StatusForbidden, code: problem.CodeNoAccount, detail: "No account is linked to this identity"},
+	{err: service.ErrIdentityLinked, status: http.StatusConflict, code: problem.CodeIdentityLinked, detail: "This identity is linked to another account"},
+	{err: service.ErrAccountExists, status: http.StatusConflict, code: problem.CodeAccountExists,
+		detail: "An account with this user name already exists; sign in to it and link the identity"},
+}
+
+// toProblem maps a service error onto the problem answering it. Errors it
+// does not know are left to problem.From, which hides them behind a 500.
+func toProblem(err error) error {
+	var p *problem.Error
+	if errors.As(err, &p) {
+		return p
+	}
+	var lockout *service.LockoutError
+	if errors.As(err, &lockout) {
+		return &problem.Error{Status: http.StatusTooManyRequests, Code: problem.CodeLockedOut,
+			Detail: "Too many failed login attempts; try again later", RetryAfter: lockout.RetryAfter(), Err: err}
+	}
+	for _, m := range serviceProblems {
+		if errors.Is(err, m.err) {
+			detail := m.detail
+			if m.public {
+				detail = err.Error()
+			}
+			return &problem.Error{Status: m.status, Code: m.code, Detail: detail, Err: err}
+		}
+	}
+	return err
+}
+
+// fail answers err as a problem+json response and aborts the request.
+func fail(c *gin.Context, err error) {
+	problem.Abort(c, toProblem(err))
+}
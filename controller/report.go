@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"expense-tracker/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CashFlow godoc
+// @Summary      Monthly cash flow
+// @Description  Income, expenses and net per month and per category. Without currency each currency is reported separately; with it every amount is converted using the configured exchange rates.
+// @Tags         reports
+// @Produce      json
+// @Param        user_id       query     string  false  "User ID"
+// @Param        household_id  query     string  false  "Household ID (omit for personal records)"
+// @Param        from          query     string  false  "Start date (YYYY-MM-DD)"
+// @Param        to            query     string  false  "End date (YYYY-MM-DD)"
+// @Param        currency      query     string  false  "Convert every amount into this currency"
+// @Success      200           {object}  service.CashFlowReport
+// @Failure      400           {object}  problem.Problem
+// @Failure      403           {object}  problem.Problem
+// @Failure      500           {object}  problem.Problem
+// @Router       /api/v1/reports/cashflow [get]
+// @Security     BearerAuth
+func CashFlow(c *gin.Context) {
+	filter := service.ExpenseFilter{
+		UserID:      c.Query("user_id"),
+		HouseholdID: c.Query("household_id"),
+		From:        c.Query("from"),
+		To:          c.Query("to"),
+	}
+	report, err := service.CashFlow(c.Request.Context(), filter, c.Query("currency"))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,202 @@
+package controller
+
+import (
+	"expense-tracker/logging"
+	"expense-tracker/problem"
+	"expense-tracker/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// CreateRule godoc
+// @Summary      Create a categorization rule
+// @Description  Create a rule that sets the category, tags or description of matching expenses as they are created or imported. Conditions on description (contains, regex), amount range, currency and account must all match; rules run by priority, lowest first.
+// @Tags         rules
+// @Accept       json
+// @Produce      json
+// @Param        rule  body      service.RuleInput  true  "Rule data"
+// @Success      201   {object}  model.Rule
+// @Failure      400   {object}  problem.Problem
+// @Failure      422   {object}  problem.Problem
+// @Router       /api/v1/rules [post]
+// @Security     BearerAuth
+func CreateRule(c *gin.Context) {
+	logger := logging.FromContext(c)
+
+	var input service.RuleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Warnf("Unable to bind JSON: %v", err)
+		fail(c, errInvalidPayload)
+		return
+	}
+
+	rule, err := service.CreateRule(c.Request.Context(), c.GetString("user_id"), input)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	logger.WithFields(log.Fields{"rule_id": rule.Id, "priority": rule.Priority}).Info("Created rule")
+	c.JSON(http.StatusCreated, gin.H{"rule": rule})
+}
+
+// ListRules godoc
+// @Summary      List categorization rules
+// @Description  List your rules in the order they run
+// @Tags         rules
+// @Produce      json
+// @Success      200  {object}  []model.Rule
+// @Router       /api/v1/rules [get]
+// @Security     BearerAuth
+func ListRules(c *gin.Context) {
+	rules, err := service.ListRules(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// GetRule godoc
+// @Summary      Get a categorization rule
+// @Tags         rules
+// @Produce      json
+// @Param        id   path      string  true  "Rule ID"
+// @Success      200  {object}  model.Rule
+// @Failure      404  {object}  problem.Problem
+// @Router       /api/v1/rules/{id} [get]
+// @Security     BearerAuth
+func GetRule(c *gin.Context) {
+	rule, err := service.GetRule(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rule": rule})
+}
+
+// UpdateRule godoc
+// @Summary      Update a categorization rule
+// @Tags         rules
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string             true  "Rule ID"
+// @Param        rule  body      service.RuleInput  true  "Rule data"
+// @Success      200   {object}  model.Rule
+// @Failure      400   {object}  problem.Problem
+// @Failure      404   {object}  problem.Problem
+// @Failure      422   {object}  problem.Problem
+// @Router       /api/v1/rules/{id} [put]
+// @Security     BearerAuth
+func UpdateRule(c *gin.Context) {
+	logger := logging.FromContext(c)
+	id := c.Param("id")
+
+	var input service.RuleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Warnf("Unable to bind JSON: %v", err)
+		fail(c, errInvalidPayload)
+		return
+	}
+
+	rule, err := service.UpdateRule(c.Request.Context(), c.GetString("user_id"), id, input)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	logger.WithField("rule_id", id).Info("Updated rule")
+	c.JSON(http.StatusOK, gin.H{"rule": rule})
+}
+
+// DeleteRule godoc
+// @Summary      Delete a categorization rule
+// @Tags         rules
+// @Produce      json
+// @Param        id   path      string  true  "Rule ID"
+// @Success      200  {object}  map[string]string
+// @Router       /api/v1/rules/{id} [delete]
+// @Security     BearerAuth
+func DeleteRule(c *gin.Context) {
+	id := c.Param("id")
+	if err := service.DeleteRule(c.Request.Context(), c.GetString("user_id"), id); err != nil {
+		fail(c, err)
+		return
+	}
+	logging.FromContext(c).WithField("rule_id", id).Info("Deleted rule")
+	c.JSON(http.StatusOK, gin.H{"message": "Rule deleted"})
+}
+
+// TryRules godoc
+// @Summary      Test categorization rules
+// @Description  Show which rules match an expense and what they would make of it, without storing anything. Pass rule to try an unsaved rule on its own; without it your saved rules run.
+// @Tags         rules
+// @Accept       json
+// @Produce      json
+// @Param        test  body      service.RuleTestInput  true  "Expense and optional rule"
+// @Success      200   {object}  service.RuleTestResult
+// @Failure      400   {object}  problem.Problem
+// @Failure      422   {object}  problem.Problem
+// @Router       /api/v1/rules/test [post]
+// @Security     BearerAuth
+func TryRules(c *gin.Context) {
+	var input service.RuleTestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logging.FromContext(c).Warnf("Unable to bind JSON: %v", err)
+		fail(c, errInvalidPayload)
+		return
+	}
+
+	result, err := service.TryRules(c.Request.Context(), c.GetString("user_id"), input)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// ApplyRules godoc
+// @Summary      Re-apply rules to existing expenses
+// @Description  Run your rules over the expenses matching the filters and save the changes. With dry_run=true nothing is saved. Personal expenses are limited to your own; a household's need the editor role.
+// @Tags         rules
+// @Produce      json
+// @Param        household_id  query     string  false  "Household ID (omit for personal expenses)"
+// @Param        user_id       query     string  false  "Member to limit a household run to"
+// @Param        account_id    query     string  false  "Account ID"
+// @Param        category      query     string  false  "Only expenses in this category"
+// @Param        from          query     string  false  "Start date (YYYY-MM-DD)"
+// @Param        to            query     string  false  "End date (YYYY-MM-DD)"
+// @Param        dry_run       query     bool    false  "Report the changes without saving them"
+// @Success      200           {object}  service.RuleApplyReport
+// @Failure      400           {object}  problem.Problem
+// @Failure      403           {object}  problem.Problem
+// @Router       /api/v1/rules/apply [post]
+// @Security     BearerAuth
+func ApplyRules(c *gin.Context) {
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		fail(c, problem.BadRequest("dry_run must be true or false"))
+		return
+	}
+	filter := service.ExpenseFilter{
+		UserID:      c.Query("user_id"),
+		HouseholdID: c.Query("household_id"),
+		AccountID:   c.Query("account_id"),
+		Category:    c.Query("category"),
+		From:        c.Query("from"),
+		To:          c.Query("to"),
+	}
+
+	report, err := service.ApplyRules(c.Request.Context(), c.GetString("user_id"), filter, dryRun)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	logging.FromContext(c).WithFields(log.Fields{
+		"checked": report.Checked,
+		"changed": len(report.Changed),
+		"dry_run": dryRun,
+	}).Info("Applied rules")
+	c.JSON(http.StatusOK, report)
+}
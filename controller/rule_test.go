@@ -0,0 +1,259 @@
+package controller_test
+
+import (
+	"context"
+	"expense-tracker/model"
+	"expense-tracker/service"
+	"expense-tracker/store"
+	"expense-tracker/testutil"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createRule(t *testing.T, r http.Handler, token string, body map[string]interface{}) string {
+	t.Helper()
+	w := testutil.Do(t, r, http.MethodPost, "/api/v1/rules/", token, body)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created struct{ Rule struct{ Id string } }
+	testutil.Decode(t, w, &created)
+	return created.Rule.Id
+}
+
+func TestRules_CategorizeNewExpenses(t *testing.T) {
+	r := testutil.Router(t)
+	token := testutil.Token(t, testutil.User().Create(t))
+	createRule(t, r, token, map[string]interface{}{
+		"name": "Coffee", "priority": 10,
+		"conditions": map[string]interface{}{"description_contains": "starbucks", "max_amount": 20},
+		"actions":    map[string]interface{}{"category": "coffee", "tags": []string{"Treats"}, "description": "Starbucks"},
+	})
+	createRule(t, r, token, map[string]interface{}{
+		"name": "Catch-all card", "priority": 20,
+		"conditions": map[string]interface{}{"currency": "usd"},
+		"actions":    map[string]interface{}{"category": "card", "tags": []string{"card"}},
+	})
+
+	var created struct{ Expense model.Expense }
+	w := testutil.Do(t, r, http.MethodPost, "/api/v1/expenses/", token, map[string]interface{}{
+		"amount": 4.5, "description": "STARBUCKS #1234 SEATTLE", "tags": []string{"work"}})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	testutil.Decode(t, w, &created)
+	assert.Equal(t, "coffee", created.Expense.Category, "the first rule by priority wins")
+	assert.Equal(t, "Starbucks", created.Expense.Description)
+	assert.Equal(t, []string{"work", "treats", "card"}, created.Expense.Tags)
+
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/expenses/", token, map[string]interface{}{
+		"amount": 45, "description": "STARBUCKS RESERVE"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	testutil.Decode(t, w, &created)
+	assert.Equal(t, "card", created.Expense.Category, "out of the coffee rule's amount range")
+
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/expenses/", token, map[string]interface{}{
+		"amount": 4, "currency": "EUR", "description": "Bakery"})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code, "no rule supplied the category")
+
+	other := testutil.Token(t, testutil.User().Create(t))
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/expenses/", other, map[string]interface{}{
+		"amount": 4.5, "category": "food", "description": "STARBUCKS"})
+	testutil.Decode(t, w, &created)
+	assert.Equal(t, "food", created.Expense.Category, "rules are per user")
+}
+
+func TestRules_AppliedOnImport(t *testing.T) {
+	r := testutil.Router(t)
+	token := testutil.Token(t, testutil.User().Create(t))
+	createRule(t, r, token, map[string]interface{}{
+		"name":       "Groceries",
+		"conditions": map[string]interface{}{"description_regex": "^CORNER\\s+GROCERY$"},
+		"actions":    map[string]interface{}{"category": "groceries"},
+	})
+
+	w := testutil.Upload(t, r, "/api/v1/imports/", token, "march.ofx", []byte(statementOFX), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var list struct{ Expenses []model.Expense }
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/", token, nil)
+	testutil.Decode(t, w, &list)
+	require.Len(t, list.Expenses, 1)
+	assert.Equal(t, "groceries", list.Expenses[0].Category)
+}
+
+// countingRules counts how often a user's rules are listed.
+type countingRules struct {
+	store.RuleStore
+	lists int
+}
+
+func (c *countingRules) List(ctx context.Context, userID string) ([]model.Rule, error) {
+	c.lists++
+	return c.RuleStore.List(ctx, userID)
+}
+
+func TestRules_LoadedOncePerImport(t *testing.T) {
+	r := testutil.Router(t)
+	token := testutil.Token(t, testutil.User().Create(t))
+	createRule(t, r, token, map[string]interface{}{
+		"name":       "Shops",
+		"conditions": map[string]interface{}{"description_contains": "shop"},
+		"actions":    map[string]interface{}{"category": "shopping"},
+	})
+	counting := &countingRules{RuleStore: service.Rules}
+	service.Rules = counting
+	t.Cleanup(func() { service.Rules = counting.RuleStore })
+
+	var lines strings.Builder
+	for i := 1; i <= 5; i++ {
+		fmt.Fprintf(&lines, "<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>2026030%d<TRNAMT>-%d.00<FITID>S%d<NAME>SHOP %d</STMTTRN>\n", i, i, i, i)
+	}
+	ofx := strings.Replace(statementOFX, "<BANKTRANLIST>\n", "<BANKTRANLIST>\n"+lines.String(), 1)
+	w := testutil.Upload(t, r, "/api/v1/imports/", token, "march.ofx", []byte(ofx), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var report service.ImportReport
+	testutil.Decode(t, w, &report)
+	assert.Len(t, report.New, 7)
+	assert.Equal(t, 1, counting.lists, "one statement, one read of the rules")
+}
+
+func TestRules_KeepGivenCategory(t *testing.T) {
+	r := testutil.Router(t)
+	token := testutil.Token(t, testutil.User().Create(t))
+	createRule(t, r, token, map[string]interface{}{
+		"name":       "Grocery",
+		"conditions": map[string]interface{}{"description_contains": "grocery"},
+		"actions":    map[string]interface{}{"category": "groceries", "tags": []string{"food"}},
+	})
+
+	var created struct{ Expense model.Expense }
+	w := testutil.Do(t, r, http.MethodPost, "/api/v1/expenses/", token, map[string]interface{}{
+		"amount": 12, "category": "gifts", "description": "Corner grocery flowers"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	testutil.Decode(t, w, &created)
+	assert.Equal(t, "gifts", created.Expense.Category, "the caller's category wins over the rule's")
+	assert.Equal(t, []string{"food"}, created.Expense.Tags, "the rule's other actions still apply")
+
+	w = testutil.Upload(t, r, "/api/v1/imports/", token, "march.ofx", []byte(statementOFX), map[string]string{"category": "household"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var list struct{ Expenses []model.Expense }
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/?to=2026-03-31", token, nil)
+	testutil.Decode(t, w, &list)
+	require.Len(t, list.Expenses, 1)
+	assert.Equal(t, "household", list.Expenses[0].Category, "an import's category is kept")
+
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/rules/test", token, map[string]interface{}{
+		"expense": map[string]interface{}{"amount": 3, "category": "gifts", "description": "grocery"}})
+	var tried service.RuleTestResult
+	testutil.Decode(t, w, &tried)
+	assert.Equal(t, "gifts", tried.Result.Category)
+	assert.Len(t, tried.Matched, 1)
+}
+
+func TestRules_TestAndValidate(t *testing.T) {
+	r := testutil.Router(t)
+	token := testutil.Token(t, testutil.User().Create(t))
+
+	w := testutil.Do(t, r, http.MethodPost, "/api/v1/rules/", token, map[string]interface{}{
+		"name":       "Broken",
+		"conditions": map[string]interface{}{"description_regex": "(", "min_amount": 10, "max_amount": 5},
+	})
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	for _, field := range []string{"conditions.description_regex", "conditions.max_amount", "actions"} {
+		assert.Contains(t, w.Body.String(), `"field":"`+field+`"`)
+	}
+
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/rules/test", token, map[string]interface{}{
+		"rule": map[string]interface{}{
+			"name":       "Uber",
+			"conditions": map[string]interface{}{"description_regex": "(?i)uber\\s+trip"},
+			"actions":    map[string]interface{}{"category": "transport", "tags": []string{"taxi"}},
+		},
+		"expense": map[string]interface{}{"amount": 12, "description": "Uber Trip HELP.UBER.COM"},
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result service.RuleTestResult
+	testutil.Decode(t, w, &result)
+	require.Len(t, result.Matched, 1)
+	assert.Equal(t, "Uber", result.Matched[0].Name)
+	assert.Equal(t, service.Categorization{Category: "transport", Description: "Uber Trip HELP.UBER.COM", Tags: []string{"taxi"}}, result.Result)
+
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/rules/test", token, map[string]interface{}{
+		"expense": map[string]interface{}{"amount": 12, "description": "Uber Trip"},
+	})
+	testutil.Decode(t, w, &result)
+	assert.Empty(t, result.Matched, "nothing is saved by testing")
+}
+
+func TestRules_ApplyToHistory(t *testing.T) {
+	r := testutil.Router(t)
+	alice := testutil.User().Create(t)
+	bob := testutil.User().Create(t)
+	token := testutil.Token(t, alice)
+	old := testutil.Expense().Owner(alice).Category("misc").Description("UBER TRIP").Create(t)
+	testutil.Expense().Owner(alice).Category("misc").Description("Cinema").Create(t)
+	bobs := testutil.Expense().Owner(bob).Category("misc").Description("UBER TRIP").Create(t)
+	id := createRule(t, r, token, map[string]interface{}{
+		"name":       "Uber",
+		"conditions": map[string]interface{}{"description_contains": "uber"},
+		"actions":    map[string]interface{}{"category": "transport"},
+	})
+
+	w := testutil.Do(t, r, http.MethodPost, "/api/v1/rules/apply?dry_run=true", token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var report service.RuleApplyReport
+	testutil.Decode(t, w, &report)
+	assert.Equal(t, 2, report.Checked, "only the caller's personal expenses")
+	require.Len(t, report.Changed, 1)
+	assert.Equal(t, service.RuleChange{
+		ExpenseID: old.Id, Rules: []string{id},
+		Before: service.Categorization{Category: "misc", Description: "UBER TRIP", Tags: []string{}},
+		After:  service.Categorization{Category: "transport", Description: "UBER TRIP", Tags: []string{}},
+	}, report.Changed[0])
+	got, err := service.Expenses.Get(context.Background(), old.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "misc", got.Category, "a dry run saves nothing")
+
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/rules/apply", token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	got, _ = service.Expenses.Get(context.Background(), old.Id)
+	assert.Equal(t, "transport", got.Category)
+	got, _ = service.Expenses.Get(context.Background(), bobs.Id)
+	assert.Equal(t, "misc", got.Category)
+
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/rules/"+id, testutil.Token(t, bob), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/rules/apply?dry_run=maybe", token, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestRules_ApplyToMoreThanOneBatch(t *testing.T) {
+	r := testutil.Router(t)
+	user := testutil.User().Create(t)
+	token := testutil.Token(t, user)
+	for i := range 1100 {
+		testutil.Expense().Owner(user).Amount(1).Category("misc").Description("UBER TRIP").
+			At(testutil.BaseTime.Add(time.Duration(i/3) * time.Minute)).Create(t)
+	}
+	createRule(t, r, token, map[string]interface{}{
+		"name":       "Uber",
+		"conditions": map[string]interface{}{"description_contains": "uber"},
+		"actions":    map[string]interface{}{"category": "transport"},
+	})
+
+	// Filtering on the category being changed must not make the scan skip
+	// or repeat expenses as they leave it.
+	w := testutil.Do(t, r, http.MethodPost, "/api/v1/rules/apply?category=misc", token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var report service.RuleApplyReport
+	testutil.Decode(t, w, &report)
+	assert.Equal(t, 1100, report.Checked)
+	assert.Len(t, report.Changed, 1100)
+
+	var summary map[string]float64
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/summary", token, nil)
+	testutil.Decode(t, w, &summary)
+	assert.Equal(t, map[string]float64{"transport": 1100}, summary)
+}
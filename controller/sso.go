@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"errors"
+	"expense-tracker/auth"
+	"expense-tracker/logging"
+	"expense-tracker/problem"
+	"expense-tracker/service"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ssoStateCookie carries the signed OIDC state between SSOLogin (or
+// LinkSSO) and SSOCallback.
+const ssoStateCookie = "oidc_state"
+
+// setSSOState stores the state token in a cookie scoped to the OIDC routes.
+// SameSite=Lax lets it ride along on the provider's top-level redirect.
+func setSSOState(c *gin.Context, stateToken string) {
+	secure := strings.HasPrefix(service.SSO.Config().RedirectURL, "https://")
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(ssoStateCookie, stateToken, int(auth.OIDCStateTTL.Seconds()), "/api/v1/auth/oidc", "", secure, true)
+}
+
+// SSOLogin godoc
+// @Summary      Sign in with the OIDC provider
+// @Description  Redirect the browser to the configured OpenID Connect provider. It returns to /api/v1/auth/oidc/callback.
+// @Tags         users
+// @Success      302
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/auth/oidc/login [get]
+func SSOLogin(c *gin.Context) {
+	authURL, stateToken, err := service.BeginSSO(c.Request.Context(), "")
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	setSSOState(c, stateToken)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// LinkSSO godoc
+// @Summary      Link an OIDC identity
+// @Description  Start a sign-in at the OpenID Connect provider that links the identity to the caller's account. Send the browser to the returned auth_url.
+// @Tags         users
+// @Produce      json
+// @Success      200  {object}  map[string]string
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/auth/oidc/link [post]
+// @Security     BearerAuth
+func LinkSSO(c *gin.Context) {
+	authURL, stateToken, err := service.BeginSSO(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	setSSOState(c, stateToken)
+	c.JSON(http.StatusOK, gin.H{"auth_url": authURL})
+}
+
+// SSOCallback godoc
+// @Summary      OIDC sign-in callback
+// @Description  Complete a sign-in started at /api/v1/auth/oidc/login or /api/v1/auth/oidc/link and return a JWT token, or a two-factor challenge like /api/v1/login. First-time identities get a new account if oidc.auto_provision is on.
+// @Tags         users
+// @Produce      json
+// @Param        code   query     string  true  "Authorization code"
+// @Param        state  query     string  true  "State"
+// @Success      200    {object}  map[string]interface{}
+// @Failure      401    {object}  problem.Problem
+// @Failure      403    {object}  problem.Problem
+// @Failure      404    {object}  problem.Problem
+// @Failure      409    {object}  problem.Problem
+// @Failure      500    {object}  problem.Problem
+// @Router       /api/v1/auth/oidc/callback [get]
+func SSOCallback(c *gin.Context) {
+	logger := logging.FromContext(c)
+	if service.SSO == nil {
+		fail(c, service.ErrSSODisabled)
+		return
+	}
+	stateToken, _ := c.Cookie(ssoStateCookie)
+	setSSOState(c, "")
+	if reason := c.Query("error"); reason != "" {
+		logger.WithField("oidc_error", reason).Warn("Single sign-on refused by provider")
+		fail(c, problem.New(http.StatusUnauthorized, problem.CodeSSOFailed, "Sign-in was refused by the provider"))
+		return
+	}
+
+	user, token, err := service.CompleteSSO(c.Request.Context(), stateToken, c.Query("state"), c.Query("code"))
+	switch {
+	case errors.Is(err, service.ErrTwoFactorRequired):
+		logger.WithField("login_user_id", user.UserId).Info("Login needs a second factor")
+		c.JSON(http.StatusOK, gin.H{"two_factor_required": true, "challenge_token": token})
+		return
+	case errors.Is(err, service.ErrSSOFailed):
+		logger.Warnf("Single sign-on failed: %v", err)
+	case errors.Is(err, service.ErrIdentityLinked), errors.Is(err, service.ErrAccountExists):
+		logger.Warnf("Single sign-on conflict: %v", err)
+	}
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	logger.WithField("login_user_id", user.UserId).Info("Login succeeded via single sign-on")
+	c.JSON(http.StatusOK, gin.H{"user": user.UserName, "user_id": user.UserId, "token": token})
+}
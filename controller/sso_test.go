@@ -0,0 +1,183 @@
+package controller_test
+
+import (
+	"context"
+	"expense-tracker/config"
+	"expense-tracker/oidc"
+	"expense-tracker/oidc/oidctest"
+	"expense-tracker/service"
+	"expense-tracker/testutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useSSO starts a mock provider and points service.SSO at it.
+func useSSO(t *testing.T, autoProvision bool) *oidctest.Server {
+	t.Helper()
+	mock := oidctest.NewServer("tracker", "client-secret")
+	t.Cleanup(mock.Close)
+	cfg := config.Default().OIDC
+	cfg.Issuer, cfg.ClientID, cfg.ClientSecret = mock.Issuer(), "tracker", "client-secret"
+	cfg.RedirectURL = "http://tracker.test/api/v1/auth/oidc/callback"
+	cfg.AutoProvision = autoProvision
+	provider, err := oidc.Discover(context.Background(), cfg, nil)
+	require.NoError(t, err)
+	prev := service.SSO
+	service.SSO = provider
+	t.Cleanup(func() { service.SSO = prev })
+	return mock
+}
+
+// followProvider sends the browser to authURL and returns the callback path
+// the provider redirects back to.
+func followProvider(t *testing.T, authURL string) string {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	back, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return back.RequestURI()
+}
+
+// callback delivers the provider's redirect with the state cookie.
+func callback(t *testing.T, r http.Handler, path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func stateCookie(t *testing.T, w *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "oidc_state" {
+			assert.True(t, c.HttpOnly)
+			return c
+		}
+	}
+	t.Fatal("no oidc_state cookie set")
+	return nil
+}
+
+// ssoLogin runs the whole browser flow and returns the callback response.
+func ssoLogin(t *testing.T, r http.Handler) *httptest.ResponseRecorder {
+	t.Helper()
+	w := testutil.Do(t, r, http.MethodGet, "/api/v1/auth/oidc/login", "", nil)
+	require.Equal(t, http.StatusFound, w.Code)
+	return callback(t, r, followProvider(t, w.Header().Get("Location")), stateCookie(t, w))
+}
+
+type ssoSession struct {
+	User              string
+	UserID            string `json:"user_id"`
+	Token             string
+	TwoFactorRequired bool `json:"two_factor_required"`
+}
+
+func TestSSO_ProvisionsAndSignsIn(t *testing.T) {
+	r := testutil.Router(t)
+	mock := useSSO(t, true)
+	mock.SignInAs(map[string]interface{}{"sub": "idp-42", "preferred_username": "carol", "email": "carol@example.com"})
+
+	w := ssoLogin(t, r)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var first ssoSession
+	testutil.Decode(t, w, &first)
+	assert.Equal(t, "carol", first.User)
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/", first.Token, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = ssoLogin(t, r)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var second ssoSession
+	testutil.Decode(t, w, &second)
+	assert.Equal(t, first.UserID, second.UserID)
+}
+
+func TestSSO_RejectsForgedCallbacks(t *testing.T) {
+	r := testutil.Router(t)
+	useSSO(t, true)
+
+	w := testutil.Do(t, r, http.MethodGet, "/api/v1/auth/oidc/login", "", nil)
+	path := followProvider(t, w.Header().Get("Location"))
+	assert.Equal(t, http.StatusUnauthorized, callback(t, r, path, nil).Code, "no state cookie")
+
+	other := testutil.Do(t, r, http.MethodGet, "/api/v1/auth/oidc/login", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, callback(t, r, path, stateCookie(t, other)).Code, "state from another attempt")
+
+	assert.Equal(t, http.StatusOK, callback(t, r, path, stateCookie(t, w)).Code)
+	assert.Equal(t, http.StatusUnauthorized, callback(t, r, path, stateCookie(t, w)).Code, "code replayed")
+
+	w = callback(t, r, "/api/v1/auth/oidc/callback?error=access_denied", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestSSO_LinksExistingAccount(t *testing.T) {
+	r := testutil.Router(t)
+	mock := useSSO(t, true)
+	alice := testutil.User().Create(t)
+	mock.SignInAs(map[string]interface{}{"sub": "idp-alice", "preferred_username": alice.UserName})
+
+	w := ssoLogin(t, r)
+	assert.Equal(t, http.StatusConflict, w.Code, "the name is taken, so the account must be linked first")
+
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/auth/oidc/link", testutil.Token(t, alice), nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var link map[string]string
+	testutil.Decode(t, w, &link)
+	w = callback(t, r, followProvider(t, link["auth_url"]), stateCookie(t, w))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = ssoLogin(t, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	var session ssoSession
+	testutil.Decode(t, w, &session)
+	assert.Equal(t, alice.UserId, session.UserID)
+
+	// The identity cannot then be linked to someone else.
+	bob := testutil.User().Create(t)
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/auth/oidc/link", testutil.Token(t, bob), nil)
+	testutil.Decode(t, w, &link)
+	w = callback(t, r, followProvider(t, link["auth_url"]), stateCookie(t, w))
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestSSO_WithoutAutoProvisioning(t *testing.T) {
+	r := testutil.Router(t)
+	useSSO(t, false)
+	assert.Equal(t, http.StatusForbidden, ssoLogin(t, r).Code)
+}
+
+func TestSSO_HonoursTwoFactor(t *testing.T) {
+	r := testutil.Router(t)
+	mock := useSSO(t, true)
+	mock.SignInAs(map[string]interface{}{"sub": "idp-dave", "preferred_username": "dave"})
+	w := ssoLogin(t, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	var session ssoSession
+	testutil.Decode(t, w, &session)
+	enroll(t, r, session.Token)
+
+	w = ssoLogin(t, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	testutil.Decode(t, w, &session)
+	assert.True(t, session.TwoFactorRequired)
+}
+
+func TestSSO_Disabled(t *testing.T) {
+	r := testutil.Router(t)
+	w := testutil.Do(t, r, http.MethodGet, "/api/v1/auth/oidc/login", "", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,138 @@
+package controller_test
+
+import (
+	"expense-tracker/model"
+	"expense-tracker/service"
+	"expense-tracker/testutil"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func suggest(t *testing.T, r http.Handler, token, description string) []service.CategorySuggestion {
+	t.Helper()
+	w := testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/suggest-category?description="+url.QueryEscape(description), token, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct{ Suggestions []service.CategorySuggestion }
+	testutil.Decode(t, w, &resp)
+	return resp.Suggestions
+}
+
+func TestSuggestCategory_LearnsFromHistory(t *testing.T) {
+	r := testutil.Router(t)
+	user := testutil.User().Create(t)
+	token := testutil.Token(t, user)
+	for _, d := range []string{"Starbucks Seattle", "STARBUCKS #88", "Blue Bottle coffee"} {
+		testutil.Expense().Owner(user).Category("coffee").Description(d).Create(t)
+	}
+	testutil.Expense().Owner(user).Category("transport").Description("Uber trip").Create(t)
+	testutil.Expense().Owner(user).Category("transport").Description("Metro card").Create(t)
+	testutil.Expense().Owner(testutil.User().Create(t)).Category("secret").Description("Starbucks").Create(t)
+
+	got := suggest(t, r, token, "STARBUCKS 1520 PIKE")
+	require.Len(t, got, 2, "only the caller's categories")
+	assert.Equal(t, "coffee", got[0].Category)
+	assert.Greater(t, got[0].Confidence, service.SuggestionThreshold)
+	assert.Empty(t, suggest(t, r, token, "Netflix"))
+
+	var created struct{ Expense model.Expense }
+	w := testutil.Do(t, r, http.MethodPost, "/api/v1/expenses/", token, map[string]interface{}{"amount": 3.2, "description": "Starbucks Reserve"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	testutil.Decode(t, w, &created)
+	assert.Equal(t, "coffee", created.Expense.Category)
+
+	// Recategorizing, adding and deleting expenses retrain the model.
+	w = testutil.Do(t, r, http.MethodPut, "/api/v1/expenses/"+created.Expense.Id, token, map[string]interface{}{
+		"amount": 3.2, "category": "gifts", "description": "Netflix gift card"})
+	require.Equal(t, http.StatusOK, w.Code)
+	got = suggest(t, r, token, "netflix")
+	require.NotEmpty(t, got)
+	assert.Equal(t, "gifts", got[0].Category)
+	w = testutil.Do(t, r, http.MethodDelete, "/api/v1/expenses/"+created.Expense.Id, token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, suggest(t, r, token, "netflix"))
+
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/suggest-category", token, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/suggest-category?description=x&limit=50", token, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSuggestCategory_CategorizesImports(t *testing.T) {
+	r := testutil.Router(t)
+	user := testutil.User().Create(t)
+	token := testutil.Token(t, user)
+	testutil.Expense().Owner(user).Category("groceries").Description("Corner grocery store").Create(t)
+	testutil.Expense().Owner(user).Category("groceries").Description("Farmers market").Create(t)
+	testutil.Expense().Owner(user).Category("transport").Description("Metro card").Create(t)
+	testutil.Expense().Owner(user).Category("transport").Description("Uber trip").Create(t)
+	testutil.Expense().Owner(user).Category("transport").Description("Train ticket").Create(t)
+
+	qif := "!Type:Bank\nD03/01/2026\nT-20.00\nPCORNER GROCERY\n^\nD03/02/2026\nT-9.00\nPBOOKSHOP\n^\n"
+	w := testutil.Upload(t, r, "/api/v1/imports/", token, "march.qif", []byte(qif), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	categories := map[string]string{}
+	var list struct{ Expenses []model.Expense }
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/?user_id="+user.UserId, token, nil)
+	testutil.Decode(t, w, &list)
+	for _, e := range list.Expenses {
+		categories[e.Description] = e.Category
+	}
+	assert.Equal(t, "groceries", categories["CORNER GROCERY"])
+	assert.Equal(t, service.ImportCategory, categories["BOOKSHOP"], "nothing to go on")
+}
+
+func TestSuggestCategory_NeedsEnoughHistory(t *testing.T) {
+	r := testutil.Router(t)
+	user := testutil.User().Create(t)
+	token := testutil.Token(t, user)
+	for _, d := range []string{"Starbucks Seattle", "STARBUCKS #88", "Blue Bottle", "Starbucks Pike", "Starbucks Reserve"} {
+		testutil.Expense().Owner(user).Category("coffee").Description(d).Create(t)
+	}
+
+	got := suggest(t, r, token, "Starbucks")
+	require.Len(t, got, 1)
+	assert.Equal(t, 1.0, got[0].Confidence, "the only category is always certain")
+	w := testutil.Do(t, r, http.MethodPost, "/api/v1/expenses/", token, map[string]interface{}{"amount": 3, "description": "Starbucks"})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code, "one category is not enough to go on")
+
+	newcomer := testutil.User().Create(t)
+	testutil.Expense().Owner(newcomer).Category("coffee").Description("Starbucks").Create(t)
+	testutil.Expense().Owner(newcomer).Category("transport").Description("Uber").Create(t)
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/expenses/", testutil.Token(t, newcomer), map[string]interface{}{"amount": 3, "description": "Starbucks"})
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code, "two expenses are not enough to go on")
+
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/expenses/", token, map[string]interface{}{"amount": 9, "category": "transport", "description": "Uber trip"})
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created struct{ Expense model.Expense }
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/expenses/", token, map[string]interface{}{"amount": 3, "description": "Starbucks"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	testutil.Decode(t, w, &created)
+	assert.Equal(t, "coffee", created.Expense.Category)
+}
+
+func TestSuggestCategory_TrainsOnEveryExpense(t *testing.T) {
+	r := testutil.Router(t)
+	user := testutil.User().Create(t)
+	token := testutil.Token(t, user)
+	// More history than is read at a time, each expense with a word of its
+	// own, so an expense the model missed gets no suggestion.
+	categories := []string{"coffee", "transport", "groceries"}
+	word := func(i int) string { return fmt.Sprintf("shop%c%c", 'a'+i/26, 'a'+i%26) }
+	for i := range 600 {
+		testutil.Expense().Owner(user).Category(categories[i%3]).Description(word(i)).
+			At(testutil.BaseTime.Add(time.Duration(i/3) * time.Minute)).Create(t)
+	}
+
+	for i := range 600 {
+		got := suggest(t, r, token, word(i))
+		require.NotEmpty(t, got, word(i))
+		assert.Equal(t, categories[i%3], got[0].Category, word(i))
+	}
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"expense-tracker/logging"
+	"expense-tracker/problem"
+	"expense-tracker/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// CreateTransfer godoc
+// @Summary      Transfer between accounts
+// @Description  Move money between two accounts. Transfers change both balances but are not income or spending. Between currencies, to_amount defaults to the amount converted with the configured exchange rates.
+// @Tags         accounts
+// @Accept       json
+// @Produce      json
+// @Param        transfer  body      service.TransferInput  true  "Transfer data"
+// @Success      201       {object}  model.Transfer
+// @Failure      400       {object}  problem.Problem
+// @Failure      403       {object}  problem.Problem
+// @Failure      422       {object}  problem.Problem
+// @Router       /api/v1/transfers [post]
+// @Security     BearerAuth
+func CreateTransfer(c *gin.Context) {
+	logger := logging.FromContext(c)
+
+	var input service.TransferInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		logger.Warnf("Unable to bind JSON: %v", err)
+		fail(c, errInvalidPayload)
+		return
+	}
+
+	transfer, err := service.CreateTransfer(c.Request.Context(), c.GetString("user_id"), input)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	logger.WithFields(log.Fields{
+		"transfer_id":     transfer.Id,
+		"from_account_id": transfer.FromAccountId,
+		"to_account_id":   transfer.ToAccountId,
+	}).Info("Created transfer")
+	c.JSON(http.StatusCreated, gin.H{"transfer": transfer})
+}
+
+// ListTransfers godoc
+// @Summary      List transfers
+// @Description  List the transfers into or out of an account, oldest first
+// @Tags         accounts
+// @Produce      json
+// @Param        account_id  query     string  true  "Account ID"
+// @Success      200         {object}  []model.Transfer
+// @Failure      400         {object}  problem.Problem
+// @Failure      403         {object}  problem.Problem
+// @Failure      404         {object}  problem.Problem
+// @Router       /api/v1/transfers [get]
+// @Security     BearerAuth
+func ListTransfers(c *gin.Context) {
+	accountID := c.Query("account_id")
+	if accountID == "" {
+		fail(c, problem.BadRequest("account_id is required"))
+		return
+	}
+	transfers, err := service.ListTransfers(c.Request.Context(), c.GetString("user_id"), accountID)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"transfers": transfers})
+}
+
+// DeleteTransfer godoc
+// @Summary      Delete a transfer
+// @Description  Delete a transfer by ID; needs edit access to both accounts
+// @Tags         accounts
+// @Produce      json
+// @Param        id   path      string  true  "Transfer ID"
+// @Success      200  {object}  map[string]string
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Router       /api/v1/transfers/{id} [delete]
+// @Security     BearerAuth
+func DeleteTransfer(c *gin.Context) {
+	id := c.Param("id")
+	if err := service.DeleteTransfer(c.Request.Context(), c.GetString("user_id"), id); err != nil {
+		fail(c, err)
+		return
+	}
+	logging.FromContext(c).WithField("transfer_id", id).Info("Deleted transfer")
+	c.JSON(http.StatusOK, gin.H{"message": "Transfer deleted"})
+}
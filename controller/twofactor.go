@@ -0,0 +1,185 @@
+package controller
+
+import (
+	"errors"
+	"expense-tracker/logging"
+	"expense-tracker/problem"
+	"expense-tracker/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoginTwoFactor godoc
+// @Summary      Complete a two-factor login
+// @Description  Exchange the challenge token from /api/v1/login and a TOTP or recovery code for a JWT token
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        login  body      object  true  "Challenge token and code"  example({"challenge_token":"...","code":"123456"})
+// @Success      200    {object}  map[string]string
+// @Failure      400    {object}  problem.Problem
+// @Failure      401    {object}  problem.Problem
+// @Failure      429    {object}  problem.Problem
+// @Failure      500    {object}  problem.Problem
+// @Router       /api/v1/login/2fa [post]
+func LoginTwoFactor(c *gin.Context) {
+	var req struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.ChallengeToken == "" || req.Code == "" {
+		fail(c, errInvalidPayload)
+		return
+	}
+	logger := logging.FromContext(c)
+	user, token, err := service.CompleteLogin(c.Request.Context(), req.ChallengeToken, req.Code, c.ClientIP())
+	switch {
+	case errors.Is(err, service.ErrInvalidCode):
+		logger.Warn("Login failed: invalid second factor")
+	case errors.Is(err, service.ErrLockedOut):
+		logger.Warn("Login refused: too many failed attempts")
+	}
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	logger.WithField("login_user_id", user.UserId).Info("Login succeeded")
+	c.JSON(http.StatusOK, gin.H{"user": user.UserName, "user_id": user.UserId, "token": token})
+}
+
+// GetTwoFactor godoc
+// @Summary      Two-factor status
+// @Description  Report whether two-factor authentication is enabled and how many recovery codes are left
+// @Tags         users
+// @Produce      json
+// @Success      200  {object}  service.TwoFactorStatus
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/auth/2fa [get]
+// @Security     BearerAuth
+func GetTwoFactor(c *gin.Context) {
+	status, err := service.GetTwoFactorStatus(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// EnrollTwoFactor godoc
+// @Summary      Start two-factor enrolment
+// @Description  Generate a TOTP secret and otpauth URI for an authenticator app. Confirm with a code at /api/v1/auth/2fa/confirm.
+// @Tags         users
+// @Produce      json
+// @Success      200  {object}  map[string]string
+// @Failure      409  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/auth/2fa/enroll [post]
+// @Security     BearerAuth
+func EnrollTwoFactor(c *gin.Context) {
+	secret, uri, err := service.EnrollTwoFactor(c.Request.Context(), c.GetString("user_id"))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"secret": secret, "otpauth_uri": uri})
+}
+
+// ConfirmTwoFactor godoc
+// @Summary      Confirm two-factor enrolment
+// @Description  Enable two-factor authentication with a first code from the authenticator app. The returned recovery codes are shown only once.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        code  body      object  true  "TOTP code"  example({"code":"123456"})
+// @Success      200   {object}  map[string][]string
+// @Failure      400   {object}  problem.Problem
+// @Failure      401   {object}  problem.Problem
+// @Failure      409   {object}  problem.Problem
+// @Failure      500   {object}  problem.Problem
+// @Router       /api/v1/auth/2fa/confirm [post]
+// @Security     BearerAuth
+func ConfirmTwoFactor(c *gin.Context) {
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		fail(c, errInvalidPayload)
+		return
+	}
+	logger := logging.FromContext(c)
+	codes, err := service.ConfirmTwoFactor(c.Request.Context(), c.GetString("user_id"), req.Code)
+	if errors.Is(err, service.ErrTwoFactorNotEnabled) {
+		fail(c, problem.New(http.StatusBadRequest, problem.CodeTwoFactorNotEnabled, "Start enrolment first"))
+		return
+	}
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	logger.Info("Two-factor authentication enabled")
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// DisableTwoFactor godoc
+// @Summary      Disable two-factor authentication
+// @Description  Turn two-factor authentication off; needs the password and a TOTP or recovery code
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        disable  body      object  true  "Password and code"  example({"password":"mypassword","code":"123456"})
+// @Success      200      {object}  map[string]string
+// @Failure      400      {object}  problem.Problem
+// @Failure      401      {object}  problem.Problem
+// @Failure      500      {object}  problem.Problem
+// @Router       /api/v1/auth/2fa/disable [post]
+// @Security     BearerAuth
+func DisableTwoFactor(c *gin.Context) {
+	var req struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Password == "" || req.Code == "" {
+		fail(c, errInvalidPayload)
+		return
+	}
+	logger := logging.FromContext(c)
+	err := service.DisableTwoFactor(c.Request.Context(), c.GetString("user_id"), req.Password, req.Code)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	logger.Info("Two-factor authentication disabled")
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// ResetUserTwoFactor godoc
+// @Summary      Reset a user's two-factor authentication (admin)
+// @Description  Turn off two-factor authentication for a user who lost their device and recovery codes
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {object}  map[string]string
+// @Failure      403  {object}  problem.Problem
+// @Failure      404  {object}  problem.Problem
+// @Failure      500  {object}  problem.Problem
+// @Router       /api/v1/admin/users/{id}/2fa [delete]
+// @Security     BearerAuth
+func ResetUserTwoFactor(c *gin.Context) {
+	logger := logging.FromContext(c).WithField("target_user_id", c.Param("id"))
+	err := service.ResetTwoFactor(c.Request.Context(), c.GetString("user_id"), c.Param("id"))
+	switch {
+	case errors.Is(err, service.ErrForbidden):
+		logger.Warn("Admin access denied")
+		fail(c, errAdminRequired)
+		return
+	case errors.Is(err, service.ErrNotFound):
+		fail(c, problem.NotFound("User not found"))
+		return
+	case err != nil:
+		fail(c, err)
+		return
+	}
+	logger.Info("Admin reset two-factor authentication")
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication reset"})
+}
@@ -0,0 +1,159 @@
+package controller_test
+
+import (
+	"expense-tracker/auth"
+	"expense-tracker/service"
+	"expense-tracker/testutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// totp returns the current code for secret, offset by steps periods.
+func totp(t *testing.T, secret string, steps int) string {
+	t.Helper()
+	code, err := auth.TOTPCode(secret, auth.TOTPStep(time.Now())+int64(steps))
+	require.NoError(t, err)
+	return code
+}
+
+// enroll turns on two-factor authentication for the token's user and
+// returns the secret and recovery codes.
+func enroll(t *testing.T, r http.Handler, token string) (string, []string) {
+	t.Helper()
+	w := testutil.Do(t, r, http.MethodPost, "/api/v1/auth/2fa/enroll", token, nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var enrolment struct {
+		Secret     string
+		OtpauthURI string `json:"otpauth_uri"`
+	}
+	testutil.Decode(t, w, &enrolment)
+	uri, err := url.Parse(enrolment.OtpauthURI)
+	require.NoError(t, err)
+	assert.Equal(t, enrolment.Secret, uri.Query().Get("secret"))
+	assert.Equal(t, service.TOTPIssuer, uri.Query().Get("issuer"))
+
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/auth/2fa/confirm", token, map[string]string{"code": totp(t, enrolment.Secret, 5)})
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	w = testutil.Do(t, r, http.MethodPost, "/api/v1/auth/2fa/confirm", token, map[string]string{"code": totp(t, enrolment.Secret, -1)})
+	require.Equal(t, http.StatusOK, w.Code)
+	var confirmed struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	testutil.Decode(t, w, &confirmed)
+	require.Len(t, confirmed.RecoveryCodes, service.RecoveryCodeCount)
+	return enrolment.Secret, confirmed.RecoveryCodes
+}
+
+func login(t *testing.T, r http.Handler, userName, password string) *httptest.ResponseRecorder {
+	t.Helper()
+	return testutil.Do(t, r, http.MethodPost, "/api/v1/login", "", map[string]string{"user_name": userName, "password": password})
+}
+
+func TestTwoFactor_EnrolAndLogin(t *testing.T) {
+	r := testutil.Router(t)
+	alice := testutil.User().Create(t)
+	token := testutil.Token(t, alice)
+	secret, recovery := enroll(t, r, token)
+
+	w := testutil.Do(t, r, http.MethodPost, "/api/v1/auth/2fa/enroll", token, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = login(t, r, alice.UserName, testutil.DefaultPassword)
+	require.Equal(t, http.StatusOK, w.Code)
+	var challenge struct {
+		TwoFactorRequired bool   `json:"two_factor_required"`
+		ChallengeToken    string `json:"challenge_token"`
+		Token             string
+	}
+	testutil.Decode(t, w, &challenge)
+	require.True(t, challenge.TwoFactorRequired)
+	assert.Empty(t, challenge.Token)
+
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/", challenge.ChallengeToken, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "a challenge token is not a session")
+
+	second := func(code string) *httptest.ResponseRecorder {
+		return testutil.Do(t, r, http.MethodPost, "/api/v1/login/2fa", "",
+			map[string]string{"challenge_token": challenge.ChallengeToken, "code": code})
+	}
+	assert.Equal(t, http.StatusUnauthorized, second(totp(t, secret, 5)).Code)
+	assert.Equal(t, http.StatusUnauthorized, second(totp(t, secret, -1)).Code, "the code used to confirm cannot be replayed")
+
+	w = second(totp(t, secret, 0))
+	require.Equal(t, http.StatusOK, w.Code)
+	var session map[string]string
+	testutil.Decode(t, w, &session)
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/expenses/", session["token"], nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusUnauthorized, second(totp(t, secret, 0)).Code, "codes are single use")
+
+	// Recovery codes work once each, in any case and without dashes.
+	code := recovery[0]
+	assert.Equal(t, http.StatusOK, second(code).Code)
+	assert.Equal(t, http.StatusUnauthorized, second(code).Code)
+	assert.Equal(t, http.StatusOK, second(toLowerNoDashes(recovery[1])).Code)
+
+	var status service.TwoFactorStatus
+	w = testutil.Do(t, r, http.MethodGet, "/api/v1/auth/2fa", token, nil)
+	testutil.Decode(t, w, &status)
+	assert.Equal(t, service.TwoFactorStatus{Enabled: true, RecoveryCodes: service.RecoveryCodeCount - 2}, status)
+}
+
+func toLowerNoDashes(s string) string {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c != '-' {
+			if c >= 'A' && c <= 'Z' {
+				c += 'a' - 'A'
+			}
+			out = append(out, c)
+		}
+	}
+	return string(out)
+}
+
+func TestTwoFactor_Disable(t *testing.T) {
+	r := testutil.Router(t)
+	alice := testutil.User().Create(t)
+	token := testutil.Token(t, alice)
+	_, recovery := enroll(t, r, token)
+
+	disable := func(password, code string) int {
+		return testutil.Do(t, r, http.MethodPost, "/api/v1/auth/2fa/disable", token,
+			map[string]string{"password": password, "code": code}).Code
+	}
+	assert.Equal(t, http.StatusUnauthorized, disable("wrong", recovery[0]))
+	assert.Equal(t, http.StatusUnauthorized, disable(testutil.DefaultPassword, "123-456"))
+	assert.Equal(t, http.StatusOK, disable(testutil.DefaultPassword, recovery[0]))
+	assert.Equal(t, http.StatusBadRequest, disable(testutil.DefaultPassword, recovery[1]))
+
+	w := login(t, r, alice.UserName, testutil.DefaultPassword)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "challenge_token")
+}
+
+func TestTwoFactor_AdminReset(t *testing.T) {
+	r := testutil.Router(t)
+	admin := testutil.User().Name("root-admin").Create(t)
+	alice := testutil.User().Create(t)
+	testutil.ConfigureService(t, func(c *service.Config) { c.AdminUsers = []string{admin.UserName} })
+	enroll(t, r, testutil.Token(t, alice))
+
+	path := "/api/v1/admin/users/" + alice.UserId + "/2fa"
+	w := testutil.Do(t, r, http.MethodDelete, path, testutil.Token(t, alice), nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = testutil.Do(t, r, http.MethodDelete, "/api/v1/admin/users/nobody/2fa", testutil.Token(t, admin), nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = testutil.Do(t, r, http.MethodDelete, path, testutil.Token(t, admin), nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = login(t, r, alice.UserName, testutil.DefaultPassword)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "challenge_token")
+}
@@ -1,14 +1,12 @@
 package controller
 
 import (
-	"expense-tracker/auth"
-	"expense-tracker/model"
-	"expense-tracker/postgresql"
+	"errors"
+	"expense-tracker/logging"
+	"expense-tracker/service"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // CreateUser godoc
@@ -19,8 +17,8 @@ import (
 // @Produce      json
 // @Param        user  body  object  true  "User credentials"  example({"user_name":"admin","password":"secret"})
 // @Success      201   {object}  map[string]string
-// @Failure      400   {object}  map[string]string
-// @Failure      500   {object}  map[string]string
+// @Failure      400   {object}  problem.Problem
+// @Failure      500   {object}  problem.Problem
 // @Router       /api/v1/users [post]
 // @Security     BearerAuth
 func CreateUser(c *gin.Context) {
@@ -29,21 +27,12 @@ func CreateUser(c *gin.Context) {
 		Password string `json:"password"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil || req.UserName == "" || req.Password == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		fail(c, errInvalidPayload)
 		return
 	}
-	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	user, err := service.CreateUser(c.Request.Context(), req.UserName, req.Password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
-		return
-	}
-	user := model.User{
-		UserId:   uuid.New().String(),
-		UserName: req.UserName,
-		Password: string(hashed),
-	}
-	if err := postgresql.DB.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		fail(c, err)
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"user": user.UserName, "user_id": user.UserId})
@@ -57,8 +46,8 @@ func CreateUser(c *gin.Context) {
 // @Produce      json
 // @Param        user  body  object  true  "User credentials"  example({"user_name":"alice","password":"mypassword"})
 // @Success      201   {object}  map[string]string
-// @Failure      400   {object}  map[string]string
-// @Failure      500   {object}  map[string]string
+// @Failure      400   {object}  problem.Problem
+// @Failure      500   {object}  problem.Problem
 // @Router       /api/v1/signup [post]
 func SignUp(c *gin.Context) {
 	var req struct {
@@ -66,26 +55,12 @@ func SignUp(c *gin.Context) {
 		Password string `json:"password"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil || req.UserName == "" || req.Password == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		fail(c, errInvalidPayload)
 		return
 	}
-	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	user, token, err := service.SignUp(c.Request.Context(), req.UserName, req.Password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
-		return
-	}
-	user := model.User{
-		UserId:   uuid.New().String(),
-		UserName: req.UserName,
-		Password: string(hashed),
-	}
-	if err := postgresql.DB.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign up"})
-		return
-	}
-	token, err := auth.GenerateToken(user.UserId)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		fail(c, err)
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"user": user.UserName, "user_id": user.UserId, "token": token})
@@ -93,15 +68,16 @@ func SignUp(c *gin.Context) {
 
 // Login godoc
 // @Summary      Login user
-// @Description  Authenticate user and return JWT token
+// @Description  Authenticate user and return JWT token. Users with two-factor authentication instead get {"two_factor_required": true, "challenge_token": ...} to complete at /api/v1/login/2fa. Repeated failures for a user name delay and then lock out further attempts (429 with Retry-After).
 // @Tags         users
 // @Accept       json
 // @Produce      json
 // @Param        user  body  object  true  "User credentials"  example({"user_name":"alice","password":"mypassword"})
 // @Success      200   {object}  map[string]string
-// @Failure      400   {object}  map[string]string
-// @Failure      401   {object}  map[string]string
-// @Failure      500   {object}  map[string]string
+// @Failure      400   {object}  problem.Problem
+// @Failure      401   {object}  problem.Problem
+// @Failure      429   {object}  problem.Problem
+// @Failure      500   {object}  problem.Problem
 // @Router       /api/v1/login [post]
 func Login(c *gin.Context) {
 	var req struct {
@@ -109,22 +85,25 @@ func Login(c *gin.Context) {
 		Password string `json:"password"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil || req.UserName == "" || req.Password == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
-		return
-	}
-	var user model.User
-	if err := postgresql.DB.Where("user_name = ?", req.UserName).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		fail(c, errInvalidPayload)
 		return
 	}
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+	logger := logging.FromContext(c).WithField("user_name", req.UserName)
+	user, token, err := service.Login(c.Request.Context(), req.UserName, req.Password, c.ClientIP())
+	switch {
+	case errors.Is(err, service.ErrTwoFactorRequired):
+		logger.WithField("login_user_id", user.UserId).Info("Login needs a second factor")
+		c.JSON(http.StatusOK, gin.H{"two_factor_required": true, "challenge_token": token})
 		return
+	case errors.Is(err, service.ErrInvalidCredentials):
+		logger.Warn("Login failed: invalid credentials")
+	case errors.Is(err, service.ErrLockedOut):
+		logger.Warn("Login refused: too many failed attempts")
 	}
-	token, err := auth.GenerateToken(user.UserId)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		fail(c, err)
 		return
 	}
+	logger.WithField("login_user_id", user.UserId).Info("Login succeeded")
 	c.JSON(http.StatusOK, gin.H{"user": user.UserName, "user_id": user.UserId, "token": token})
 }
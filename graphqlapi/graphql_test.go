@@ -0,0 +1,84 @@
+package graphqlapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		vars  map[string]interface{}
+		ok    bool
+	}{
+		{"shallow", `{ expenses { id amount } }`, nil, true},
+		{"too deep", `{ summary { categories { expenses { user { id } } } } }`, nil, false},
+		{"fragment depth counted", `{ summary { ...S } } fragment S on Summary { categories { expenses { user { id } } } }`, nil, false},
+		{"limit multiplies cost", `{ expenses(limit: 500) { id amount currency } }`, nil, false},
+		{"limit from variable", `query Q($n: Int) { expenses(limit: $n) { id } }`, map[string]interface{}{"n": float64(5)}, true},
+		{"introspection ignored", `{ __schema { types { name fields { name type { name ofType { name ofType { name } } } } } } }`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+			require.NoError(t, err)
+			err = checkLimits(doc, "", tt.vars, 4, 1000)
+			if tt.ok {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestLoader_BatchesPendingKeys(t *testing.T) {
+	var calls [][]string
+	l := newLoader(func(keys []string) (map[string]int, error) {
+		calls = append(calls, append([]string(nil), keys...))
+		out := map[string]int{}
+		for _, k := range keys {
+			out[k] = len(k)
+		}
+		return out, nil
+	})
+
+	a, b, again := l.Load("a"), l.Load("bb"), l.Load("a")
+	va, _ := a()
+	vb, _ := b()
+	vagain, _ := again()
+	missing, _ := l.Load("ccc")()
+
+	assert.Equal(t, 1, va)
+	assert.Equal(t, 2, vb)
+	assert.Equal(t, 1, vagain)
+	assert.Equal(t, 3, missing)
+	assert.Equal(t, [][]string{{"a", "bb"}, {"ccc"}}, calls)
+}
+
+func TestHandler_RejectsBadQueries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/graphql", Handler(3, 100))
+
+	for _, query := range []string{
+		`{ nope }`,
+		`{ summary { categories { expenses { id } } } }`,
+		`{ expenses(`,
+	} {
+		body, _ := json.Marshal(Request{Query: query})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		assert.Contains(t, w.Body.String(), `"errors"`, query)
+	}
+}
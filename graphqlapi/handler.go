@@ -0,0 +1,75 @@
+// Package graphqlapi serves a read-only GraphQL view of expenses, categories
+// and summaries on top of the service layer.
+package graphqlapi
+
+import (
+	"context"
+	"expense-tracker/logging"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// Request is the standard GraphQL-over-HTTP POST body.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Handler godoc
+// @Summary      GraphQL endpoint
+// @Description  Run a GraphQL query over expenses, categories and summaries
+// @Tags         graphql
+// @Accept       json
+// @Produce      json
+// @Param        request  body      graphqlapi.Request  true  "GraphQL request"
+// @Success      200      {object}  map[string]interface{}
+// @Failure      400      {object}  map[string]interface{}
+// @Router       /graphql [post]
+// @Security     BearerAuth
+func Handler(maxDepth, maxComplexity int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req Request
+		if err := c.ShouldBindJSON(&req); err != nil || req.Query == "" {
+			c.JSON(http.StatusBadRequest, errorResult("Invalid request payload"))
+			return
+		}
+
+		doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(req.Query)})})
+		if err != nil {
+			c.JSON(http.StatusBadRequest, &graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+			return
+		}
+		if v := graphql.ValidateDocument(&Schema, doc, nil); !v.IsValid {
+			c.JSON(http.StatusBadRequest, &graphql.Result{Errors: v.Errors})
+			return
+		}
+		if err := checkLimits(doc, req.OperationName, req.Variables, maxDepth, maxComplexity); err != nil {
+			c.JSON(http.StatusBadRequest, errorResult(err.Error()))
+			return
+		}
+
+		ctx := c.Request.Context()
+		ctx = context.WithValue(ctx, loadersKey{}, newLoaders(ctx))
+		result := graphql.Execute(graphql.ExecuteParams{
+			Schema:        Schema,
+			AST:           doc,
+			OperationName: req.OperationName,
+			Args:          req.Variables,
+			Context:       ctx,
+		})
+		if result.HasErrors() {
+			logging.FromContext(c).WithField("errors", result.Errors).Warn("GraphQL query returned errors")
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func errorResult(message string) *graphql.Result {
+	return &graphql.Result{Errors: []gqlerrors.FormattedError{{Message: message}}}
+}
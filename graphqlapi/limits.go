@@ -0,0 +1,112 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// defaultListSize is the multiplier assumed for list fields that are not
+// given an explicit limit argument.
+const defaultListSize = 10
+
+// listFields are the fields returning lists; their children are counted once
+// per expected element when computing complexity.
+var listFields = map[string]bool{"expenses": true, "categories": true}
+
+// limitChecker computes query depth and complexity from the parsed document.
+// Introspection fields (those starting with "__") are not counted.
+type limitChecker struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+// checkLimits rejects operations nested deeper than maxDepth fields or whose
+// estimated cost exceeds maxComplexity. It must run after validation so that
+// fragment cycles have already been rejected.
+func checkLimits(doc *ast.Document, operationName string, variables map[string]interface{}, maxDepth, maxComplexity int) error {
+	lc := limitChecker{fragments: map[string]*ast.FragmentDefinition{}, variables: variables}
+	var ops []*ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch d := def.(type) {
+		case *ast.FragmentDefinition:
+			lc.fragments[d.Name.Value] = d
+		case *ast.OperationDefinition:
+			if operationName == "" || (d.Name != nil && d.Name.Value == operationName) {
+				ops = append(ops, d)
+			}
+		}
+	}
+	for _, op := range ops {
+		depth, cost := lc.measure(op.SelectionSet)
+		if depth > maxDepth {
+			return fmt.Errorf("query depth %d exceeds the maximum of %d", depth, maxDepth)
+		}
+		if cost > maxComplexity {
+			return fmt.Errorf("query complexity %d exceeds the maximum of %d", cost, maxComplexity)
+		}
+	}
+	return nil
+}
+
+// measure returns the depth and cost of a selection set.
+func (lc limitChecker) measure(set *ast.SelectionSet) (depth, cost int) {
+	if set == nil {
+		return 0, 0
+	}
+	for _, sel := range set.Selections {
+		var d, c int
+		switch s := sel.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name.Value, "__") {
+				continue
+			}
+			childDepth, childCost := lc.measure(s.SelectionSet)
+			d = childDepth + 1
+			c = 1 + lc.multiplier(s)*childCost
+		case *ast.InlineFragment:
+			d, c = lc.measure(s.SelectionSet)
+		case *ast.FragmentSpread:
+			if frag, ok := lc.fragments[s.Name.Value]; ok {
+				d, c = lc.measure(frag.SelectionSet)
+			}
+		}
+		if d > depth {
+			depth = d
+		}
+		cost += c
+	}
+	return depth, cost
+}
+
+// multiplier estimates how many elements a field returns.
+func (lc limitChecker) multiplier(field *ast.Field) int {
+	if !listFields[field.Name.Value] {
+		return 1
+	}
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "limit" {
+			continue
+		}
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(v.Value); err == nil && n > 0 {
+				return n
+			}
+		case *ast.Variable:
+			switch n := lc.variables[v.Name.Value].(type) {
+			case float64:
+				if n > 0 {
+					return int(n)
+				}
+			case int:
+				if n > 0 {
+					return n
+				}
+			}
+		}
+	}
+	return defaultListSize
+}
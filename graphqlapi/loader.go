@@ -0,0 +1,54 @@
+package graphqlapi
+
+import "sync"
+
+// loader batches lookups made while resolving one level of a query. Load
+// registers a key and returns a thunk; graphql-go runs all thunks of a level
+// after every resolver at that level has been called, so the first thunk to
+// run fetches every pending key in a single call.
+type loader[K comparable, V any] struct {
+	mu      sync.Mutex
+	fetch   func(keys []K) (map[K]V, error)
+	pending []K
+	queued  map[K]bool
+	cache   map[K]V
+	err     error
+}
+
+func newLoader[K comparable, V any](fetch func(keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{fetch: fetch, queued: map[K]bool{}, cache: map[K]V{}}
+}
+
+// Load returns a graphql-go thunk resolving to the value for key. Missing
+// keys resolve to nil.
+func (l *loader[K, V]) Load(key K) func() (interface{}, error) {
+	l.mu.Lock()
+	if !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if len(l.pending) > 0 {
+			values, err := l.fetch(l.pending)
+			l.pending = nil
+			if err != nil {
+				l.err = err
+			}
+			for k, v := range values {
+				l.cache[k] = v
+			}
+		}
+		if l.err != nil {
+			return nil, l.err
+		}
+		v, ok := l.cache[key]
+		if !ok {
+			return nil, nil
+		}
+		return v, nil
+	}
+}
@@ -0,0 +1,291 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"expense-tracker/model"
+	"expense-tracker/service"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/graphql-go/graphql"
+	log "github.com/sirupsen/logrus"
+)
+
+// categoryExpensesDefault is the number of expenses returned per category
+// when Category.expenses is queried without a limit.
+const categoryExpensesDefault = 5
+
+// category is the source object behind the Category type. It remembers the
+// filter it was summarized with so nested expenses use the same range.
+type category struct {
+	Name   string
+	Total  float64
+	filter service.ExpenseFilter
+}
+
+// summary is the source object behind the Summary type.
+type summary struct {
+	Total      float64
+	Categories []category
+}
+
+type loadersKey struct{}
+
+// loaders holds the per-request batch loaders.
+type loaders struct {
+	ctx   context.Context
+	users *loader[string, model.User]
+
+	mu         sync.Mutex
+	byCategory map[string]*loader[string, []model.Expense]
+}
+
+func newLoaders(ctx context.Context) *loaders {
+	l := &loaders{ctx: ctx, byCategory: map[string]*loader[string, []model.Expense]{}}
+	l.users = newLoader(func(ids []string) (map[string]model.User, error) {
+		users, err := service.UsersByID(ctx, ids)
+		return users, publicError(err)
+	})
+	return l
+}
+
+// categoryExpenses returns the loader shared by all categories summarized
+// with the same filter and requesting the same number of expenses.
+func (l *loaders) categoryExpenses(f service.ExpenseFilter, limit int) *loader[string, []model.Expense] {
+	key := fmt.Sprintf("%+v|%d", f, limit)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if ld, ok := l.byCategory[key]; ok {
+		return ld
+	}
+	ld := newLoader(func(names []string) (map[string][]model.Expense, error) {
+		expenses, err := service.ExpensesByCategory(l.ctx, f, names, limit)
+		return expenses, publicError(err)
+	})
+	l.byCategory[key] = ld
+	return ld
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	if l, ok := ctx.Value(loadersKey{}).(*loaders); ok {
+		return l
+	}
+	return newLoaders(ctx)
+}
+
+var filterArgs = graphql.FieldConfigArgument{
+	"userId":      &graphql.ArgumentConfig{Type: graphql.String},
+	"householdId": &graphql.ArgumentConfig{Type: graphql.String, Description: "Household ID (omit for personal expenses)"},
+	"category":    &graphql.ArgumentConfig{Type: graphql.String},
+	"currency":    &graphql.ArgumentConfig{Type: graphql.String},
+	"from":        &graphql.ArgumentConfig{Type: graphql.String, Description: "Start date (YYYY-MM-DD)"},
+	"to":          &graphql.ArgumentConfig{Type: graphql.String, Description: "End date (YYYY-MM-DD)"},
+	"limit":       &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: service.DefaultPageSize},
+	"offset":      &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+}
+
+var summaryArgs = graphql.FieldConfigArgument{
+	"userId":      &graphql.ArgumentConfig{Type: graphql.String},
+	"householdId": &graphql.ArgumentConfig{Type: graphql.String, Description: "Household ID (omit for personal expenses)"},
+	"from":        &graphql.ArgumentConfig{Type: graphql.String, Description: "Start date (YYYY-MM-DD)"},
+	"to":          &graphql.ArgumentConfig{Type: graphql.String, Description: "End date (YYYY-MM-DD)"},
+}
+
+// filterFromArgs builds the same filter ListExpensesWithFilters reads from
+// the query string.
+func filterFromArgs(args map[string]interface{}) service.ExpenseFilter {
+	str := func(name string) string {
+		s, _ := args[name].(string)
+		return s
+	}
+	num := func(name string) int {
+		n, _ := args[name].(int)
+		return n
+	}
+	return service.ExpenseFilter{
+		UserID:      str("userId"),
+		HouseholdID: str("householdId"),
+		Category:    str("category"),
+		Currency:    str("currency"),
+		From:        str("from"),
+		To:          str("to"),
+		Limit:       num("limit"),
+		Offset:      num("offset"),
+	}
+}
+
+var userType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "User",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{Type: graphql.NewNonNull(graphql.ID), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(model.User).UserId, nil
+		}},
+		"userName": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(model.User).UserName, nil
+		}},
+	},
+})
+
+var expenseType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Expense",
+	Fields: graphql.Fields{
+		"id":     &graphql.Field{Type: graphql.NewNonNull(graphql.ID), Resolve: expenseField(func(e model.Expense) interface{} { return e.Id })},
+		"userId": &graphql.Field{Type: graphql.NewNonNull(graphql.ID), Resolve: expenseField(func(e model.Expense) interface{} { return e.User_id })},
+		"householdId": &graphql.Field{Type: graphql.ID, Resolve: expenseField(func(e model.Expense) interface{} {
+			if e.Household_id == "" {
+				return nil
+			}
+			return e.Household_id
+		})},
+		"amount":      &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Resolve: expenseField(func(e model.Expense) interface{} { return e.Amount })},
+		"currency":    &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: expenseField(func(e model.Expense) interface{} { return e.Currency })},
+		"category":    &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: expenseField(func(e model.Expense) interface{} { return e.Category })},
+		"description": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: expenseField(func(e model.Expense) interface{} { return e.Description })},
+		"timestamp":   &graphql.Field{Type: graphql.DateTime, Resolve: expenseField(func(e model.Expense) interface{} { return e.TimeStamp })},
+		"user": &graphql.Field{
+			Type:        userType,
+			Description: "The owner of the expense. Batched across all expenses in the response.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return loadersFrom(p.Context).users.Load(p.Source.(model.Expense).User_id), nil
+			},
+		},
+	},
+})
+
+func expenseField(get func(model.Expense) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(model.Expense)), nil
+	}
+}
+
+var categoryType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Category",
+	Fields: graphql.Fields{
+		"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(category).Name, nil
+		}},
+		"total": &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(category).Total, nil
+		}},
+		"expenses": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(expenseType))),
+			Description: "The most recent expenses in the category. Batched across all categories in the response.",
+			Args: graphql.FieldConfigArgument{
+				"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: categoryExpensesDefault},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				c := p.Source.(category)
+				limit, _ := p.Args["limit"].(int)
+				thunk := loadersFrom(p.Context).categoryExpenses(c.filter, limit).Load(c.Name)
+				return func() (interface{}, error) {
+					v, err := thunk()
+					if err != nil || v == nil {
+						return []model.Expense{}, err
+					}
+					return v, nil
+				}, nil
+			},
+		},
+	},
+})
+
+var summaryType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Summary",
+	Fields: graphql.Fields{
+		"total": &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(summary).Total, nil
+		}},
+		"categories": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(categoryType))), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(summary).Categories, nil
+		}},
+	},
+})
+
+func resolveSummary(p graphql.ResolveParams) (summary, error) {
+	f := filterFromArgs(p.Args)
+	totals, err := service.SummarizeExpenses(p.Context, f)
+	if err != nil {
+		return summary{}, publicError(err)
+	}
+	s := summary{Categories: make([]category, 0, len(totals))}
+	for name, total := range totals {
+		s.Total += total
+		s.Categories = append(s.Categories, category{Name: name, Total: total, filter: f})
+	}
+	sort.Slice(s.Categories, func(i, j int) bool { return s.Categories[i].Name < s.Categories[j].Name })
+	return s, nil
+}
+
+var queryType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Query",
+	Fields: graphql.Fields{
+		"expenses": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(expenseType))),
+			Description: "Expenses matching the same filters as GET /api/v1/expenses.",
+			Args:        filterArgs,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				expenses, err := service.ListExpenses(p.Context, filterFromArgs(p.Args))
+				return expenses, publicError(err)
+			},
+		},
+		"expense": &graphql.Field{
+			Type: expenseType,
+			Args: graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				id, _ := p.Args["id"].(string)
+				expense, err := service.GetExpense(p.Context, id)
+				if errors.Is(err, service.ErrNotFound) {
+					return nil, nil
+				}
+				if err != nil {
+					return nil, publicError(err)
+				}
+				return expense, nil
+			},
+		},
+		"categories": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(categoryType))),
+			Description: "Categories with their totals, as in GET /api/v1/expenses/summary.",
+			Args:        summaryArgs,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				s, err := resolveSummary(p)
+				return s.Categories, err
+			},
+		},
+		"summary": &graphql.Field{
+			Type: graphql.NewNonNull(summaryType),
+			Args: summaryArgs,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return resolveSummary(p)
+			},
+		},
+	},
+})
+
+// publicError logs internal errors and replaces them with a generic one so
+// database details never reach clients.
+func publicError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, service.ErrInvalidArgument), errors.Is(err, service.ErrForbidden):
+		return err
+	}
+	log.Errorf("GraphQL resolver failed: %v", err)
+	return errInternal
+}
+
+var errInternal = errors.New("internal error")
+
+// Schema is the read-only GraphQL schema served on /graphql.
+var Schema = mustSchema()
+
+func mustSchema() graphql.Schema {
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}
@@ -0,0 +1,191 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"expense-tracker/auth"
+	expensev1 "expense-tracker/proto/expense/v1"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// publicPrefix marks methods that don't require a JWT.
+var publicPrefix = "/" + expensev1.AuthService_ServiceDesc.ServiceName + "/"
+
+// healthPrefix marks the standard gRPC health service, which probes call
+// without a token and outside the rate limits.
+var healthPrefix = "/" + healthpb.Health_ServiceDesc.ServiceName + "/"
+
+// rateLimitGroup picks the same route group the REST API uses for the method.
+func rateLimitGroup(fullMethod string) string {
+	if strings.HasPrefix(fullMethod, publicPrefix) {
+		return "auth"
+	}
+	return "expenses"
+}
+
+// authenticate validates the bearer token in the call metadata and stores the
+// user on the returned context. Auth and health service methods pass through
+// untouched.
+func authenticate(ctx context.Context, fullMethod string) (context.Context, error) {
+	if strings.HasPrefix(fullMethod, publicPrefix) || strings.HasPrefix(fullMethod, healthPrefix) {
+		return ctx, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "missing or invalid authorization metadata")
+	}
+	token, ok := auth.BearerToken(values[0])
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "missing or invalid authorization metadata")
+	}
+	id, err := auth.Authenticate(ctx, token)
+	if err != nil && !errors.Is(err, auth.ErrInvalidToken) && !errors.Is(err, auth.ErrSessionRevoked) {
+		return nil, status.Error(codes.Internal, "failed to check session")
+	}
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired token")
+	}
+	if scope := methodScope(fullMethod); !id.Allows(scope) {
+		return nil, status.Errorf(codes.PermissionDenied, "access token lacks the %s scope", scope)
+	}
+	return auth.ContextWithIdentity(ctx, id), nil
+}
+
+// methodScope is the access token scope an ExpenseService method needs:
+// expenses:write for methods that change data, expenses:read otherwise.
+func methodScope(fullMethod string) string {
+	name := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	for _, verb := range []string{"Create", "Update", "Delete"} {
+		if strings.HasPrefix(name, verb) {
+			return auth.ScopeExpensesWrite
+		}
+	}
+	return auth.ScopeExpensesRead
+}
+
+func limit(ctx context.Context, rl *auth.RateLimiter, fullMethod string) error {
+	if strings.HasPrefix(fullMethod, healthPrefix) {
+		return nil
+	}
+	userID, tier, _ := auth.UserFromContext(ctx)
+	result, ok, err := rl.Limit(ctx, rateLimitGroup(fullMethod), userID, tier, peerIP(ctx))
+	if err != nil {
+		return status.Error(codes.Internal, "rate limiter error")
+	}
+	if !ok {
+		return nil
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(
+		"x-ratelimit-limit", fmt.Sprint(result.Limit),
+		"x-ratelimit-remaining", fmt.Sprint(result.Remaining),
+		"x-ratelimit-reset", fmt.Sprint(result.Reset),
+	))
+	if result.Reached {
+		_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", fmt.Sprint(auth.RetryAfter(result.Reset))))
+		return status.Error(codes.ResourceExhausted, "too many requests")
+	}
+	return nil
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+func unaryAuth(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func unaryRateLimit(rl *auth.RateLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := limit(ctx, rl, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func unaryLogging(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	logCall(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+// wrappedStream lets stream interceptors replace the stream context.
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (w *wrappedStream) Context() context.Context { return w.ctx }
+
+func streamAuth(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &wrappedStream{ServerStream: ss, ctx: ctx})
+}
+
+func streamRateLimit(rl *auth.RateLimiter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := limit(ss.Context(), rl, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func streamLogging(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	logCall(ss.Context(), info.FullMethod, start, err)
+	return err
+}
+
+// logCall writes one access log line per call, mirroring logging.Middleware.
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	requestID := uuid.New().String()
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get("x-request-id"); len(ids) > 0 {
+			requestID = ids[0]
+		}
+	}
+	entry := log.WithFields(log.Fields{
+		"request_id": requestID,
+		"method":     method,
+		"code":       status.Code(err).String(),
+		"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+		"client_ip":  peerIP(ctx),
+	})
+	if err != nil && status.Code(err) == codes.Internal {
+		entry.Error("grpc call completed")
+		return
+	}
+	entry.Info("grpc call completed")
+}
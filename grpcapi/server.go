@@ -0,0 +1,260 @@
+// Package grpcapi serves the expense and auth APIs over gRPC using the same
+// service layer as the REST handlers.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"expense-tracker/auth"
+	"expense-tracker/health"
+	"expense-tracker/model"
+	expensev1 "expense-tracker/proto/expense/v1"
+	"expense-tracker/service"
+	"expense-tracker/validation"
+	"fmt"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// streamBatchSize is how many rows StreamExpenses reads from the database at a time.
+const streamBatchSize = 500
+
+// NewServer returns a gRPC server with both services registered behind the
+// tracing, logging, JWT and rate-limit interceptors, along with the standard
+// health service.
+func NewServer(rl *auth.RateLimiter) *grpc.Server {
+	srv := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(unaryLogging, unaryAuth, unaryRateLimit(rl)),
+		grpc.ChainStreamInterceptor(streamLogging, streamAuth, streamRateLimit(rl)),
+	)
+	expensev1.RegisterAuthServiceServer(srv, &AuthServer{})
+	expensev1.RegisterExpenseServiceServer(srv, &ExpenseServer{})
+	healthpb.RegisterHealthServer(srv, grpchealth.NewServer())
+	return srv
+}
+
+// ReadinessCheck asks the gRPC server behind conn for its health, so that
+// readiness fails when it stops serving.
+func ReadinessCheck(conn grpc.ClientConnInterface) health.CheckFunc {
+	client := healthpb.NewHealthClient(conn)
+	return func(ctx context.Context) error {
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+		if err != nil {
+			return err
+		}
+		if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			return fmt.Errorf("gRPC server is %s", resp.GetStatus())
+		}
+		return nil
+	}
+}
+
+// AuthServer implements expensev1.AuthServiceServer.
+type AuthServer struct {
+	expensev1.UnimplementedAuthServiceServer
+}
+
+func (s *AuthServer) SignUp(ctx context.Context, req *expensev1.SignUpRequest) (*expensev1.AuthResponse, error) {
+	user, token, err := service.SignUp(ctx, req.GetUserName(), req.GetPassword())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &expensev1.AuthResponse{UserId: user.UserId, UserName: user.UserName, Token: token}, nil
+}
+
+func (s *AuthServer) Login(ctx context.Context, req *expensev1.LoginRequest) (*expensev1.AuthResponse, error) {
+	user, token, err := service.Login(ctx, req.GetUserName(), req.GetPassword(), peerIP(ctx))
+	if errors.Is(err, service.ErrTwoFactorRequired) {
+		return &expensev1.AuthResponse{TwoFactorRequired: true, ChallengeToken: token}, nil
+	}
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &expensev1.AuthResponse{UserId: user.UserId, UserName: user.UserName, Token: token}, nil
+}
+
+func (s *AuthServer) LoginSecondFactor(ctx context.Context, req *expensev1.LoginSecondFactorRequest) (*expensev1.AuthResponse, error) {
+	user, token, err := service.CompleteLogin(ctx, req.GetChallengeToken(), req.GetCode(), peerIP(ctx))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &expensev1.AuthResponse{UserId: user.UserId, UserName: user.UserName, Token: token}, nil
+}
+
+// ExpenseServer implements expensev1.ExpenseServiceServer.
+type ExpenseServer struct {
+	expensev1.UnimplementedExpenseServiceServer
+}
+
+func (s *ExpenseServer) CreateExpense(ctx context.Context, req *expensev1.CreateExpenseRequest) (*expensev1.Expense, error) {
+	if req.GetExpense() == nil {
+		return nil, status.Error(codes.InvalidArgument, "expense is required")
+	}
+	userID, _, _ := auth.UserFromContext(ctx)
+	expense, err := service.CreateExpense(ctx, userID, fromProto(req.GetExpense()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(expense), nil
+}
+
+func (s *ExpenseServer) GetExpense(ctx context.Context, req *expensev1.GetExpenseRequest) (*expensev1.Expense, error) {
+	expense, err := service.GetExpense(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(expense), nil
+}
+
+func (s *ExpenseServer) UpdateExpense(ctx context.Context, req *expensev1.UpdateExpenseRequest) (*expensev1.Expense, error) {
+	if req.GetExpense() == nil {
+		return nil, status.Error(codes.InvalidArgument, "expense is required")
+	}
+	expense, err := service.UpdateExpense(ctx, req.GetId(), fromProto(req.GetExpense()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(expense), nil
+}
+
+func (s *ExpenseServer) DeleteExpense(ctx context.Context, req *expensev1.DeleteExpenseRequest) (*expensev1.DeleteExpenseResponse, error) {
+	if err := service.DeleteExpense(ctx, req.GetId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &expensev1.DeleteExpenseResponse{}, nil
+}
+
+func (s *ExpenseServer) ListExpenses(ctx context.Context, req *expensev1.ListExpensesRequest) (*expensev1.ListExpensesResponse, error) {
+	expenses, err := service.ListExpenses(ctx, listFilter(req))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &expensev1.ListExpensesResponse{Expenses: make([]*expensev1.Expense, 0, len(expenses))}
+	for _, e := range expenses {
+		resp.Expenses = append(resp.Expenses, toProto(e))
+	}
+	return resp, nil
+}
+
+func (s *ExpenseServer) StreamExpenses(req *expensev1.ListExpensesRequest, stream grpc.ServerStreamingServer[expensev1.Expense]) error {
+	err := service.StreamExpenses(stream.Context(), listFilter(req), streamBatchSize, func(e model.Expense) error {
+		return stream.Send(toProto(e))
+	})
+	if err != nil {
+		return toStatus(err)
+	}
+	return nil
+}
+
+func (s *ExpenseServer) Summary(ctx context.Context, req *expensev1.SummaryRequest) (*expensev1.SummaryResponse, error) {
+	totals, err := service.SummarizeExpenses(ctx, service.ExpenseFilter{
+		UserID:      req.GetUserId(),
+		HouseholdID: req.GetHouseholdId(),
+		From:        req.GetFrom(),
+		To:          req.GetTo(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &expensev1.SummaryResponse{Totals: totals}, nil
+}
+
+func listFilter(req *expensev1.ListExpensesRequest) service.ExpenseFilter {
+	return service.ExpenseFilter{
+		UserID:      req.GetUserId(),
+		HouseholdID: req.GetHouseholdId(),
+		Category:    req.GetCategory(),
+		Currency:    req.GetCurrency(),
+		From:        req.GetFrom(),
+		To:          req.GetTo(),
+		Limit:       int(req.GetLimit()),
+		Offset:      int(req.GetOffset()),
+	}
+}
+
+func toProto(e model.Expense) *expensev1.Expense {
+	return &expensev1.Expense{
+		Id:          e.Id,
+		UserId:      e.User_id,
+		Amount:      e.Amount,
+		Currency:    e.Currency,
+		Category:    e.Category,
+		Description: e.Description,
+		Timestamp:   timestamppb.New(e.TimeStamp),
+		AccountId:   e.Account_id,
+		Tags:        e.Tags,
+		HouseholdId: e.Household_id,
+	}
+}
+
+func fromProto(e *expensev1.Expense) service.ExpenseInput {
+	expense := service.ExpenseInput{
+		Amount:      e.GetAmount(),
+		Currency:    e.GetCurrency(),
+		Category:    e.GetCategory(),
+		Description: e.GetDescription(),
+		AccountID:   e.GetAccountId(),
+		Tags:        e.GetTags(),
+		HouseholdID: e.GetHouseholdId(),
+		UserID:      e.GetUserId(),
+	}
+	if e.GetTimestamp() != nil {
+		expense.TimeStamp = e.GetTimestamp().AsTime()
+	}
+	return expense
+}
+
+// toStatus maps service errors onto gRPC status codes without exposing
+// internal error details.
+func toStatus(err error) error {
+	var fields validation.Errors
+	switch {
+	case errors.As(err, &fields):
+		return invalidFields(fields)
+	case errors.Is(err, service.ErrNotFound):
+		return status.Error(codes.NotFound, "expense not found")
+	case errors.Is(err, service.ErrInvalidArgument):
+		return status.Error(codes.InvalidArgument, "invalid argument")
+	case errors.Is(err, service.ErrWeakPassword):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrForbidden):
+		return status.Error(codes.PermissionDenied, "not allowed in this household")
+	case errors.Is(err, service.ErrInvalidCode):
+		return status.Error(codes.Unauthenticated, "invalid authentication code")
+	case errors.Is(err, service.ErrInvalidCredentials), errors.Is(err, service.ErrInvalidPassword):
+		return status.Error(codes.Unauthenticated, "invalid credentials")
+	case errors.Is(err, service.ErrLockedOut):
+		return status.Error(codes.ResourceExhausted, "too many failed login attempts; try again later")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "request canceled")
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Error(codes.Internal, "internal error")
+}
+
+// invalidFields reports validation failures as InvalidArgument with a
+// BadRequest detail per field, the gRPC counterpart of the REST 422 body.
+func invalidFields(fields validation.Errors) error {
+	st := status.New(codes.InvalidArgument, fields.Error())
+	detail := &errdetails.BadRequest{}
+	for _, f := range fields {
+		detail.FieldViolations = append(detail.FieldViolations,
+			&errdetails.BadRequest_FieldViolation{Field: "expense." + f.Field, Description: f.Reason})
+	}
+	if withDetails, err := st.WithDetails(detail); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
@@ -0,0 +1,188 @@
+package grpcapi
+
+import (
+	"context"
+	"expense-tracker/auth"
+	"expense-tracker/config"
+	"expense-tracker/model"
+	expensev1 "expense-tracker/proto/expense/v1"
+	"expense-tracker/service"
+	"expense-tracker/testutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestClient(t *testing.T, rates config.GroupLimit) expensev1.ExpenseServiceClient {
+	return expensev1.NewExpenseServiceClient(newTestConn(t, rates))
+}
+
+func newTestConn(t *testing.T, rates config.GroupLimit) *grpc.ClientConn {
+	auth.Configure(config.JWT{Secret: "test-secret"})
+	cfg := config.RateLimit{Groups: map[string]config.GroupLimit{"expenses": rates}}
+	store, err := auth.NewRateLimitStore(cfg)
+	require.NoError(t, err)
+	rl, err := auth.NewRateLimiter(store, cfg)
+	require.NoError(t, err)
+
+	lis := bufconn.Listen(1 << 20)
+	srv := NewServer(rl)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func withToken(t *testing.T) context.Context {
+	token, err := auth.GenerateToken("user-1", auth.DefaultTier, 0)
+	require.NoError(t, err)
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestExpenseService_RequiresToken(t *testing.T) {
+	client := newTestClient(t, config.GroupLimit{Default: "10-M"})
+
+	_, err := client.GetExpense(context.Background(), &expensev1.GetExpenseRequest{Id: "x"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	bad := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer nope")
+	_, err = client.GetExpense(bad, &expensev1.GetExpenseRequest{Id: "x"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestExpenseService_RateLimited(t *testing.T) {
+	client := newTestClient(t, config.GroupLimit{Default: "1-M"})
+	ctx := withToken(t)
+
+	// An empty ID is rejected by the service before touching the database.
+	_, err := client.GetExpense(ctx, &expensev1.GetExpenseRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	var header metadata.MD
+	_, err = client.GetExpense(ctx, &expensev1.GetExpenseRequest{}, grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.NotEmpty(t, header.Get("retry-after"))
+}
+
+func TestExpenseService_AccessTokenScopes(t *testing.T) {
+	client := newTestClient(t, config.GroupLimit{Default: "10-M"})
+	auth.SetAccessTokenValidator(func(ctx context.Context, token string) (auth.Identity, error) {
+		return auth.Identity{UserID: "user-1", Tier: auth.DefaultTier, Scopes: []string{auth.ScopeExpensesRead}}, nil
+	})
+	t.Cleanup(func() { auth.SetAccessTokenValidator(nil) })
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+auth.AccessTokenPrefix+"read")
+
+	_, err := client.GetExpense(ctx, &expensev1.GetExpenseRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "read scope reaches the service")
+
+	_, err = client.DeleteExpense(ctx, &expensev1.DeleteExpenseRequest{Id: "x"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestExpenseService_ValidationDetails(t *testing.T) {
+	client := newTestClient(t, config.GroupLimit{Default: "10-M"})
+
+	_, err := client.CreateExpense(withToken(t), &expensev1.CreateExpenseRequest{
+		Expense: &expensev1.Expense{Amount: -1, Currency: "USD", Category: "food"},
+	})
+	st := status.Convert(err)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+	require.Len(t, st.Details(), 1)
+	detail, ok := st.Details()[0].(*errdetails.BadRequest)
+	require.True(t, ok)
+	require.Len(t, detail.FieldViolations, 1)
+	assert.Equal(t, "expense.amount", detail.FieldViolations[0].Field)
+}
+
+func TestProtoMapping_KeepsAccountAndTags(t *testing.T) {
+	e := toProto(model.Expense{Id: "e1", Amount: 5, Currency: "USD", Category: "food", Account_id: "acc-1", Tags: []string{"work"}})
+	assert.Equal(t, "acc-1", e.GetAccountId())
+	assert.Equal(t, []string{"work"}, e.GetTags())
+	in := fromProto(e)
+	assert.Equal(t, "acc-1", in.AccountID, "an update sending back what it read keeps the account")
+	assert.Equal(t, []string{"work"}, in.Tags)
+}
+
+func TestReadinessCheck_NeedsNoTokenAndIsNotLimited(t *testing.T) {
+	check := ReadinessCheck(newTestConn(t, config.GroupLimit{Default: "1-M", Anonymous: "1-M"}))
+	for range 3 {
+		assert.NoError(t, check(context.Background()))
+	}
+}
+
+func TestAuthService_LoginWithSecondFactor(t *testing.T) {
+	testutil.UseMemoryStores(t)
+	conn := newTestConn(t, config.GroupLimit{Default: "10-M"})
+	ctx := context.Background()
+	alice := testutil.User().Create(t)
+	secret, _, err := service.EnrollTwoFactor(ctx, alice.UserId)
+	require.NoError(t, err)
+	code := func(steps int64) string {
+		c, err := auth.TOTPCode(secret, auth.TOTPStep(time.Now())+steps)
+		require.NoError(t, err)
+		return c
+	}
+	_, err = service.ConfirmTwoFactor(ctx, alice.UserId, code(-1))
+	require.NoError(t, err)
+
+	client := expensev1.NewAuthServiceClient(conn)
+	challenge, err := client.Login(ctx, &expensev1.LoginRequest{UserName: alice.UserName, Password: testutil.DefaultPassword})
+	require.NoError(t, err)
+	require.True(t, challenge.GetTwoFactorRequired())
+	assert.Empty(t, challenge.GetToken())
+
+	_, err = client.LoginSecondFactor(ctx, &expensev1.LoginSecondFactorRequest{ChallengeToken: challenge.GetChallengeToken(), Code: code(5)})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	session, err := client.LoginSecondFactor(ctx, &expensev1.LoginSecondFactorRequest{ChallengeToken: challenge.GetChallengeToken(), Code: code(0)})
+	require.NoError(t, err)
+	assert.Equal(t, alice.UserId, session.GetUserId())
+	authed := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+session.GetToken())
+	_, err = expensev1.NewExpenseServiceClient(conn).ListExpenses(authed, &expensev1.ListExpensesRequest{})
+	assert.NoError(t, err)
+}
+
+func TestExpenseService_HouseholdExpenses(t *testing.T) {
+	testutil.UseMemoryStores(t)
+	client := newTestClient(t, config.GroupLimit{Default: "10-M"})
+	alice, bob, carol := testutil.User().Create(t), testutil.User().Create(t), testutil.User().Create(t)
+	home := testutil.Household(alice).Member(bob, service.RoleEditor).Create(t)
+	as := func(u model.User) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+testutil.Token(t, u))
+	}
+
+	created, err := client.CreateExpense(as(alice), &expensev1.CreateExpenseRequest{Expense: &expensev1.Expense{
+		Amount: 30, Currency: "USD", Category: "groceries", HouseholdId: home.Id, UserId: bob.UserId}})
+	require.NoError(t, err)
+	assert.Equal(t, home.Id, created.GetHouseholdId())
+	assert.Equal(t, bob.UserId, created.GetUserId(), "attributed to the member who paid")
+	testutil.Expense().Owner(alice).Create(t)
+
+	list, err := client.ListExpenses(as(bob), &expensev1.ListExpensesRequest{HouseholdId: home.Id})
+	require.NoError(t, err)
+	require.Len(t, list.GetExpenses(), 1)
+	assert.Equal(t, created.GetId(), list.GetExpenses()[0].GetId())
+
+	summary, err := client.Summary(as(bob), &expensev1.SummaryRequest{HouseholdId: home.Id})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"groceries": 30}, summary.GetTotals())
+
+	_, err = client.ListExpenses(as(carol), &expensev1.ListExpensesRequest{HouseholdId: home.Id})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
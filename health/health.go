@@ -0,0 +1,130 @@
+// Package health serves liveness and readiness probes.
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CheckFunc reports whether a dependency is usable. It must honour ctx cancellation.
+type CheckFunc func(ctx context.Context) error
+
+// CheckResult is the outcome of one readiness check.
+type CheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the JSON body returned by the probes.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type namedCheck struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker runs the registered readiness checks. The zero value is not usable;
+// create one with New.
+type Checker struct {
+	timeout      time.Duration
+	mu           sync.RWMutex
+	checks       []namedCheck
+	shuttingDown atomic.Bool
+}
+
+// New returns a Checker that gives each check at most timeout to complete.
+func New(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Register adds a readiness check. Names should be unique and stable since
+// they are used as keys in the report.
+func (h *Checker) Register(name string, fn CheckFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks = append(h.checks, namedCheck{name: name, fn: fn})
+}
+
+// BeginShutdown makes readiness fail from now on so load balancers stop
+// routing new traffic while in-flight requests drain.
+func (h *Checker) BeginShutdown() {
+	h.shuttingDown.Store(true)
+}
+
+// Check runs every registered check concurrently and aggregates the results.
+func (h *Checker) Check(ctx context.Context) Report {
+	h.mu.RLock()
+	checks := append([]namedCheck(nil), h.checks...)
+	h.mu.RUnlock()
+
+	report := Report{Status: "ok", Checks: make(map[string]CheckResult, len(checks)+1)}
+	if h.shuttingDown.Load() {
+		report.Status = "fail"
+		report.Checks["shutdown"] = CheckResult{Status: "fail", Error: "server is shutting down"}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, chk := range checks {
+		wg.Add(1)
+		go func(chk namedCheck) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, h.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := chk.fn(ctx)
+			result := CheckResult{Status: "ok", LatencyMs: float64(time.Since(start).Microseconds()) / 1000}
+			if err != nil {
+				result.Status = "fail"
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[chk.name] = result
+			if err != nil {
+				report.Status = "fail"
+			}
+		}(chk)
+	}
+	wg.Wait()
+	return report
+}
+
+// Liveness godoc
+// @Summary      Liveness probe
+// @Description  Reports that the process is up. Does not check dependencies.
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  health.Report
+// @Router       /healthz [get]
+func (h *Checker) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, Report{Status: "ok"})
+}
+
+// Readiness godoc
+// @Summary      Readiness probe
+// @Description  Runs the registered checks (the database, schema migrations and, when enabled, the gRPC server) and fails once shutdown has begun
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  health.Report
+// @Failure      503  {object}  health.Report
+// @Router       /readyz [get]
+func (h *Checker) Readiness(c *gin.Context) {
+	report := h.Check(c.Request.Context())
+	status := http.StatusOK
+	if report.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
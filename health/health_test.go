@@ -0,0 +1,57 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func probe(t *testing.T, h *Checker, path string) (int, Report) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/healthz", h.Liveness)
+	r.GET("/readyz", h.Readiness)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	var report Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	return w.Code, report
+}
+
+func TestReadiness(t *testing.T) {
+	h := New(50 * time.Millisecond)
+	h.Register("database", func(context.Context) error { return nil })
+
+	code, report := probe(t, h, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", report.Checks["database"].Status)
+
+	h.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	code, report = probe(t, h, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "fail", report.Status)
+	assert.Equal(t, "ok", report.Checks["database"].Status)
+	assert.Contains(t, report.Checks["slow"].Error, "deadline")
+}
+
+func TestReadiness_FailsDuringShutdown(t *testing.T) {
+	h := New(time.Second)
+	h.BeginShutdown()
+
+	code, report := probe(t, h, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "fail", report.Checks["shutdown"].Status)
+
+	code, _ = probe(t, h, "/healthz")
+	assert.Equal(t, http.StatusOK, code, "liveness is unaffected by shutdown")
+}
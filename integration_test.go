@@ -3,6 +3,8 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"expense-tracker/auth"
+	"expense-tracker/config"
 	"expense-tracker/controller"
 	"expense-tracker/model"
 	"expense-tracker/postgresql"
@@ -16,9 +18,13 @@ import (
 )
 
 func TestMain(m *testing.M) {
-	os.Setenv("DATABASE_URL", "user=user password=password dbname=expense_tracker_test host=localhost port=5432 sslmode=disable")
-	os.Setenv("JWT_SECRET", "test_jwt_secret")
-	postgresql.ConnectPostgres()
+	cfg := config.Default()
+	cfg.Database.URL = "user=user password=password dbname=expense_tracker_test host=localhost port=5432 sslmode=disable"
+	cfg.JWT.Secret = "test_jwt_secret"
+	if err := auth.Configure(cfg.JWT); err != nil {
+		panic(err)
+	}
+	postgresql.Connect(cfg.Database)
 	setupTestDB()
 	os.Exit(m.Run())
 }
@@ -30,7 +36,7 @@ func setupTestDB() {
 
 func TestExpenseIntegrationFlow(t *testing.T) {
 	// Use test DB or your dev DB (make sure it's running)
-	// postgresql.ConnectPostgres() // Moved to TestMain
+	// postgresql.Connect() // Moved to TestMain
 
 	// Setup Gin router
 	r := gin.Default()
@@ -0,0 +1,114 @@
+// Package logging provides request-scoped structured logging on top of logrus.
+package logging
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader is read from incoming requests and echoed on every response.
+const RequestIDHeader = "X-Request-ID"
+
+const (
+	loggerKey    = "logger"
+	requestIDKey = "request_id"
+)
+
+type ctxKey struct{}
+
+// validRequestID limits propagated IDs to a safe charset and length so they
+// can't be used to inject content into logs.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// Setup switches the global logger to JSON output at the given level.
+func Setup(level string) {
+	log.SetFormatter(&log.JSONFormatter{TimestampFormat: time.RFC3339Nano})
+	if lvl, err := log.ParseLevel(level); err == nil {
+		log.SetLevel(lvl)
+	}
+}
+
+// Middleware assigns or propagates an X-Request-ID, attaches a logger scoped
+// to the request and writes one JSON access log line once the request is done.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.New().String()
+		}
+		c.Header(RequestIDHeader, requestID)
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		entry := log.WithFields(log.Fields{
+			"request_id": requestID,
+			"method":     c.Request.Method,
+			"route":      route,
+		})
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.IsValid() {
+			entry = entry.WithFields(log.Fields{
+				"trace_id": sc.TraceID().String(),
+				"span_id":  sc.SpanID().String(),
+			})
+		}
+		c.Set(requestIDKey, requestID)
+		c.Set(loggerKey, entry)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), ctxKey{}, entry))
+
+		c.Next()
+
+		access := FromContext(c).WithFields(log.Fields{
+			"status":     c.Writer.Status(),
+			"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+			"path":       c.Request.URL.Path,
+			"client_ip":  c.ClientIP(),
+			"bytes":      c.Writer.Size(),
+		})
+		if len(c.Errors) > 0 {
+			access = access.WithField("errors", c.Errors.String())
+		}
+		switch status := c.Writer.Status(); {
+		case status >= 500:
+			access.Error("request completed")
+		case status >= 400:
+			access.Warn("request completed")
+		default:
+			access.Info("request completed")
+		}
+	}
+}
+
+// FromContext returns the logger for the current request, including the
+// authenticated user ID once JWTAuthMiddleware has run. Outside a request it
+// falls back to the global logger.
+func FromContext(ctx context.Context) *log.Entry {
+	if c, ok := ctx.(*gin.Context); ok {
+		entry, ok := c.Value(loggerKey).(*log.Entry)
+		if !ok {
+			entry = log.NewEntry(log.StandardLogger())
+		}
+		if userID := c.GetString("user_id"); userID != "" {
+			entry = entry.WithField("user_id", userID)
+		}
+		return entry
+	}
+	if entry, ok := ctx.Value(ctxKey{}).(*log.Entry); ok {
+		return entry
+	}
+	return log.NewEntry(log.StandardLogger())
+}
+
+// RequestID returns the ID assigned to the current request, if any.
+func RequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
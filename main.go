@@ -3,10 +3,21 @@ package main
 import (
 	"context"
 	"expense-tracker/auth"
-	"expense-tracker/controller"
+	"expense-tracker/config"
 	_ "expense-tracker/docs"
+	"expense-tracker/grpcapi"
+	"expense-tracker/health"
+	"expense-tracker/logging"
+	"expense-tracker/metrics"
+	"expense-tracker/notify"
+	"expense-tracker/oidc"
 	"expense-tracker/postgresql"
+	"expense-tracker/problem"
+	"expense-tracker/routes"
+	"expense-tracker/service"
+	"expense-tracker/tracing"
 
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -19,6 +30,8 @@ import (
 	log "github.com/sirupsen/logrus"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 // @title Expense Tracker API
@@ -35,28 +48,75 @@ func main() {
 	id := uuid.New()
 	println(id.String())
 
-	postgresql.ConnectPostgres()
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	logging.Setup(cfg.Log.Level)
+	if err := auth.Configure(cfg.JWT); err != nil {
+		log.Fatalf("Failed to load JWT keys: %v", err)
+	}
+	auth.SetSessionValidator(service.ValidateSession)
+	auth.SetAccessTokenValidator(service.ValidateAccessToken)
+	if err := auth.ConfigurePasswords(cfg.Password); err != nil {
+		log.Fatalf("Failed to load password policy: %v", err)
+	}
+	notifier, err := notify.New(cfg.Password)
+	if err != nil {
+		log.Fatalf("Failed to create notifier: %v", err)
+	}
+	service.Configure(service.NewConfig(*cfg, notifier))
+
+	if cfg.OIDC.Enabled() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		service.SSO, err = oidc.Discover(ctx, cfg.OIDC, nil)
+		cancel()
+		if err != nil {
+			log.Fatalf("Failed to discover OIDC provider: %v", err)
+		}
+	}
+
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
+	postgresql.Connect(cfg.Database)
+	if err := tracing.InstrumentDB(postgresql.DB); err != nil {
+		log.Fatalf("Failed to instrument database: %v", err)
+	}
+	if sqlDB, err := postgresql.DB.DB(); err == nil {
+		if err := metrics.RegisterDBStats(sqlDB); err != nil {
+			log.Errorf("Failed to register DB metrics: %v", err)
+		}
+	}
 
-	s := gin.Default()
+	rateStore, err := auth.NewRateLimitStore(cfg.RateLimit)
+	if err != nil {
+		log.Fatalf("Failed to create rate limit store: %v", err)
+	}
+	limiter, err := auth.NewRateLimiter(rateStore, cfg.RateLimit)
+	if err != nil {
+		log.Fatalf("Invalid rate limit config: %v", err)
+	}
 
-	// Public routes
-	s.POST("/api/v1/login", controller.Login)
-	s.POST("/api/v1/signup", controller.SignUp)
+	checker := health.New(cfg.Server.HealthCheckTimeout.Duration)
+	checker.Register("database", postgresql.Ping)
+	checker.Register("migrations", postgresql.CheckMigrations)
 
-	// Protected routes with JWT and Rate Limiting
-	r := s.Group("/api/v1/expenses")
-	r.Use(auth.JWTAuthMiddleware(), auth.RateLimitMiddleware())
-	r.POST("/", controller.CreateExpense)
-	r.GET("/:id", controller.GetExpenseById)
-	r.PUT("/:id", controller.UpdateExpense)
-	r.DELETE("/:id", controller.DeleteExpense)
-	r.GET("/", controller.ListExpensesWithFilters)
-	r.GET("/summary", controller.Summary)
+	s := gin.New()
+	s.Use(tracing.Middleware(cfg.Tracing.ServiceName), logging.Middleware(), metrics.Middleware(), problem.Recovery())
+	s.GET("/metrics", gin.WrapH(metrics.Handler()))
+	s.GET("/healthz", checker.Liveness)
+	s.GET("/readyz", checker.Readiness)
+
+	routes.Register(s, limiter, cfg.GraphQL)
 
 	s.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	srv := &http.Server{
-		Addr:    ":8080",
+		Addr:    cfg.Server.Addr,
 		Handler: s,
 	}
 	go func() {
@@ -64,14 +124,54 @@ func main() {
 			log.Fatalf("listen: %s\n", err)
 		}
 	}()
+
+	var grpcSrv *grpc.Server
+	if cfg.Server.GRPCAddr != "" {
+		lis, err := net.Listen("tcp", cfg.Server.GRPCAddr)
+		if err != nil {
+			log.Fatalf("grpc listen: %s\n", err)
+		}
+		grpcSrv = grpcapi.NewServer(limiter)
+		go func() {
+			if err := grpcSrv.Serve(lis); err != nil {
+				log.Fatalf("grpc serve: %s\n", err)
+			}
+		}()
+		probe, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			log.Fatalf("grpc health client: %s\n", err)
+		}
+		defer probe.Close()
+		checker.Register("grpc", grpcapi.ReadinessCheck(probe))
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Info("Shutting down server...")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	checker.BeginShutdown()
+	time.Sleep(cfg.Server.DrainDelay.Duration)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Duration)
 	defer cancel()
+	grpcStopped := make(chan struct{})
+	go func() {
+		if grpcSrv != nil {
+			grpcSrv.GracefulStop()
+		}
+		close(grpcStopped)
+	}()
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatal("Server forced to shutdown:", err)
 	}
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		if grpcSrv != nil {
+			grpcSrv.Stop()
+		}
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		log.Errorf("Failed to flush traces: %v", err)
+	}
 	log.Info("Server exiting")
 }
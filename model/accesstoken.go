@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// AccessToken is a named personal access token for scripts and
+// integrations. Only the SHA-256 of the token is stored; Prefix keeps its
+// first characters so users can tell their tokens apart.
+type AccessToken struct {
+	Id         string     `gorm:"primaryKey" json:"id"`
+	UserId     string     `gorm:"not null;index" json:"-"`
+	Name       string     `gorm:"not null" json:"name"`
+	TokenHash  string     `gorm:"not null;uniqueIndex" json:"-"`
+	Prefix     string     `gorm:"not null" json:"prefix"`
+	Scopes     []string   `gorm:"serializer:json;not null" json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// Account is somewhere money is kept: a checking or savings account, a
+// credit card, a cash wallet. Its balance is the opening balance plus the
+// income and minus the expenses linked to it, adjusted by transfers.
+type Account struct {
+	Id     string `gorm:"primaryKey" json:"id"`
+	UserId string `gorm:"not null;index" json:"user_id"`
+	// HouseholdId is set for accounts shared in a household ledger.
+	HouseholdId    string    `gorm:"index;not null;default:''" json:"household_id,omitempty"`
+	Name           string    `gorm:"not null" json:"name"`
+	Kind           string    `gorm:"not null" json:"kind"`
+	Currency       string    `gorm:"not null" json:"currency"`
+	OpeningBalance float64   `gorm:"not null" json:"opening_balance"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Transfer moves money between two accounts. It changes both balances but
+// is neither income nor an expense. ToAmount is what arrives, in the
+// destination account's currency.
+type Transfer struct {
+	Id            string    `gorm:"primaryKey" json:"id"`
+	UserId        string    `gorm:"not null" json:"user_id"`
+	FromAccountId string    `gorm:"not null;index" json:"from_account_id"`
+	ToAccountId   string    `gorm:"not null;index" json:"to_account_id"`
+	Amount        float64   `gorm:"not null" json:"amount"`
+	ToAmount      float64   `gorm:"not null" json:"to_amount"`
+	Description   string    `gorm:"not null" json:"description"`
+	TimeStamp     time.Time `json:"timestamp"`
+}
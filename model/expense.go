@@ -4,10 +4,24 @@ import "time"
 
 type Expense struct {
 	Id          string  `gorm:"primaryKey"`
-	User_id     string  `gorm:"not null"`
+	User_id     string  `gorm:"not null;uniqueIndex:idx_expenses_personal_external_id,where:household_id = '' AND external_id <> ''"`
 	Amount      float64 `gorm:"not null"`
 	Currency    string  `gorm:"default:USD;not null"`
 	Category    string  `gorm:"not null"`
 	Description string  `gorm:"not null"`
 	TimeStamp   time.Time
+	// Household_id is set for expenses in a shared household ledger;
+	// User_id is then the member the expense is attributed to.
+	Household_id string `gorm:"index;not null;default:'';uniqueIndex:idx_expenses_household_external_id,where:household_id <> '' AND external_id <> ''"`
+	// Account_id links the record to the account the money moved through;
+	// empty when it is not tracked.
+	Account_id string `gorm:"index;not null;default:''"`
+	// External_id is the bank's ID of an imported transaction, used to skip
+	// it when a statement is imported again, and is unique within a ledger;
+	// Payee and Memo keep the statement's raw text.
+	External_id string `gorm:"index;not null;default:'';uniqueIndex:idx_expenses_personal_external_id;uniqueIndex:idx_expenses_household_external_id"`
+	Payee       string `gorm:"not null;default:''"`
+	Memo        string `gorm:"not null;default:''"`
+	// Tags are free-form labels, set by the user or by rules.
+	Tags []string `gorm:"serializer:json"`
 }
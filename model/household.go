@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// Household is a shared ledger that several users record expenses in.
+type Household struct {
+	Id        string `gorm:"primaryKey"`
+	Name      string `gorm:"not null"`
+	OwnerId   string `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+// HouseholdMember gives a user a role (owner, editor or viewer) in a household.
+type HouseholdMember struct {
+	HouseholdId string `gorm:"primaryKey"`
+	UserId      string `gorm:"primaryKey;index"`
+	Role        string `gorm:"not null"`
+	JoinedAt    time.Time
+}
+
+// HouseholdInvite is a single-use code that adds whoever redeems it to a
+// household with the given role.
+type HouseholdInvite struct {
+	Code        string `gorm:"primaryKey"`
+	HouseholdId string `gorm:"not null;index"`
+	Role        string `gorm:"not null"`
+	CreatedBy   string `gorm:"not null"`
+	ExpiresAt   time.Time
+}
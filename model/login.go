@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// LoginAttempt is a failed login, kept so admins can see who is being
+// targeted and from where.
+type LoginAttempt struct {
+	Id       string `gorm:"primaryKey" json:"id"`
+	UserName string `gorm:"not null;index" json:"user_name"`
+	// UserId is empty when the user name does not exist.
+	UserId    string    `json:"user_id,omitempty"`
+	ClientIP  string    `json:"client_ip"`
+	Reason    string    `gorm:"not null" json:"reason"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// LoginThrottle counts the consecutive failed logins for one user name,
+// whether or not it exists, so lockouts do not reveal which names do.
+type LoginThrottle struct {
+	// Key is the lower-cased user name.
+	Key         string `gorm:"primaryKey"`
+	Failures    int    `gorm:"not null;default:0"`
+	LastFailure time.Time
+	LockedUntil time.Time
+}
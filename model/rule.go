@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// Rule categorizes a user's expenses automatically: when every condition
+// that is set matches an expense, the actions are applied to it. Rules run
+// in Priority order, lowest first.
+type Rule struct {
+	Id         string         `gorm:"primaryKey" json:"id"`
+	UserId     string         `gorm:"not null;index" json:"-"`
+	Name       string         `gorm:"not null" json:"name"`
+	Priority   int            `gorm:"not null" json:"priority"`
+	Conditions RuleConditions `gorm:"embedded;embeddedPrefix:if_" json:"conditions"`
+	Actions    RuleActions    `gorm:"embedded;embeddedPrefix:then_" json:"actions"`
+	CreatedAt  time.Time      `json:"created_at"`
+}
+
+// RuleConditions are the tests an expense must pass; empty ones are not
+// checked. Amounts are compared in the expense's own currency.
+type RuleConditions struct {
+	DescriptionContains string   `json:"description_contains,omitempty"`
+	DescriptionRegex    string   `json:"description_regex,omitempty"`
+	MinAmount           *float64 `json:"min_amount,omitempty"`
+	MaxAmount           *float64 `json:"max_amount,omitempty"`
+	Currency            string   `json:"currency,omitempty"`
+	AccountId           string   `json:"account_id,omitempty"`
+}
+
+// RuleActions change a matching expense; empty ones leave it alone. Tags
+// are added to those the expense already has.
+type RuleActions struct {
+	Category    string   `json:"category,omitempty"`
+	Tags        []string `gorm:"serializer:json" json:"tags,omitempty"`
+	Description string   `json:"description,omitempty"`
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// TwoFactor is a user's TOTP enrolment. The secret is stored as is because
+// codes are recomputed from it on every login.
+type TwoFactor struct {
+	UserId string `gorm:"primaryKey"`
+	Secret string `gorm:"not null"`
+	// EnabledAt is nil until the enrolment is confirmed with a first code.
+	EnabledAt *time.Time
+	// LastStep is the TOTP time step of the last accepted code, so a code
+	// cannot be replayed within its validity window.
+	LastStep int64 `gorm:"not null;default:0"`
+}
+
+// RecoveryCode is a single-use code that stands in for a TOTP code. Only its
+// SHA-256 is stored.
+type RecoveryCode struct {
+	CodeHash string `gorm:"primaryKey"`
+	UserId   string `gorm:"not null;index"`
+}
@@ -1,7 +1,33 @@
 package model
 
+import "time"
+
 type User struct {
 	UserId   string `gorm:"primaryKey"`
 	UserName string `gorm:"not null;unique"`
 	Password string `gorm:"not null"`
+	Tier     string `gorm:"default:free;not null"`
+	// SessionVersion is embedded in issued tokens; incrementing it (on a
+	// password change or reset) revokes every token issued before.
+	SessionVersion int `gorm:"not null;default:0"`
+}
+
+// PasswordReset is an outstanding password reset token. Only the SHA-256 of
+// the token is stored, so a database leak does not expose usable tokens.
+type PasswordReset struct {
+	TokenHash string `gorm:"primaryKey"`
+	UserId    string `gorm:"not null;index"`
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// ExternalIdentity links an account at an OpenID Connect provider, named by
+// its issuer and subject, to a user.
+type ExternalIdentity struct {
+	Issuer  string `gorm:"primaryKey"`
+	Subject string `gorm:"primaryKey"`
+	UserId  string `gorm:"not null;index"`
+	// Email is the provider's email claim at link time, for support only.
+	Email     string
+	CreatedAt time.Time
 }
@@ -0,0 +1,102 @@
+// Package notify delivers account messages, such as password reset tokens,
+// to users. Users have no e-mail address yet, so the built-in notifiers are
+// meant for local use; a real deployment plugs in its own Notifier.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"expense-tracker/config"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// KindPasswordReset is the Kind of password reset messages.
+const KindPasswordReset = "password_reset"
+
+// Message is one notification for a user.
+type Message struct {
+	Kind      string    `json:"kind"`
+	UserID    string    `json:"user_id"`
+	UserName  string    `json:"user_name"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Notifier delivers messages to users.
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// New returns the notifier selected by cfg.Notifier.
+func New(cfg config.Password) (Notifier, error) {
+	switch cfg.Notifier {
+	case "log":
+		return Log{}, nil
+	case "file":
+		return &File{Path: cfg.NotifierFile}, nil
+	}
+	return nil, fmt.Errorf("unknown notifier %q", cfg.Notifier)
+}
+
+// Log writes messages, including their tokens, to the service log.
+type Log struct{}
+
+func (Log) Notify(_ context.Context, msg Message) error {
+	log.WithFields(log.Fields{
+		"kind":       msg.Kind,
+		"user_id":    msg.UserID,
+		"user_name":  msg.UserName,
+		"token":      msg.Token,
+		"expires_at": msg.ExpiresAt,
+	}).Info("Notification")
+	return nil
+}
+
+// File appends messages as JSON lines to Path, readable only by its owner.
+type File struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (f *File) Notify(_ context.Context, msg Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out, err := os.OpenFile(f.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := out.Write(append(data, '\n')); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Memory records messages so tests can read them back.
+type Memory struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func (m *Memory) Notify(_ context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages returns the messages received so far.
+func (m *Memory) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.messages...)
+}
@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"expense-tracker/config"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile_AppendsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.jsonl")
+	n, err := New(config.Password{Notifier: "file", NotifierFile: path})
+	require.NoError(t, err)
+
+	expires := time.Date(2025, 7, 1, 13, 0, 0, 0, time.UTC)
+	for _, token := range []string{"first", "second"} {
+		require.NoError(t, n.Notify(context.Background(), Message{Kind: KindPasswordReset, UserName: "alice", Token: token, ExpiresAt: expires}))
+	}
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	var tokens []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var msg Message
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &msg))
+		assert.Equal(t, expires, msg.ExpiresAt)
+		tokens = append(tokens, msg.Token)
+	}
+	assert.Equal(t, []string{"first", "second"}, tokens)
+}
+
+func TestNew_UnknownNotifier(t *testing.T) {
+	_, err := New(config.Password{Notifier: "smtp"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,10 @@
+package oidc
+
+import "time"
+
+// ExpireKeys lets tests skip the JWKS refetch rate limit.
+func ExpireKeys(p *Provider) {
+	p.keys.mu.Lock()
+	defer p.keys.mu.Unlock()
+	p.keys.fetched = time.Time{}
+}
@@ -0,0 +1,109 @@
+package oidc_test
+
+import (
+	"context"
+	"expense-tracker/config"
+	"expense-tracker/oidc"
+	"expense-tracker/oidc/oidctest"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func discover(t *testing.T, secret string) (*oidctest.Server, *oidc.Provider) {
+	t.Helper()
+	mock := oidctest.NewServer("tracker", secret)
+	t.Cleanup(mock.Close)
+	p, err := oidc.Discover(context.Background(), config.OIDC{
+		Issuer:       mock.Issuer(),
+		ClientID:     "tracker",
+		ClientSecret: secret,
+		RedirectURL:  "http://tracker.test/callback",
+		Scopes:       []string{"openid"},
+	}, nil)
+	require.NoError(t, err)
+	return mock, p
+}
+
+// authorize follows the provider's redirect and returns the code and state.
+func authorize(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return loc.Query().Get("code"), loc.Query().Get("state")
+}
+
+func TestCodeFlowWithPKCE(t *testing.T) {
+	mock, p := discover(t, "s3cret")
+	mock.SignInAs(map[string]interface{}{"sub": "abc", "email": "a@example.com", "email_verified": true, "preferred_username": "alice"})
+	ctx := context.Background()
+
+	verifier, _ := oidc.RandomString()
+	code, state := authorize(t, p.AuthCodeURL("st", "n0nce", verifier))
+	assert.Equal(t, "st", state)
+
+	_, err := p.Exchange(ctx, code, "wrong-verifier")
+	assert.ErrorContains(t, err, "PKCE")
+
+	code, _ = authorize(t, p.AuthCodeURL("st", "n0nce", verifier))
+	raw, err := p.Exchange(ctx, code, verifier)
+	require.NoError(t, err)
+	_, err = p.Exchange(ctx, code, verifier)
+	assert.Error(t, err, "codes are single use")
+
+	_, err = p.Verify(ctx, raw, "other-nonce")
+	assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+	claims, err := p.Verify(ctx, raw, "n0nce")
+	require.NoError(t, err)
+	assert.Equal(t, "abc", claims.Subject)
+	assert.Equal(t, "a@example.com", claims.Email)
+	assert.True(t, claims.EmailVerified)
+	assert.Equal(t, "alice", claims.String("preferred_username"))
+}
+
+func TestVerify_RejectsBadTokens(t *testing.T) {
+	mock, p := discover(t, "")
+	ctx := context.Background()
+	for name, claims := range map[string]map[string]interface{}{
+		"wrong audience": {"sub": "a", "aud": "someone-else"},
+		"wrong issuer":   {"sub": "a", "iss": "https://evil.test"},
+		"expired":        {"sub": "a", "exp": time.Now().Add(-time.Hour).Unix()},
+		"no subject":     {},
+		"foreign azp":    {"sub": "a", "aud": []string{"tracker", "other"}, "azp": "other"},
+	} {
+		_, err := p.Verify(ctx, mock.SignIDToken(claims), "")
+		assert.ErrorIs(t, err, oidc.ErrInvalidIDToken, name)
+	}
+	_, err := p.Verify(ctx, mock.SignIDToken(map[string]interface{}{"sub": "a", "aud": []string{"tracker", "other"}, "azp": "tracker"}), "")
+	assert.NoError(t, err)
+}
+
+func TestVerify_FollowsKeyRotation(t *testing.T) {
+	mock, p := discover(t, "")
+	ctx := context.Background()
+	_, err := p.Verify(ctx, mock.SignIDToken(map[string]interface{}{"sub": "a"}), "")
+	require.NoError(t, err)
+
+	// A token signed with a new key makes the provider refetch the JWKS; the
+	// refetch is rate limited, so back-date the last one.
+	mock.RotateKey()
+	oidc.ExpireKeys(p)
+	_, err = p.Verify(ctx, mock.SignIDToken(map[string]interface{}{"sub": "a"}), "")
+	assert.NoError(t, err)
+}
+
+func TestDiscover_IssuerMismatch(t *testing.T) {
+	mock := oidctest.NewServer("tracker", "")
+	defer mock.Close()
+	_, err := oidc.Discover(context.Background(), config.OIDC{Issuer: mock.Issuer() + "/"}, nil)
+	assert.ErrorContains(t, err, "does not match")
+}
@@ -0,0 +1,230 @@
+// Package oidctest is a minimal OpenID Connect provider for tests and local
+// development. It signs in a preset user without asking, supports the
+// authorization code flow with PKCE, and can roll its signing key.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"expense-tracker/oidc"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Provider is the mock provider's http.Handler.
+type Provider struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+
+	mu    sync.Mutex
+	user  map[string]interface{}
+	key   *rsa.PrivateKey
+	kid   int
+	old   []*rsa.PrivateKey
+	codes map[string]grant
+	mux   *http.ServeMux
+}
+
+type grant struct {
+	claims      map[string]interface{}
+	nonce       string
+	challenge   string
+	redirectURI string
+}
+
+// NewProvider returns a provider for issuer that accepts clientID, and
+// clientSecret unless it is empty. It signs in as subject "user-1" until
+// SignInAs is called.
+func NewProvider(issuer, clientID, clientSecret string) *Provider {
+	p := &Provider{
+		issuer:       issuer,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		user:         map[string]interface{}{"sub": "user-1", "preferred_username": "user1"},
+		codes:        map[string]grant{},
+		mux:          http.NewServeMux(),
+	}
+	p.RotateKey()
+	p.mux.HandleFunc("GET /.well-known/openid-configuration", p.discovery)
+	p.mux.HandleFunc("GET /jwks", p.jwks)
+	p.mux.HandleFunc("GET /authorize", p.authorize)
+	p.mux.HandleFunc("POST /token", p.token)
+	return p
+}
+
+// Server is a Provider listening on a local httptest server.
+type Server struct {
+	*Provider
+	*httptest.Server
+}
+
+// NewServer starts a provider on a local port. Close it when done.
+func NewServer(clientID, clientSecret string) *Server {
+	s := &Server{}
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.Provider.ServeHTTP(w, r)
+	}))
+	s.Server.Start()
+	s.Provider = NewProvider(s.Server.URL, clientID, clientSecret)
+	return s
+}
+
+// Issuer returns the provider's issuer URL.
+func (p *Provider) Issuer() string { return p.issuer }
+
+func (p *Provider) ServeHTTP(w http.ResponseWriter, r *http.Request) { p.mux.ServeHTTP(w, r) }
+
+// SignInAs sets the claims of the user signed in by the next authorization
+// request. claims must include "sub".
+func (p *Provider) SignInAs(claims map[string]interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.user = claims
+}
+
+// RotateKey starts signing with a new key. Old keys stay in the JWKS, as
+// they would during a real rollover.
+func (p *Provider) RotateKey() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.key != nil {
+		p.old = append(p.old, p.key)
+	}
+	p.key = key
+	p.kid++
+}
+
+// SignIDToken signs claims with the current key, filling in iss, aud, iat
+// and exp if they are missing.
+func (p *Provider) SignIDToken(claims map[string]interface{}) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.sign(claims)
+}
+
+// sign must be called with mu held.
+func (p *Provider) sign(claims map[string]interface{}) string {
+	mc := jwt.MapClaims{
+		"iss": p.issuer,
+		"aud": p.clientID,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range claims {
+		mc[k] = v
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, mc)
+	token.Header["kid"] = p.keyID(p.kid)
+	signed, err := token.SignedString(p.key)
+	if err != nil {
+		panic(err)
+	}
+	return signed
+}
+
+func (p *Provider) keyID(n int) string { return "key-" + strconv.Itoa(n) }
+
+func (p *Provider) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, oidc.Metadata{
+		Issuer:                p.issuer,
+		AuthorizationEndpoint: p.issuer + "/authorize",
+		TokenEndpoint:         p.issuer + "/token",
+		JWKSURI:               p.issuer + "/jwks",
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, _ *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var set oidc.JWKS
+	keys := append(append([]*rsa.PrivateKey{}, p.old...), p.key)
+	first := p.kid - len(keys) + 1
+	for i, k := range keys {
+		jwk, err := oidc.NewJWK(p.keyID(first+i), "RS256", &k.PublicKey)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	writeJSON(w, http.StatusOK, set)
+}
+
+// authorize signs the preset user in and redirects straight back.
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || redirect.Scheme == "" || q.Get("client_id") != p.clientID {
+		http.Error(w, "invalid client_id or redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "only the code flow with S256 PKCE is supported", http.StatusBadRequest)
+		return
+	}
+	code, _ := oidc.RandomString()
+	p.mu.Lock()
+	p.codes[code] = grant{claims: p.user, nonce: q.Get("nonce"), challenge: q.Get("code_challenge"), redirectURI: redirect.String()}
+	p.mu.Unlock()
+
+	back := redirect.Query()
+	back.Set("code", code)
+	back.Set("state", q.Get("state"))
+	redirect.RawQuery = back.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	if p.clientSecret != "" {
+		id, secret, _ := r.BasicAuth()
+		if id != p.clientID || secret != p.clientSecret {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+			return
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	code := r.PostForm.Get("code")
+	g, ok := p.codes[code]
+	delete(p.codes, code)
+	switch {
+	case !ok, r.PostForm.Get("redirect_uri") != g.redirectURI, r.PostForm.Get("client_id") != p.clientID:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	case oidc.Challenge(r.PostForm.Get("code_verifier")) != g.challenge:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+	claims := map[string]interface{}{"nonce": g.nonce}
+	for k, v := range g.claims {
+		claims[k] = v
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": code,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     p.sign(claims),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
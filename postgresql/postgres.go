@@ -1,9 +1,12 @@
 package postgresql
 
 import (
+	"context"
+	"expense-tracker/config"
 	"expense-tracker/model"
-	"os"
+	"fmt"
 
+	"github.com/glebarez/sqlite"
 	log "github.com/sirupsen/logrus"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -11,30 +14,76 @@ import (
 
 var DB *gorm.DB // global DB instance
 
-func ConnectPostgres() {
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
-		dsn = "user=user password=password dbname=expense_tracker host=localhost port=5432 sslmode=disable"
+// Models lists every model managed by AutoMigrate.
+var Models = []interface{}{
+	&model.User{}, &model.PasswordReset{}, &model.ExternalIdentity{}, &model.TwoFactor{}, &model.RecoveryCode{},
+	&model.AccessToken{}, &model.LoginAttempt{}, &model.LoginThrottle{},
+	&model.Expense{}, &model.Income{}, &model.Account{}, &model.Transfer{}, &model.Rule{},
+	&model.Household{}, &model.HouseholdMember{}, &model.HouseholdInvite{},
+}
+
+// Open connects to the database selected by cfg.Driver and migrates Models.
+// SQLite is limited to one connection, which serializes writers and keeps an
+// in-memory database alive for the life of the pool.
+func Open(cfg config.Database) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch cfg.Driver {
+	case "", "postgres":
+		dialector = postgres.Open(cfg.URL)
+	case "sqlite":
+		dialector = sqlite.Open(cfg.URL)
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
 	}
-	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(dialector, &gorm.Config{})
 	if err != nil {
-		log.Fatalf("Failed to connect to DB: %v", err)
+		return nil, fmt.Errorf("connect: %w", err)
 	}
 
-	// Optional: check if DB is really connected
-	sqlDB, err := DB.DB()
+	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatalf("Failed to get database instance: %v", err)
+		return nil, fmt.Errorf("get database instance: %w", err)
+	}
+	if cfg.Driver == "sqlite" {
+		sqlDB.SetMaxOpenConns(1)
 	}
 	if err := sqlDB.Ping(); err != nil {
-		log.Fatalf("Database not reachable: %v", err)
+		return nil, fmt.Errorf("database not reachable: %w", err)
 	}
 
 	// Auto-migrate your models (creates tables if not exist, does not drop data)
-	if err := DB.AutoMigrate(&model.User{}, &model.Expense{}); err != nil {
-		log.Fatalf("Failed to auto-migrate database schema: %v", err)
+	if err := db.AutoMigrate(Models...); err != nil {
+		return nil, fmt.Errorf("auto-migrate database schema: %w", err)
 	}
+	return db, nil
+}
 
-	log.Info("Connected to the database and migration complete.")
+// Connect opens the configured database into DB, exiting on failure.
+func Connect(cfg config.Database) {
+	var err error
+	DB, err = Open(cfg)
+	if err != nil {
+		log.Fatalf("Failed to open %s database: %v", cfg.Driver, err)
+	}
+	log.WithField("driver", cfg.Driver).Info("Connected to the database and migration complete.")
+}
+
+// Ping checks that the database is reachable.
+func Ping(ctx context.Context) error {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// CheckMigrations verifies that the table of every model in Models exists.
+func CheckMigrations(ctx context.Context) error {
+	migrator := DB.WithContext(ctx).Migrator()
+	for _, m := range Models {
+		if !migrator.HasTable(m) {
+			return fmt.Errorf("table for %T is missing", m)
+		}
+	}
+	return nil
 }
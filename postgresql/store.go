@@ -0,0 +1,771 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"expense-tracker/model"
+	"expense-tracker/store"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ExpenseStore is the SQL store.ExpenseStore. The zero value uses the global DB.
+type ExpenseStore struct {
+	DB *gorm.DB
+}
+
+func (s ExpenseStore) db(ctx context.Context) *gorm.DB {
+	if s.DB != nil {
+		return s.DB.WithContext(ctx)
+	}
+	return DB.WithContext(ctx)
+}
+
+// filter applies f to query. Date bounds are passed as times rather than
+// strings so that SQLite, which stores timestamps as text, compares them the
+// same way PostgreSQL does.
+func filter(query *gorm.DB, f store.ExpenseFilter) *gorm.DB {
+	from, to, err := f.Bounds()
+	if err != nil {
+		query.AddError(err)
+		return query
+	}
+	if f.UserID != "" {
+		query = query.Where("user_id = ?", f.UserID)
+	}
+	query = query.Where("household_id = ?", f.HouseholdID)
+	if f.Category != "" {
+		query = query.Where("category = ?", f.Category)
+	}
+	if f.Currency != "" {
+		query = query.Where("currency = ?", f.Currency)
+	}
+	if f.AccountID != "" {
+		query = query.Where("account_id = ?", f.AccountID)
+	}
+	if !from.IsZero() {
+		query = query.Where("time_stamp >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("time_stamp <= ?", to)
+	}
+	return query
+}
+
+func (s ExpenseStore) Create(ctx context.Context, expense model.Expense) error {
+	db := s.db(ctx)
+	return duplicate(db, db.Create(&expense).Error)
+}
+
+// duplicate turns a unique index violation, such as a bank ID imported twice
+// into one ledger, into store.ErrDuplicate.
+func duplicate(db *gorm.DB, err error) error {
+	if translator, ok := db.Dialector.(gorm.ErrorTranslator); ok && errors.Is(translator.Translate(err), gorm.ErrDuplicatedKey) {
+		return store.ErrDuplicate
+	}
+	return err
+}
+
+func (s ExpenseStore) Get(ctx context.Context, id string) (model.Expense, error) {
+	var expense model.Expense
+	err := s.db(ctx).Where("id = ?", id).First(&expense).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.Expense{}, store.ErrNotFound
+	}
+	return expense, err
+}
+
+func (s ExpenseStore) Update(ctx context.Context, expense model.Expense) error {
+	return s.db(ctx).Save(&expense).Error
+}
+
+func (s ExpenseStore) Delete(ctx context.Context, id string) error {
+	return s.db(ctx).Delete(&model.Expense{}, "id = ?", id).Error
+}
+
+func (s ExpenseStore) List(ctx context.Context, f store.ExpenseFilter) ([]model.Expense, error) {
+	var expenses []model.Expense
+	err := filter(s.db(ctx), f).Order("time_stamp, id").Limit(f.PageSize()).Offset(f.Offset).Find(&expenses).Error
+	return expenses, err
+}
+
+func (s ExpenseStore) Stream(ctx context.Context, f store.ExpenseFilter, batchSize int, fn func(model.Expense) error) error {
+	return streamByTime(func() *gorm.DB { return filter(s.db(ctx), f) }, batchSize,
+		func(e model.Expense) (time.Time, string) { return e.TimeStamp, e.Id }, fn)
+}
+
+// streamByTime reads what query selects in (time_stamp, id) order,
+// batchSize rows at a time, and passes each row to fn. Every page resumes
+// after the last row of the one before. GORM's FindInBatches is no use
+// here: it resumes on the primary key alone, so with rows sorted by time
+// it skips some and repeats others.
+func streamByTime[T any](query func() *gorm.DB, batchSize int, key func(T) (time.Time, string), fn func(T) error) error {
+	batchSize = max(batchSize, 1)
+	var after time.Time
+	var afterID string
+	for {
+		page := query().Order("time_stamp, id").Limit(batchSize)
+		if afterID != "" {
+			page = page.Where("(time_stamp > ? OR (time_stamp = ? AND id > ?))", after, after, afterID)
+		}
+		var batch []T
+		if err := page.Find(&batch).Error; err != nil {
+			return err
+		}
+		for _, row := range batch {
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		if len(batch) < batchSize {
+			return nil
+		}
+		after, afterID = key(batch[len(batch)-1])
+	}
+}
+
+func (s ExpenseStore) Summarize(ctx context.Context, f store.ExpenseFilter) (map[string]float64, error) {
+	return s.totals(ctx, f, "category")
+}
+
+func (s ExpenseStore) SummarizeByUser(ctx context.Context, f store.ExpenseFilter) (map[string]float64, error) {
+	return s.totals(ctx, f, "user_id")
+}
+
+// totals sums amounts grouped by column, which must be a trusted column name.
+func (s ExpenseStore) totals(ctx context.Context, f store.ExpenseFilter, column string) (map[string]float64, error) {
+	type Result struct {
+		GroupKey string
+		Total    float64
+	}
+	var results []Result
+	query := filter(s.db(ctx).Model(&model.Expense{}), f)
+	if err := query.Select(column + " AS group_key, SUM(amount) AS total").Group(column).Scan(&results).Error; err != nil {
+		return nil, err
+	}
+
+	summary := make(map[string]float64, len(results))
+	for _, r := range results {
+		summary[r.GroupKey] = r.Total
+	}
+	return summary, nil
+}
+
+func (s ExpenseStore) ByCategory(ctx context.Context, f store.ExpenseFilter, categories []string, perCategory int) (map[string][]model.Expense, error) {
+	var expenses []model.Expense
+	err := filter(s.db(ctx), f).Where("category IN ?", categories).
+		Order("time_stamp DESC, id").Find(&expenses).Error
+	if err != nil {
+		return nil, err
+	}
+	grouped := make(map[string][]model.Expense, len(categories))
+	for _, e := range expenses {
+		if len(grouped[e.Category]) < perCategory {
+			grouped[e.Category] = append(grouped[e.Category], e)
+		}
+	}
+	return grouped, nil
+}
+
+func (s ExpenseStore) ExternalIDs(ctx context.Context, f store.ExpenseFilter, ids []string) (map[string]bool, error) {
+	return externalIDs(filter(s.db(ctx).Model(&model.Expense{}), f), ids)
+}
+
+// externalIDsBatch bounds the IN lists of externalIDs well below the
+// parameter limits of PostgreSQL and SQLite.
+const externalIDsBatch = 500
+
+// externalIDs returns which of ids are the external_id of a row matched by
+// query.
+func externalIDs(query *gorm.DB, ids []string) (map[string]bool, error) {
+	found := map[string]bool{}
+	query = query.Session(&gorm.Session{})
+	for start := 0; start < len(ids); start += externalIDsBatch {
+		var existing []string
+		batch := ids[start:min(start+externalIDsBatch, len(ids))]
+		if err := query.Where("external_id IN ?", batch).Pluck("external_id", &existing).Error; err != nil {
+			return nil, err
+		}
+		for _, id := range existing {
+			found[id] = true
+		}
+	}
+	return found, nil
+}
+
+// IncomeStore is the SQL store.IncomeStore. The zero value uses the global DB.
+type IncomeStore struct {
+	DB *gorm.DB
+}
+
+func (s IncomeStore) db(ctx context.Context) *gorm.DB {
+	if s.DB != nil {
+		return s.DB.WithContext(ctx)
+	}
+	return DB.WithContext(ctx)
+}
+
+func (s IncomeStore) Create(ctx context.Context, income model.Income) error {
+	db := s.db(ctx)
+	return duplicate(db, db.Create(&income).Error)
+}
+
+func (s IncomeStore) Get(ctx context.Context, id string) (model.Income, error) {
+	var income model.Income
+	err := s.db(ctx).Where("id = ?", id).First(&income).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.Income{}, store.ErrNotFound
+	}
+	return income, err
+}
+
+func (s IncomeStore) Update(ctx context.Context, income model.Income) error {
+	return s.db(ctx).Save(&income).Error
+}
+
+func (s IncomeStore) Delete(ctx context.Context, id string) error {
+	return s.db(ctx).Delete(&model.Income{}, "id = ?", id).Error
+}
+
+func (s IncomeStore) List(ctx context.Context, f store.ExpenseFilter) ([]model.Income, error) {
+	var incomes []model.Income
+	err := filter(s.db(ctx), f).Order("time_stamp, id").Limit(f.PageSize()).Offset(f.Offset).Find(&incomes).Error
+	return incomes, err
+}
+
+func (s IncomeStore) Stream(ctx context.Context, f store.ExpenseFilter, batchSize int, fn func(model.Income) error) error {
+	return streamByTime(func() *gorm.DB { return filter(s.db(ctx), f) }, batchSize,
+		func(i model.Income) (time.Time, string) { return i.TimeStamp, i.Id }, fn)
+}
+
+func (s IncomeStore) ExternalIDs(ctx context.Context, f store.ExpenseFilter, ids []string) (map[string]bool, error) {
+	return externalIDs(filter(s.db(ctx).Model(&model.Income{}), f), ids)
+}
+
+// AccountStore is the SQL store.AccountStore. The zero value uses the global DB.
+type AccountStore struct {
+	DB *gorm.DB
+}
+
+func (s AccountStore) db(ctx context.Context) *gorm.DB {
+	if s.DB != nil {
+		return s.DB.WithContext(ctx)
+	}
+	return DB.WithContext(ctx)
+}
+
+func (s AccountStore) Create(ctx context.Context, account model.Account) error {
+	return s.db(ctx).Create(&account).Error
+}
+
+func (s AccountStore) Get(ctx context.Context, id string) (model.Account, error) {
+	var account model.Account
+	err := s.db(ctx).Where("id = ?", id).First(&account).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.Account{}, store.ErrNotFound
+	}
+	return account, err
+}
+
+func (s AccountStore) Update(ctx context.Context, account model.Account) error {
+	return s.db(ctx).Save(&account).Error
+}
+
+func (s AccountStore) List(ctx context.Context, userID, householdID string) ([]model.Account, error) {
+	query := s.db(ctx).Where("household_id = ?", householdID)
+	if householdID == "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	var accounts []model.Account
+	err := query.Order("name, id").Find(&accounts).Error
+	return accounts, err
+}
+
+// TransferStore is the SQL store.TransferStore. The zero value uses the global DB.
+type TransferStore struct {
+	DB *gorm.DB
+}
+
+func (s TransferStore) db(ctx context.Context) *gorm.DB {
+	if s.DB != nil {
+		return s.DB.WithContext(ctx)
+	}
+	return DB.WithContext(ctx)
+}
+
+func (s TransferStore) Create(ctx context.Context, transfer model.Transfer) error {
+	return s.db(ctx).Create(&transfer).Error
+}
+
+func (s TransferStore) Get(ctx context.Context, id string) (model.Transfer, error) {
+	var transfer model.Transfer
+	err := s.db(ctx).Where("id = ?", id).First(&transfer).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.Transfer{}, store.ErrNotFound
+	}
+	return transfer, err
+}
+
+func (s TransferStore) Delete(ctx context.Context, id string) error {
+	return s.db(ctx).Delete(&model.Transfer{}, "id = ?", id).Error
+}
+
+func (s TransferStore) List(ctx context.Context, accountID string) ([]model.Transfer, error) {
+	var transfers []model.Transfer
+	err := s.db(ctx).Where("from_account_id = ? OR to_account_id = ?", accountID, accountID).
+		Order("time_stamp, id").Find(&transfers).Error
+	return transfers, err
+}
+
+// RuleStore is the SQL store.RuleStore. The zero value uses the global DB.
+type RuleStore struct {
+	DB *gorm.DB
+}
+
+func (s RuleStore) db(ctx context.Context) *gorm.DB {
+	if s.DB != nil {
+		return s.DB.WithContext(ctx)
+	}
+	return DB.WithContext(ctx)
+}
+
+func (s RuleStore) Create(ctx context.Context, rule model.Rule) error {
+	return s.db(ctx).Create(&rule).Error
+}
+
+func (s RuleStore) Get(ctx context.Context, id string) (model.Rule, error) {
+	var rule model.Rule
+	err := s.db(ctx).Where("id = ?", id).First(&rule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.Rule{}, store.ErrNotFound
+	}
+	return rule, err
+}
+
+func (s RuleStore) Update(ctx context.Context, rule model.Rule) error {
+	return s.db(ctx).Save(&rule).Error
+}
+
+func (s RuleStore) Delete(ctx context.Context, id string) error {
+	return s.db(ctx).Where("id = ?", id).Delete(&model.Rule{}).Error
+}
+
+func (s RuleStore) List(ctx context.Context, userID string) ([]model.Rule, error) {
+	var rules []model.Rule
+	err := s.db(ctx).Where("user_id = ?", userID).Order("priority, created_at, id").Find(&rules).Error
+	return rules, err
+}
+
+// UserStore is the SQL store.UserStore. The zero value uses the global DB.
+type UserStore struct {
+	DB *gorm.DB
+}
+
+func (s UserStore) db(ctx context.Context) *gorm.DB {
+	if s.DB != nil {
+		return s.DB.WithContext(ctx)
+	}
+	return DB.WithContext(ctx)
+}
+
+func (s UserStore) Create(ctx context.Context, user model.User) error {
+	return s.db(ctx).Create(&user).Error
+}
+
+func (s UserStore) ByName(ctx context.Context, userName string) (model.User, error) {
+	var user model.User
+	err := s.db(ctx).Where("user_name = ?", userName).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.User{}, store.ErrNotFound
+	}
+	return user, err
+}
+
+func (s UserStore) ByID(ctx context.Context, id string) (model.User, error) {
+	var user model.User
+	err := s.db(ctx).Where("user_id = ?", id).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.User{}, store.ErrNotFound
+	}
+	return user, err
+}
+
+func (s UserStore) ByIDs(ctx context.Context, ids []string) (map[string]model.User, error) {
+	var users []model.User
+	if err := s.db(ctx).Where("user_id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[string]model.User, len(users))
+	for _, u := range users {
+		byID[u.UserId] = u
+	}
+	return byID, nil
+}
+
+func (s UserStore) Update(ctx context.Context, user model.User) error {
+	result := s.db(ctx).Model(&model.User{}).Where("user_id = ?", user.UserId).Updates(map[string]interface{}{
+		"user_name":       user.UserName,
+		"password":        user.Password,
+		"tier":            user.Tier,
+		"session_version": user.SessionVersion,
+	})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return store.ErrNotFound
+	}
+	return result.Error
+}
+
+func (s UserStore) CreateReset(ctx context.Context, reset model.PasswordReset) error {
+	return s.db(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", reset.UserId).Delete(&model.PasswordReset{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&reset).Error
+	})
+}
+
+// TakeReset relies on the delete's row count, like HouseholdStore.TakeInvite,
+// so a token cannot be redeemed twice concurrently.
+func (s UserStore) TakeReset(ctx context.Context, tokenHash string) (model.PasswordReset, error) {
+	var reset model.PasswordReset
+	err := s.db(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token_hash = ?", tokenHash).First(&reset).Error; err != nil {
+			return err
+		}
+		result := tx.Where("token_hash = ?", tokenHash).Delete(&model.PasswordReset{})
+		if result.Error == nil && result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return result.Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.PasswordReset{}, store.ErrNotFound
+	}
+	return reset, err
+}
+
+func (s UserStore) DeleteResets(ctx context.Context, userID string) error {
+	return s.db(ctx).Where("user_id = ?", userID).Delete(&model.PasswordReset{}).Error
+}
+
+func (s UserStore) Identity(ctx context.Context, issuer, subject string) (model.ExternalIdentity, error) {
+	var identity model.ExternalIdentity
+	err := s.db(ctx).Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.ExternalIdentity{}, store.ErrNotFound
+	}
+	return identity, err
+}
+
+func (s UserStore) LinkIdentity(ctx context.Context, identity model.ExternalIdentity) error {
+	return s.db(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&model.ExternalIdentity{}).
+			Where("issuer = ? AND subject = ?", identity.Issuer, identity.Subject).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return store.ErrDuplicate
+		}
+		return tx.Create(&identity).Error
+	})
+}
+
+// HouseholdStore is the SQL store.HouseholdStore. The zero value uses the
+// global DB.
+type HouseholdStore struct {
+	DB *gorm.DB
+}
+
+func (s HouseholdStore) db(ctx context.Context) *gorm.DB {
+	if s.DB != nil {
+		return s.DB.WithContext(ctx)
+	}
+	return DB.WithContext(ctx)
+}
+
+func (s HouseholdStore) Create(ctx context.Context, household model.Household, owner model.HouseholdMember) error {
+	return s.db(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&household).Error; err != nil {
+			return err
+		}
+		return tx.Create(&owner).Error
+	})
+}
+
+func (s HouseholdStore) Get(ctx context.Context, id string) (model.Household, error) {
+	var household model.Household
+	err := s.db(ctx).Where("id = ?", id).First(&household).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.Household{}, store.ErrNotFound
+	}
+	return household, err
+}
+
+func (s HouseholdStore) Member(ctx context.Context, householdID, userID string) (model.HouseholdMember, error) {
+	var member model.HouseholdMember
+	err := s.db(ctx).Where("household_id = ? AND user_id = ?", householdID, userID).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.HouseholdMember{}, store.ErrNotFound
+	}
+	return member, err
+}
+
+func (s HouseholdStore) Members(ctx context.Context, householdID string) ([]model.HouseholdMember, error) {
+	var members []model.HouseholdMember
+	err := s.db(ctx).Where("household_id = ?", householdID).Order("joined_at, user_id").Find(&members).Error
+	return members, err
+}
+
+func (s HouseholdStore) Memberships(ctx context.Context, userID string) ([]model.HouseholdMember, error) {
+	var members []model.HouseholdMember
+	err := s.db(ctx).Where("user_id = ?", userID).Order("joined_at, household_id").Find(&members).Error
+	return members, err
+}
+
+func (s HouseholdStore) AddMember(ctx context.Context, member model.HouseholdMember) error {
+	return s.db(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&model.HouseholdMember{}).
+			Where("household_id = ? AND user_id = ?", member.HouseholdId, member.UserId).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return store.ErrDuplicate
+		}
+		return tx.Create(&member).Error
+	})
+}
+
+func (s HouseholdStore) UpdateMember(ctx context.Context, member model.HouseholdMember) error {
+	result := s.db(ctx).Model(&model.HouseholdMember{}).
+		Where("household_id = ? AND user_id = ?", member.HouseholdId, member.UserId).
+		Update("role", member.Role)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return store.ErrNotFound
+	}
+	return result.Error
+}
+
+func (s HouseholdStore) RemoveMember(ctx context.Context, householdID, userID string) error {
+	return s.db(ctx).Where("household_id = ? AND user_id = ?", householdID, userID).
+		Delete(&model.HouseholdMember{}).Error
+}
+
+func (s HouseholdStore) CreateInvite(ctx context.Context, invite model.HouseholdInvite) error {
+	return s.db(ctx).Create(&invite).Error
+}
+
+// TakeInvite relies on the delete's row count so that two concurrent
+// redemptions of the same code cannot both succeed.
+func (s HouseholdStore) TakeInvite(ctx context.Context, code string) (model.HouseholdInvite, error) {
+	var invite model.HouseholdInvite
+	err := s.db(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("code = ?", code).First(&invite).Error; err != nil {
+			return err
+		}
+		result := tx.Where("code = ?", code).Delete(&model.HouseholdInvite{})
+		if result.Error == nil && result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return result.Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.HouseholdInvite{}, store.ErrNotFound
+	}
+	return invite, err
+}
+
+// TwoFactorStore is the SQL store.TwoFactorStore. The zero value uses the
+// global DB.
+type TwoFactorStore struct {
+	DB *gorm.DB
+}
+
+func (s TwoFactorStore) db(ctx context.Context) *gorm.DB {
+	if s.DB != nil {
+		return s.DB.WithContext(ctx)
+	}
+	return DB.WithContext(ctx)
+}
+
+func (s TwoFactorStore) Get(ctx context.Context, userID string) (model.TwoFactor, error) {
+	var tf model.TwoFactor
+	err := s.db(ctx).Where("user_id = ?", userID).First(&tf).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.TwoFactor{}, store.ErrNotFound
+	}
+	return tf, err
+}
+
+func (s TwoFactorStore) Save(ctx context.Context, tf model.TwoFactor) error {
+	return s.db(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&tf).Error
+}
+
+func (s TwoFactorStore) Delete(ctx context.Context, userID string) error {
+	return s.db(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&model.TwoFactor{}).Error
+	})
+}
+
+// UseStep is a single conditional update so that two logins racing with the
+// same code cannot both succeed.
+func (s TwoFactorStore) UseStep(ctx context.Context, userID string, step int64) error {
+	result := s.db(ctx).Model(&model.TwoFactor{}).
+		Where("user_id = ? AND last_step < ?", userID, step).
+		Update("last_step", step)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return store.ErrDuplicate
+	}
+	return result.Error
+}
+
+func (s TwoFactorStore) SetRecoveryCodes(ctx context.Context, userID string, hashes []string) error {
+	codes := make([]model.RecoveryCode, len(hashes))
+	for i, h := range hashes {
+		codes[i] = model.RecoveryCode{CodeHash: h, UserId: userID}
+	}
+	return s.db(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (s TwoFactorStore) UseRecoveryCode(ctx context.Context, userID, hash string) error {
+	result := s.db(ctx).Where("user_id = ? AND code_hash = ?", userID, hash).Delete(&model.RecoveryCode{})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return store.ErrNotFound
+	}
+	return result.Error
+}
+
+func (s TwoFactorStore) RecoveryCodes(ctx context.Context, userID string) (int, error) {
+	var n int64
+	err := s.db(ctx).Model(&model.RecoveryCode{}).Where("user_id = ?", userID).Count(&n).Error
+	return int(n), err
+}
+
+// AccessTokenStore is the SQL store.AccessTokenStore. The zero value uses the
+// global DB.
+type AccessTokenStore struct {
+	DB *gorm.DB
+}
+
+func (s AccessTokenStore) db(ctx context.Context) *gorm.DB {
+	if s.DB != nil {
+		return s.DB.WithContext(ctx)
+	}
+	return DB.WithContext(ctx)
+}
+
+func (s AccessTokenStore) Create(ctx context.Context, token model.AccessToken) error {
+	return s.db(ctx).Create(&token).Error
+}
+
+func (s AccessTokenStore) List(ctx context.Context, userID string) ([]model.AccessToken, error) {
+	var tokens []model.AccessToken
+	err := s.db(ctx).Where("user_id = ?", userID).Order("created_at, id").Find(&tokens).Error
+	return tokens, err
+}
+
+func (s AccessTokenStore) ByHash(ctx context.Context, hash string) (model.AccessToken, error) {
+	var token model.AccessToken
+	err := s.db(ctx).Where("token_hash = ?", hash).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.AccessToken{}, store.ErrNotFound
+	}
+	return token, err
+}
+
+func (s AccessTokenStore) Touch(ctx context.Context, id string, at time.Time) error {
+	return s.db(ctx).Model(&model.AccessToken{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
+
+func (s AccessTokenStore) Delete(ctx context.Context, userID, id string) error {
+	result := s.db(ctx).Where("user_id = ? AND id = ?", userID, id).Delete(&model.AccessToken{})
+	if result.Error == nil && result.RowsAffected == 0 {
+		return store.ErrNotFound
+	}
+	return result.Error
+}
+
+// LoginAttemptStore is the SQL store.LoginAttemptStore. The zero value uses
+// the global DB.
+type LoginAttemptStore struct {
+	DB *gorm.DB
+}
+
+func (s LoginAttemptStore) db(ctx context.Context) *gorm.DB {
+	if s.DB != nil {
+		return s.DB.WithContext(ctx)
+	}
+	return DB.WithContext(ctx)
+}
+
+func (s LoginAttemptStore) Throttle(ctx context.Context, key string) (model.LoginThrottle, error) {
+	var t model.LoginThrottle
+	err := s.db(ctx).Where("key = ?", key).First(&t).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.LoginThrottle{Key: key}, nil
+	}
+	return t, err
+}
+
+// RecordFailure increments the count in the upsert itself so concurrent
+// failures from several replicas are all counted.
+func (s LoginAttemptStore) RecordFailure(ctx context.Context, key string, attempt model.LoginAttempt) (model.LoginThrottle, error) {
+	var t model.LoginThrottle
+	err := s.db(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&attempt).Error; err != nil {
+			return err
+		}
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "key"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"failures":     gorm.Expr("login_throttles.failures + 1"),
+				"last_failure": attempt.CreatedAt,
+			}),
+		}).Create(&model.LoginThrottle{Key: key, Failures: 1, LastFailure: attempt.CreatedAt}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("key = ?", key).First(&t).Error
+	})
+	return t, err
+}
+
+func (s LoginAttemptStore) Lock(ctx context.Context, key string, until time.Time) error {
+	return s.db(ctx).Model(&model.LoginThrottle{}).Where("key = ?", key).Update("locked_until", until).Error
+}
+
+func (s LoginAttemptStore) Reset(ctx context.Context, key string) error {
+	return s.db(ctx).Where("key = ?", key).Delete(&model.LoginThrottle{}).Error
+}
+
+func (s LoginAttemptStore) Failures(ctx context.Context, f store.LoginAttemptFilter) ([]model.LoginAttempt, error) {
+	query := s.db(ctx).Order("created_at DESC")
+	if f.UserName != "" {
+		query = query.Where("user_name = ?", f.UserName)
+	}
+	if !f.Since.IsZero() {
+		query = query.Where("created_at >= ?", f.Since)
+	}
+	if f.Limit > 0 {
+		query = query.Limit(f.Limit)
+	}
+	attempts := []model.LoginAttempt{}
+	err := query.Find(&attempts).Error
+	return attempts, err
+}
@@ -0,0 +1,121 @@
+package postgresql
+
+import (
+	"context"
+	"expense-tracker/config"
+	"expense-tracker/model"
+	"expense-tracker/store"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := Open(config.Database{Driver: "sqlite", URL: ":memory:"})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// streamFixtureTimes returns n timestamps in random ID order, with every
+// third one shared with the next so that ties have to be broken by ID.
+func streamFixtureTimes(n int) []time.Time {
+	base := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	times := make([]time.Time, n)
+	for i := range times {
+		times[i] = base.Add(time.Duration(i-i%3) * time.Hour)
+	}
+	return times
+}
+
+func TestExpenseStore_StreamVisitsEveryRowOnceInOrder(t *testing.T) {
+	ctx := context.Background()
+	s := ExpenseStore{DB: openSQLite(t)}
+	var want []model.Expense
+	for _, ts := range streamFixtureTimes(53) {
+		e := model.Expense{Id: uuid.NewString(), User_id: "u1", Amount: 1, Currency: "USD", Category: "food", TimeStamp: ts}
+		require.NoError(t, s.Create(ctx, e))
+		want = append(want, e)
+	}
+	require.NoError(t, s.Create(ctx, model.Expense{Id: uuid.NewString(), User_id: "u2", Amount: 1, Currency: "USD", Category: "food", TimeStamp: want[0].TimeStamp}))
+	sort.Slice(want, func(i, j int) bool {
+		if !want[i].TimeStamp.Equal(want[j].TimeStamp) {
+			return want[i].TimeStamp.Before(want[j].TimeStamp)
+		}
+		return want[i].Id < want[j].Id
+	})
+
+	var got []string
+	err := s.Stream(ctx, store.ExpenseFilter{UserID: "u1"}, 10, func(e model.Expense) error {
+		got = append(got, e.Id)
+		return nil
+	})
+	require.NoError(t, err)
+	wantIDs := make([]string, len(want))
+	for i, e := range want {
+		wantIDs[i] = e.Id
+	}
+	assert.Equal(t, wantIDs, got)
+}
+
+func TestIncomeStore_StreamVisitsEveryRowOnceInOrder(t *testing.T) {
+	ctx := context.Background()
+	s := IncomeStore{DB: openSQLite(t)}
+	var want []model.Income
+	for _, ts := range streamFixtureTimes(53) {
+		i := model.Income{Id: uuid.NewString(), User_id: "u1", Amount: 1, Currency: "USD", Category: "salary", TimeStamp: ts}
+		require.NoError(t, s.Create(ctx, i))
+		want = append(want, i)
+	}
+	sort.Slice(want, func(i, j int) bool {
+		if !want[i].TimeStamp.Equal(want[j].TimeStamp) {
+			return want[i].TimeStamp.Before(want[j].TimeStamp)
+		}
+		return want[i].Id < want[j].Id
+	})
+
+	var got []string
+	err := s.Stream(ctx, store.ExpenseFilter{UserID: "u1"}, 10, func(i model.Income) error {
+		got = append(got, i.Id)
+		return nil
+	})
+	require.NoError(t, err)
+	wantIDs := make([]string, len(want))
+	for i, income := range want {
+		wantIDs[i] = income.Id
+	}
+	assert.Equal(t, wantIDs, got)
+}
+
+func TestExpenseStore_ExternalIDIsUniquePerLedger(t *testing.T) {
+	ctx := context.Background()
+	s := ExpenseStore{DB: openSQLite(t)}
+	expense := func(userID, householdID, externalID string) model.Expense {
+		return model.Expense{Id: uuid.NewString(), User_id: userID, Household_id: householdID, External_id: externalID,
+			Amount: 1, Currency: "USD", Category: "food", TimeStamp: time.Now()}
+	}
+
+	require.NoError(t, s.Create(ctx, expense("u1", "", "ofx:1:F1")))
+	assert.ErrorIs(t, s.Create(ctx, expense("u1", "", "ofx:1:F1")), store.ErrDuplicate)
+	assert.NoError(t, s.Create(ctx, expense("u2", "", "ofx:1:F1")), "another user's ledger")
+	assert.NoError(t, s.Create(ctx, expense("u1", "h1", "ofx:1:F1")), "a household ledger")
+	assert.ErrorIs(t, s.Create(ctx, expense("u2", "h1", "ofx:1:F1")), store.ErrDuplicate, "whoever it is attributed to")
+	assert.NoError(t, s.Create(ctx, expense("u1", "", "")))
+	assert.NoError(t, s.Create(ctx, expense("u1", "", "")), "records without a bank ID never clash")
+
+	incomes := IncomeStore{DB: s.DB}
+	income := model.Income{Id: uuid.NewString(), User_id: "u1", External_id: "ofx:1:F2", Amount: 1, Currency: "USD", Category: "salary"}
+	require.NoError(t, incomes.Create(ctx, income))
+	income.Id = uuid.NewString()
+	assert.ErrorIs(t, incomes.Create(ctx, income), store.ErrDuplicate)
+}
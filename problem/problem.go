@@ -0,0 +1,183 @@
+// Package problem answers failed requests with RFC 7807 problem details
+// (application/problem+json). Every problem carries a stable code clients
+// can switch on and the request ID to quote in bug reports; the text of
+// unexpected errors is logged but never sent.
+package problem
+
+import (
+	"context"
+	"errors"
+	"expense-tracker/logging"
+	"expense-tracker/validation"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentType is the media type of problem responses.
+const ContentType = "application/problem+json"
+
+// typePrefix turns a code into the problem's type URI.
+const typePrefix = "urn:expense-tracker:problem:"
+
+// Code identifies a kind of problem. Codes are part of the API: once
+// published they keep their meaning.
+type Code string
+
+const (
+	CodeBadRequest       Code = "bad_request"
+	CodeValidationFailed Code = "validation_failed"
+	CodeUnauthorized     Code = "unauthorized"
+	CodeForbidden        Code = "forbidden"
+	CodeNotFound         Code = "not_found"
+	CodeConflict         Code = "conflict"
+	CodeTooLarge         Code = "payload_too_large"
+	CodeRateLimited      Code = "rate_limited"
+	CodeInternal         Code = "internal_error"
+	CodeUnavailable      Code = "service_unavailable"
+
+	CodeInvalidCredentials  Code = "invalid_credentials"
+	CodeInvalidPassword     Code = "invalid_password"
+	CodeWeakPassword        Code = "weak_password"
+	CodeInvalidCode         Code = "invalid_code"
+	CodeInvalidResetToken   Code = "invalid_reset_token"
+	CodeInvalidToken        Code = "invalid_token"
+	CodeLockedOut           Code = "locked_out"
+	CodeTwoFactorEnabled    Code = "two_factor_enabled"
+	CodeTwoFactorNotEnabled Code = "two_factor_not_enabled"
+	CodeAlreadyMember       Code = "already_member"
+	CodeInsufficientScope   Code = "insufficient_scope"
+	CodeSessionRequired     Code = "session_required"
+	CodeAdminRequired       Code = "admin_required"
+	CodeSSODisabled         Code = "sso_disabled"
+	CodeSSOFailed           Code = "sso_failed"
+	CodeIdentityLinked      Code = "identity_linked"
+	CodeNoAccount           Code = "no_account"
+	CodeAccountExists       Code = "account_exists"
+)
+
+// Error is an error that knows how to answer itself.
+type Error struct {
+	Status int
+	Code   Code
+	// Detail is sent to the client, so it must not contain internals.
+	Detail string
+	// Fields lists each failing field of a validation problem.
+	Fields validation.Errors
+	// RetryAfter sets the Retry-After header when positive.
+	RetryAfter time.Duration
+	// Err is the cause. It is logged, never sent.
+	Err error
+}
+
+// New returns a problem with the given status, code and client-facing detail.
+func New(status int, code Code, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
+}
+
+// BadRequest reports a request that could not be parsed.
+func BadRequest(detail string) *Error {
+	return New(http.StatusBadRequest, CodeBadRequest, detail)
+}
+
+// NotFound reports a missing resource.
+func NotFound(detail string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, detail)
+}
+
+func (e *Error) Error() string {
+	msg := string(e.Code) + ": " + e.Detail
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Problem is the response body.
+type Problem struct {
+	Type      string            `json:"type" example:"urn:expense-tracker:problem:not_found"`
+	Title     string            `json:"title" example:"Not Found"`
+	Status    int               `json:"status" example:"404"`
+	Detail    string            `json:"detail,omitempty" example:"Expense not found"`
+	Instance  string            `json:"instance,omitempty" example:"/api/v1/expenses/42"`
+	Code      Code              `json:"code" example:"not_found"`
+	RequestID string            `json:"request_id,omitempty"`
+	Errors    validation.Errors `json:"errors,omitempty"`
+}
+
+// From turns any error into a problem. Validation errors become 422 and
+// canceled requests 499 or 504; anything else not already a problem is an
+// internal error.
+func From(err error) *Error {
+	var p *Error
+	if errors.As(err, &p) {
+		return p
+	}
+	var fields validation.Errors
+	switch {
+	case errors.As(err, &fields):
+		return &Error{Status: http.StatusUnprocessableEntity, Code: CodeValidationFailed,
+			Detail: "The request has invalid fields", Fields: fields, Err: err}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Error{Status: http.StatusGatewayTimeout, Code: CodeUnavailable,
+			Detail: "The request took too long", Err: err}
+	case errors.Is(err, context.Canceled):
+		// nginx's "client closed request"; nobody is listening anyway.
+		return &Error{Status: 499, Code: CodeBadRequest, Detail: "The request was canceled", Err: err}
+	}
+	return &Error{Status: http.StatusInternalServerError, Code: CodeInternal,
+		Detail: "An unexpected error occurred", Err: err}
+}
+
+// Abort answers err as a problem and stops the handler chain. err is
+// recorded on the context, so the access log line shows the cause.
+func Abort(c *gin.Context, err error) {
+	p := From(err)
+	if p.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int((p.RetryAfter+time.Second-1)/time.Second)))
+	}
+	_ = c.Error(err)
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(p.Status, Problem{
+		Type:      typePrefix + string(p.Code),
+		Title:     title(p.Status),
+		Status:    p.Status,
+		Detail:    p.Detail,
+		Instance:  c.Request.URL.Path,
+		Code:      p.Code,
+		RequestID: logging.RequestID(c),
+		Errors:    p.Fields,
+	})
+}
+
+func title(status int) string {
+	if status == 499 {
+		return "Client Closed Request"
+	}
+	if t := http.StatusText(status); t != "" {
+		return t
+	}
+	return fmt.Sprintf("HTTP %d", status)
+}
+
+// Recovery answers panics with an internal error problem.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, recovered any) {
+		Abort(c, fmt.Errorf("panic: %v", recovered))
+	})
+}
+
+// NoRoute answers requests for unknown paths.
+func NoRoute(c *gin.Context) {
+	Abort(c, NotFound("No such endpoint"))
+}
+
+// NoMethod answers requests with a method the path does not support.
+func NoMethod(c *gin.Context) {
+	Abort(c, New(http.StatusMethodNotAllowed, CodeBadRequest, "Method not allowed for this endpoint"))
+}
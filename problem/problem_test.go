@@ -0,0 +1,78 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"expense-tracker/logging"
+	"expense-tracker/validation"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serve(t *testing.T, handler gin.HandlerFunc) (*httptest.ResponseRecorder, Problem) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(logging.Middleware(), Recovery())
+	r.GET("/things/:id", handler)
+	r.NoRoute(NoRoute)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/things/42", nil)
+	req.Header.Set(logging.RequestIDHeader, "req-1")
+	r.ServeHTTP(w, req)
+	var p Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p), w.Body.String())
+	return w, p
+}
+
+func TestAbort_WritesProblem(t *testing.T) {
+	w, p := serve(t, func(c *gin.Context) {
+		Abort(c, &Error{Status: http.StatusTooManyRequests, Code: CodeLockedOut, Detail: "Slow down", RetryAfter: 1500 * time.Millisecond})
+	})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "2", w.Header().Get("Retry-After"), "rounded up")
+	assert.Equal(t, Problem{
+		Type:      "urn:expense-tracker:problem:locked_out",
+		Title:     "Too Many Requests",
+		Status:    http.StatusTooManyRequests,
+		Detail:    "Slow down",
+		Instance:  "/things/42",
+		Code:      CodeLockedOut,
+		RequestID: "req-1",
+	}, p)
+}
+
+func TestAbort_HidesUnexpectedErrors(t *testing.T) {
+	w, p := serve(t, func(c *gin.Context) {
+		Abort(c, errors.New(`pq: relation "expenses" does not exist`))
+	})
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, CodeInternal, p.Code)
+	assert.NotContains(t, w.Body.String(), "relation")
+
+	w, p = serve(t, func(c *gin.Context) { panic("boom") })
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, CodeInternal, p.Code)
+	assert.NotContains(t, w.Body.String(), "boom")
+}
+
+func TestAbort_ValidationErrors(t *testing.T) {
+	fields := validation.Errors{{Field: "amount", Reason: "must be greater than 0"}}
+	w, p := serve(t, func(c *gin.Context) { Abort(c, fields) })
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, CodeValidationFailed, p.Code)
+	assert.Equal(t, fields, p.Errors)
+}
+
+func TestFrom_KeepsWrappedProblems(t *testing.T) {
+	p := From(errors.Join(errors.New("context"), NotFound("Expense not found")))
+	assert.Equal(t, http.StatusNotFound, p.Status)
+	assert.Equal(t, "Expense not found", p.Detail)
+}
@@ -0,0 +1,124 @@
+// Package routes registers the REST and GraphQL API routes so the server and
+// tests build the same route table.
+package routes
+
+import (
+	"expense-tracker/auth"
+	"expense-tracker/config"
+	"expense-tracker/controller"
+	"expense-tracker/graphqlapi"
+	"expense-tracker/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Register adds the /api/v1, /graphql and JWKS routes to s, rate limited by limiter.
+func Register(s *gin.Engine, limiter *auth.RateLimiter, gql config.GraphQL) {
+	s.HandleMethodNotAllowed = true
+	s.NoRoute(problem.NoRoute)
+	s.NoMethod(problem.NoMethod)
+	s.GET("/.well-known/jwks.json", controller.JWKS)
+
+	// Public routes, limited per client IP
+	public := s.Group("/api/v1")
+	public.Use(limiter.RateLimitMiddleware("auth"))
+	public.POST("/login", controller.Login)
+	public.POST("/login/2fa", controller.LoginTwoFactor)
+	public.POST("/signup", controller.SignUp)
+	public.POST("/auth/password/forgot", controller.ForgotPassword)
+	public.POST("/auth/password/reset", controller.ResetPassword)
+	public.GET("/auth/oidc/login", controller.SSOLogin)
+	public.GET("/auth/oidc/callback", controller.SSOCallback)
+
+	account := s.Group("/api/v1/auth")
+	account.Use(auth.JWTAuthMiddleware(), auth.RequireSession(), limiter.RateLimitMiddleware("auth"))
+	account.POST("/password", controller.ChangePassword)
+	account.GET("/2fa", controller.GetTwoFactor)
+	account.POST("/2fa/enroll", controller.EnrollTwoFactor)
+	account.POST("/2fa/confirm", controller.ConfirmTwoFactor)
+	account.POST("/2fa/disable", controller.DisableTwoFactor)
+	account.POST("/oidc/link", controller.LinkSSO)
+	account.GET("/tokens", controller.ListAccessTokens)
+	account.POST("/tokens", controller.CreateAccessToken)
+	account.DELETE("/tokens/:id", controller.RevokeAccessToken)
+
+	admin := s.Group("/api/v1/admin")
+	admin.Use(auth.JWTAuthMiddleware(), auth.RequireSession(), limiter.RateLimitMiddleware("expenses"))
+	admin.DELETE("/users/:id/2fa", controller.ResetUserTwoFactor)
+	admin.GET("/login-failures", controller.ListLoginFailures)
+	admin.DELETE("/lockouts/:user_name", controller.UnlockLogin)
+
+	// Protected routes with JWT (or a scoped access token) and Rate Limiting
+	r := s.Group("/api/v1/expenses")
+	r.Use(auth.JWTAuthMiddleware(), auth.RequireScope(auth.ScopeExpensesRead, auth.ScopeExpensesWrite),
+		limiter.RateLimitMiddleware("expenses"))
+	r.POST("/", controller.CreateExpense)
+	r.GET("/:id", controller.GetExpenseById)
+	r.PUT("/:id", controller.UpdateExpense)
+	r.DELETE("/:id", controller.DeleteExpense)
+	r.GET("/", controller.ListExpensesWithFilters)
+	r.GET("/summary", controller.Summary)
+	r.GET("/suggest-category", controller.SuggestCategory)
+
+	i := s.Group("/api/v1/incomes")
+	i.Use(auth.JWTAuthMiddleware(), auth.RequireScope(auth.ScopeExpensesRead, auth.ScopeExpensesWrite),
+		limiter.RateLimitMiddleware("expenses"))
+	i.POST("/", controller.CreateIncome)
+	i.GET("/:id", controller.GetIncome)
+	i.PUT("/:id", controller.UpdateIncome)
+	i.DELETE("/:id", controller.DeleteIncome)
+	i.GET("/", controller.ListIncome)
+
+	a := s.Group("/api/v1/accounts")
+	a.Use(auth.JWTAuthMiddleware(), auth.RequireScope(auth.ScopeExpensesRead, auth.ScopeExpensesWrite),
+		limiter.RateLimitMiddleware("expenses"))
+	a.POST("/", controller.CreateAccount)
+	a.GET("/", controller.ListAccounts)
+	a.GET("/:id", controller.GetAccount)
+	a.PUT("/:id", controller.UpdateAccount)
+	a.GET("/:id/history", controller.GetAccountHistory)
+
+	t := s.Group("/api/v1/transfers")
+	t.Use(auth.JWTAuthMiddleware(), auth.RequireScope(auth.ScopeExpensesRead, auth.ScopeExpensesWrite),
+		limiter.RateLimitMiddleware("expenses"))
+	t.POST("/", controller.CreateTransfer)
+	t.GET("/", controller.ListTransfers)
+	t.DELETE("/:id", controller.DeleteTransfer)
+
+	imports := s.Group("/api/v1/imports")
+	imports.Use(auth.JWTAuthMiddleware(), auth.RequireScope(auth.ScopeExpensesRead, auth.ScopeExpensesWrite),
+		limiter.RateLimitMiddleware("expenses"))
+	imports.POST("/", controller.ImportStatement)
+
+	rules := s.Group("/api/v1/rules")
+	rules.Use(auth.JWTAuthMiddleware(), auth.RequireScope(auth.ScopeExpensesRead, auth.ScopeExpensesWrite),
+		limiter.RateLimitMiddleware("expenses"))
+	rules.POST("/", controller.CreateRule)
+	rules.GET("/", controller.ListRules)
+	rules.POST("/test", controller.TryRules)
+	rules.POST("/apply", controller.ApplyRules)
+	rules.GET("/:id", controller.GetRule)
+	rules.PUT("/:id", controller.UpdateRule)
+	rules.DELETE("/:id", controller.DeleteRule)
+
+	reports := s.Group("/api/v1/reports")
+	reports.Use(auth.JWTAuthMiddleware(), auth.RequireScope(auth.ScopeExpensesRead, auth.ScopeExpensesRead),
+		limiter.RateLimitMiddleware("expenses"))
+	reports.GET("/cashflow", controller.CashFlow)
+
+	h := s.Group("/api/v1/households")
+	h.Use(auth.JWTAuthMiddleware(), auth.RequireScope(auth.ScopeHouseholdsRead, auth.ScopeHouseholdsWrite),
+		limiter.RateLimitMiddleware("expenses"))
+	h.POST("/", controller.CreateHousehold)
+	h.GET("/", controller.ListHouseholds)
+	h.POST("/join", controller.JoinHousehold)
+	h.GET("/:id", controller.GetHousehold)
+	h.POST("/:id/invites", controller.CreateHouseholdInvite)
+	h.PUT("/:id/members/:user_id", controller.UpdateHouseholdMember)
+	h.DELETE("/:id/members/:user_id", controller.RemoveHouseholdMember)
+
+	// GraphQL is read only, so it needs expenses:read whatever the method.
+	s.POST("/graphql", auth.JWTAuthMiddleware(), auth.RequireScope(auth.ScopeExpensesRead, auth.ScopeExpensesRead),
+		limiter.RateLimitMiddleware("expenses"),
+		graphqlapi.Handler(gql.MaxDepth, gql.MaxComplexity))
+}
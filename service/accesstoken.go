@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"expense-tracker/auth"
+	"expense-tracker/model"
+	"expense-tracker/postgresql"
+	"expense-tracker/store"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// AccessTokens is the access token store; tests swap in the in-memory one.
+var AccessTokens store.AccessTokenStore = postgresql.AccessTokenStore{}
+
+// lastUsedResolution limits how often a busy token's LastUsedAt is written.
+const lastUsedResolution = time.Minute
+
+// accessTokenPrefixLen is how much of a token is kept to identify it.
+const accessTokenPrefixLen = len(auth.AccessTokenPrefix) + 4
+
+// CreateAccessToken issues a personal access token for userID and returns it
+// along with the token itself, which is not stored and cannot be shown again.
+// A nil expiresAt creates a token that is valid until revoked.
+func CreateAccessToken(ctx context.Context, userID, name string, scopes []string, expiresAt *time.Time) (model.AccessToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return model.AccessToken{}, "", fmt.Errorf("%w: name is required", ErrInvalidArgument)
+	}
+	if len(scopes) == 0 {
+		return model.AccessToken{}, "", fmt.Errorf("%w: at least one scope is required", ErrInvalidArgument)
+	}
+	for _, scope := range scopes {
+		if !auth.ValidScope(scope) {
+			return model.AccessToken{}, "", fmt.Errorf("%w: unknown scope %q", ErrInvalidArgument, scope)
+		}
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return model.AccessToken{}, "", fmt.Errorf("%w: expiry must be in the future", ErrInvalidArgument)
+	}
+	secret, err := auth.NewAccessToken()
+	if err != nil {
+		return model.AccessToken{}, "", err
+	}
+	scopes = slices.Clone(scopes)
+	slices.Sort(scopes)
+	token := model.AccessToken{
+		Id:        uuid.New().String(),
+		UserId:    userID,
+		Name:      name,
+		TokenHash: hashToken(secret),
+		Prefix:    secret[:accessTokenPrefixLen],
+		Scopes:    slices.Compact(scopes),
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := AccessTokens.Create(ctx, token); err != nil {
+		return model.AccessToken{}, "", err
+	}
+	return token, secret, nil
+}
+
+// ListAccessTokens returns userID's access tokens, oldest first.
+func ListAccessTokens(ctx context.Context, userID string) ([]model.AccessToken, error) {
+	return AccessTokens.List(ctx, userID)
+}
+
+// RevokeAccessToken deletes one of userID's tokens. It returns ErrNotFound
+// if the user has no token with that ID.
+func RevokeAccessToken(ctx context.Context, userID, id string) error {
+	return AccessTokens.Delete(ctx, userID, id)
+}
+
+// ValidateAccessToken is the auth.AccessTokenValidator: it looks the token
+// up by hash, rejects expired tokens and records when it was last used.
+func ValidateAccessToken(ctx context.Context, secret string) (auth.Identity, error) {
+	token, err := AccessTokens.ByHash(ctx, hashToken(secret))
+	if errors.Is(err, store.ErrNotFound) {
+		return auth.Identity{}, fmt.Errorf("%w: unknown access token", auth.ErrInvalidToken)
+	}
+	if err != nil {
+		return auth.Identity{}, err
+	}
+	now := time.Now()
+	if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+		return auth.Identity{}, fmt.Errorf("%w: access token expired", auth.ErrInvalidToken)
+	}
+	user, err := Users.ByID(ctx, token.UserId)
+	if errors.Is(err, store.ErrNotFound) {
+		return auth.Identity{}, fmt.Errorf("%w: access token owner no longer exists", auth.ErrInvalidToken)
+	}
+	if err != nil {
+		return auth.Identity{}, err
+	}
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= lastUsedResolution {
+		// Failing to record usage should not fail the request.
+		if err := AccessTokens.Touch(ctx, token.Id, now.UTC()); err != nil {
+			log.WithField("token_id", token.Id).Warnf("Failed to record access token use: %v", err)
+		}
+	}
+	tier := user.Tier
+	if tier == "" {
+		tier = auth.DefaultTier
+	}
+	return auth.Identity{UserID: user.UserId, Tier: tier, Scopes: token.Scopes}, nil
+}
@@ -0,0 +1,335 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"expense-tracker/model"
+	"expense-tracker/postgresql"
+	"expense-tracker/store"
+	"expense-tracker/validation"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Accounts and Transfers are the stores accounts and the transfers between
+// them are kept in.
+var (
+	Accounts  store.AccountStore  = postgresql.AccountStore{}
+	Transfers store.TransferStore = postgresql.TransferStore{}
+)
+
+// Account kinds.
+const (
+	AccountChecking   = "checking"
+	AccountSavings    = "savings"
+	AccountCreditCard = "credit_card"
+	AccountCash       = "cash"
+	AccountOther      = "other"
+)
+
+// Ledger entry types.
+const (
+	EntryIncome      = "income"
+	EntryExpense     = "expense"
+	EntryTransferIn  = "transfer_in"
+	EntryTransferOut = "transfer_out"
+)
+
+// AccountInput is the caller-supplied part of an account. A credit card
+// usually opens with a zero or negative balance.
+type AccountInput struct {
+	Name           string  `json:"name" validate:"required,max=64" example:"Checking"`
+	Kind           string  `json:"kind" validate:"oneof=checking savings credit_card cash other" example:"checking"`
+	Currency       string  `json:"currency" validate:"iso4217" example:"USD"`
+	OpeningBalance float64 `json:"opening_balance" validate:"gte=-1000000000,lte=1000000000,precision=Currency" example:"1200"`
+	// HouseholdID is only read on creation: it shares the account in a
+	// household ledger.
+	HouseholdID string `json:"household_id,omitempty"`
+}
+
+// Validate normalizes the input and checks it, returning validation.Errors
+// listing each failing field.
+func (in *AccountInput) Validate() error {
+	in.Name = strings.TrimSpace(in.Name)
+	in.Kind = strings.ToLower(strings.TrimSpace(in.Kind))
+	if in.Kind == "" {
+		in.Kind = AccountChecking
+	}
+	in.Currency = strings.ToUpper(strings.TrimSpace(in.Currency))
+	if in.Currency == "" {
+		in.Currency = DefaultCurrency
+	}
+	return validation.Struct(in)
+}
+
+// AccountBalance is an account with its balance at some point in time.
+type AccountBalance struct {
+	Account model.Account `json:"account"`
+	Balance float64       `json:"balance" example:"1034.5"`
+	At      time.Time     `json:"at"`
+}
+
+// LedgerEntry is one movement on an account. Amount is signed: negative for
+// money leaving the account. Balance is the running balance after it.
+type LedgerEntry struct {
+	Type        string    `json:"type" example:"expense"`
+	Id          string    `json:"id"`
+	TimeStamp   time.Time `json:"timestamp"`
+	Category    string    `json:"category,omitempty" example:"food"`
+	Description string    `json:"description" example:"lunch"`
+	Amount      float64   `json:"amount" example:"-12.5"`
+	Balance     float64   `json:"balance" example:"1034.5"`
+}
+
+// AccountHistory is the running balance of an account over a period.
+// OpeningBalance is the balance before the first entry of the period.
+type AccountHistory struct {
+	Account        model.Account `json:"account"`
+	OpeningBalance float64       `json:"opening_balance" example:"1200"`
+	ClosingBalance float64       `json:"closing_balance" example:"1034.5"`
+	Entries        []LedgerEntry `json:"entries"`
+}
+
+// accountFor loads an account userID may use with at least minRole.
+// Household accounts follow household roles; another user's personal
+// account, like another household's, is reported as not found.
+func accountFor(ctx context.Context, userID, id, minRole string) (model.Account, error) {
+	if id == "" {
+		return model.Account{}, ErrInvalidArgument
+	}
+	account, err := Accounts.Get(ctx, id)
+	if err != nil {
+		return model.Account{}, err
+	}
+	if account.HouseholdId != "" {
+		if err := authorizeRecord(ctx, account.HouseholdId, userID, minRole); err != nil {
+			return model.Account{}, err
+		}
+	} else if account.UserId != userID {
+		return model.Account{}, ErrNotFound
+	}
+	return account, nil
+}
+
+// accountCurrency fills in the currency of a record linked to an account
+// when the caller left it out, so the account's currency is the default.
+func accountCurrency(ctx context.Context, accountID string, currency *string) {
+	if accountID == "" || strings.TrimSpace(*currency) != "" {
+		return
+	}
+	if account, err := Accounts.Get(ctx, accountID); err == nil {
+		*currency = account.Currency
+	}
+}
+
+// checkAccount verifies that userID may link a record in householdID and
+// currency to accountID, reporting unusable accounts as field errors.
+func checkAccount(ctx context.Context, userID, accountID, householdID, currency string) error {
+	if accountID == "" {
+		return nil
+	}
+	account, err := accountFor(ctx, userID, accountID, RoleEditor)
+	if errors.Is(err, ErrNotFound) {
+		return validation.Errors{{Field: "account_id", Reason: "does not exist"}}
+	}
+	if err != nil {
+		return err
+	}
+	if account.HouseholdId != householdID {
+		return validation.Errors{{Field: "account_id", Reason: "must be in the same ledger as the record"}}
+	}
+	if account.Currency != currency {
+		return validation.Errors{{Field: "currency", Reason: "must be the account currency " + account.Currency}}
+	}
+	return nil
+}
+
+// CreateAccount validates in and stores it as a new account owned by
+// userID. Household accounts need an editor role.
+func CreateAccount(ctx context.Context, userID string, in AccountInput) (model.Account, error) {
+	if err := in.Validate(); err != nil {
+		return model.Account{}, err
+	}
+	if in.HouseholdID != "" {
+		if _, err := authorize(ctx, in.HouseholdID, userID, RoleEditor); err != nil {
+			return model.Account{}, err
+		}
+	}
+	account := model.Account{
+		Id:             uuid.New().String(),
+		UserId:         userID,
+		HouseholdId:    in.HouseholdID,
+		Name:           in.Name,
+		Kind:           in.Kind,
+		Currency:       in.Currency,
+		OpeningBalance: in.OpeningBalance,
+		CreatedAt:      time.Now().UTC(),
+	}
+	if err := Accounts.Create(ctx, account); err != nil {
+		return model.Account{}, err
+	}
+	return account, nil
+}
+
+// UpdateAccount renames an account or changes its kind or opening balance.
+// The currency cannot change once records may be linked to the account.
+func UpdateAccount(ctx context.Context, userID, id string, in AccountInput) (model.Account, error) {
+	account, err := accountFor(ctx, userID, id, RoleEditor)
+	if err != nil {
+		return model.Account{}, err
+	}
+	if strings.TrimSpace(in.Currency) == "" {
+		in.Currency = account.Currency
+	}
+	if err := in.Validate(); err != nil {
+		return model.Account{}, err
+	}
+	if in.Currency != account.Currency {
+		return model.Account{}, validation.Errors{{Field: "currency", Reason: "cannot be changed"}}
+	}
+	account.Name = in.Name
+	account.Kind = in.Kind
+	account.OpeningBalance = in.OpeningBalance
+	if err := Accounts.Update(ctx, account); err != nil {
+		return model.Account{}, err
+	}
+	return account, nil
+}
+
+// ListAccounts returns the household's accounts, or userID's personal ones
+// when householdID is empty, with their current balances.
+func ListAccounts(ctx context.Context, userID, householdID string) ([]AccountBalance, error) {
+	if householdID != "" {
+		if _, err := authorize(ctx, householdID, userID, RoleViewer); err != nil {
+			return nil, err
+		}
+	}
+	accounts, err := Accounts.List(ctx, userID, householdID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	balances := make([]AccountBalance, 0, len(accounts))
+	for _, account := range accounts {
+		entries, err := ledger(ctx, account)
+		if err != nil {
+			return nil, err
+		}
+		balances = append(balances, AccountBalance{Account: account, Balance: balanceAt(account, entries, now), At: now})
+	}
+	return balances, nil
+}
+
+// AccountBalanceAt returns the balance of an account at a point in time;
+// a zero at means now.
+func AccountBalanceAt(ctx context.Context, userID, id string, at time.Time) (AccountBalance, error) {
+	account, err := accountFor(ctx, userID, id, RoleViewer)
+	if err != nil {
+		return AccountBalance{}, err
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+	at = at.UTC()
+	entries, err := ledger(ctx, account)
+	if err != nil {
+		return AccountBalance{}, err
+	}
+	return AccountBalance{Account: account, Balance: balanceAt(account, entries, at), At: at}, nil
+}
+
+// GetAccountHistory lists the movements on an account within the bounds of
+// f (only From and To are read), each with the running balance after it.
+func GetAccountHistory(ctx context.Context, userID, id string, f ExpenseFilter) (AccountHistory, error) {
+	from, to, err := f.Bounds()
+	if err != nil {
+		return AccountHistory{}, err
+	}
+	account, err := accountFor(ctx, userID, id, RoleViewer)
+	if err != nil {
+		return AccountHistory{}, err
+	}
+	entries, err := ledger(ctx, account)
+	if err != nil {
+		return AccountHistory{}, err
+	}
+	history := AccountHistory{Account: account, OpeningBalance: account.OpeningBalance, Entries: []LedgerEntry{}}
+	for _, e := range entries {
+		switch {
+		case !from.IsZero() && e.TimeStamp.Before(from):
+			history.OpeningBalance = e.Balance
+			continue
+		case !to.IsZero() && e.TimeStamp.After(to):
+			continue
+		}
+		history.Entries = append(history.Entries, e)
+	}
+	history.ClosingBalance = history.OpeningBalance
+	if n := len(history.Entries); n > 0 {
+		history.ClosingBalance = history.Entries[n-1].Balance
+	}
+	return history, nil
+}
+
+// ledger returns every movement on an account, oldest first, with running
+// balances starting from the opening balance.
+func ledger(ctx context.Context, account model.Account) ([]LedgerEntry, error) {
+	f := ExpenseFilter{HouseholdID: account.HouseholdId, AccountID: account.Id}
+	var entries []LedgerEntry
+	err := Incomes.Stream(ctx, f, cashFlowBatchSize, func(i model.Income) error {
+		entries = append(entries, LedgerEntry{Type: EntryIncome, Id: i.Id, TimeStamp: i.TimeStamp,
+			Category: i.Category, Description: i.Description, Amount: i.Amount})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = Expenses.Stream(ctx, f, cashFlowBatchSize, func(e model.Expense) error {
+		entries = append(entries, LedgerEntry{Type: EntryExpense, Id: e.Id, TimeStamp: e.TimeStamp,
+			Category: e.Category, Description: e.Description, Amount: -e.Amount})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	transfers, err := Transfers.List(ctx, account.Id)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range transfers {
+		entry := LedgerEntry{Type: EntryTransferIn, Id: t.Id, TimeStamp: t.TimeStamp, Description: t.Description, Amount: t.ToAmount}
+		if t.FromAccountId == account.Id {
+			entry.Type, entry.Amount = EntryTransferOut, -t.Amount
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].TimeStamp.Equal(entries[j].TimeStamp) {
+			return entries[i].TimeStamp.Before(entries[j].TimeStamp)
+		}
+		return entries[i].Id < entries[j].Id
+	})
+	units := validation.MinorUnits(account.Currency)
+	balance := account.OpeningBalance
+	for i := range entries {
+		balance += entries[i].Amount
+		entries[i].Balance = roundTo(balance, units)
+	}
+	return entries, nil
+}
+
+// balanceAt is the balance after the last entry no later than at.
+func balanceAt(account model.Account, entries []LedgerEntry, at time.Time) float64 {
+	balance := account.OpeningBalance
+	for _, e := range entries {
+		if e.TimeStamp.After(at) {
+			break
+		}
+		balance = e.Balance
+	}
+	return balance
+}
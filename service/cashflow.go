@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"expense-tracker/model"
+	"expense-tracker/validation"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// cashFlowBatchSize is how many rows a cash-flow report reads at a time.
+const cashFlowBatchSize = 500
+
+// CategoryCashFlow is the money in and out of one category in a month.
+type CategoryCashFlow struct {
+	Category string  `json:"category" example:"salary"`
+	Income   float64 `json:"income" example:"2500"`
+	Expenses float64 `json:"expenses" example:"0"`
+	Net      float64 `json:"net" example:"2500"`
+}
+
+// MonthlyCashFlow is the money in and out in one month and currency.
+type MonthlyCashFlow struct {
+	// Month is YYYY-MM, in UTC.
+	Month      string             `json:"month" example:"2026-10"`
+	Currency   string             `json:"currency" example:"USD"`
+	Income     float64            `json:"income" example:"2500"`
+	Expenses   float64            `json:"expenses" example:"1830.4"`
+	Net        float64            `json:"net" example:"669.6"`
+	Categories []CategoryCashFlow `json:"categories"`
+}
+
+// CashFlowReport lists cash flow month by month, oldest first. Without
+// normalization a month has one entry per currency it saw.
+type CashFlowReport struct {
+	// Currency is set when every amount was converted into it.
+	Currency string            `json:"currency,omitempty" example:"USD"`
+	Months   []MonthlyCashFlow `json:"months"`
+}
+
+// Convert converts amount between two currencies through the base currency
+// of the configured exchange rates. It fails with ErrInvalidArgument for a
+// currency without a rate.
+func Convert(amount float64, from, to string) (float64, error) {
+	if from == to {
+		return amount, nil
+	}
+	fromRate, err := exchangeRate(from)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := exchangeRate(to)
+	if err != nil {
+		return 0, err
+	}
+	return amount * fromRate / toRate, nil
+}
+
+// exchangeRate returns the value of one unit of currency in the base currency.
+func exchangeRate(currency string) (float64, error) {
+	if currency == strings.ToUpper(conf.ExchangeRates.Base) {
+		return 1, nil
+	}
+	if rate, ok := conf.ExchangeRates.Rates[currency]; ok && rate > 0 {
+		return rate, nil
+	}
+	return 0, fmt.Errorf("%w: no exchange rate for %s", ErrInvalidArgument, currency)
+}
+
+type cashFlowKey struct{ month, currency string }
+
+// cashFlow accumulates a report before it is sorted and rounded.
+type cashFlow struct {
+	target string
+	months map[cashFlowKey]map[string]*CategoryCashFlow
+}
+
+// add records a transaction under its month and category, as income or as
+// an expense.
+func (r *cashFlow) add(at time.Time, category, currency string, amount float64, income bool) error {
+	if r.target != "" {
+		converted, err := Convert(amount, currency, r.target)
+		if err != nil {
+			return err
+		}
+		currency, amount = r.target, converted
+	}
+	key := cashFlowKey{at.UTC().Format("2006-01"), currency}
+	categories := r.months[key]
+	if categories == nil {
+		categories = map[string]*CategoryCashFlow{}
+		r.months[key] = categories
+	}
+	c := categories[category]
+	if c == nil {
+		c = &CategoryCashFlow{Category: category}
+		categories[category] = c
+	}
+	if income {
+		c.Income += amount
+	} else {
+		c.Expenses += amount
+	}
+	return nil
+}
+
+func (r *cashFlow) report() CashFlowReport {
+	report := CashFlowReport{Currency: r.target, Months: []MonthlyCashFlow{}}
+	for key, categories := range r.months {
+		units := validation.MinorUnits(key.currency)
+		month := MonthlyCashFlow{Month: key.month, Currency: key.currency}
+		for _, c := range categories {
+			month.Income += c.Income
+			month.Expenses += c.Expenses
+			month.Categories = append(month.Categories, CategoryCashFlow{
+				Category: c.Category,
+				Income:   roundTo(c.Income, units),
+				Expenses: roundTo(c.Expenses, units),
+				Net:      roundTo(c.Income-c.Expenses, units),
+			})
+		}
+		sort.Slice(month.Categories, func(i, j int) bool { return month.Categories[i].Category < month.Categories[j].Category })
+		month.Net = roundTo(month.Income-month.Expenses, units)
+		month.Income, month.Expenses = roundTo(month.Income, units), roundTo(month.Expenses, units)
+		report.Months = append(report.Months, month)
+	}
+	sort.Slice(report.Months, func(i, j int) bool {
+		a, b := report.Months[i], report.Months[j]
+		if a.Month != b.Month {
+			return a.Month < b.Month
+		}
+		return a.Currency < b.Currency
+	})
+	return report
+}
+
+func roundTo(amount float64, decimals int) float64 {
+	scale := math.Pow10(decimals)
+	return math.Round(amount*scale) / scale
+}
+
+// CashFlow reports income, expenses and net per month and category for the
+// user, household and date range of the filter; its category, currency,
+// limit and offset are ignored. When currency is set every amount is
+// converted into it with the configured exchange rates, otherwise each
+// currency is reported on its own.
+func CashFlow(ctx context.Context, f ExpenseFilter, currency string) (CashFlowReport, error) {
+	if err := scopeFilter(ctx, &f); err != nil {
+		return CashFlowReport{}, err
+	}
+	f.Category, f.Currency, f.Limit, f.Offset = "", "", 0, 0
+	r := &cashFlow{target: strings.ToUpper(strings.TrimSpace(currency)), months: map[cashFlowKey]map[string]*CategoryCashFlow{}}
+	if r.target != "" {
+		if _, err := exchangeRate(r.target); err != nil {
+			return CashFlowReport{}, err
+		}
+	}
+	err := Incomes.Stream(ctx, f, cashFlowBatchSize, func(i model.Income) error {
+		return r.add(i.TimeStamp, i.Category, i.Currency, i.Amount, true)
+	})
+	if err != nil {
+		return CashFlowReport{}, err
+	}
+	err = Expenses.Stream(ctx, f, cashFlowBatchSize, func(e model.Expense) error {
+		return r.add(e.TimeStamp, e.Category, e.Currency, e.Amount, false)
+	})
+	if err != nil {
+		return CashFlowReport{}, err
+	}
+	return r.report(), nil
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"expense-tracker/config"
+	"expense-tracker/notify"
+	"time"
+)
+
+// Config holds the policies and collaborators the service layer takes from
+// the application's configuration. main builds it once with NewConfig and
+// hands it to Configure; nothing else in the service layer is settable.
+//
+// Unlike the other subsystems, which take their section of config.Config as
+// an argument, the service layer keeps its Config in the package, next to
+// its stores: the service functions are called from REST, gRPC and GraphQL
+// handlers alike, and none of them carries a service value to hang the
+// settings on. Threading a Config through every call is left out on purpose;
+// Configure is the one place they are installed, and after startup only
+// tests call it.
+type Config struct {
+	// PasswordResetTTL is how long a reset token stays valid.
+	PasswordResetTTL time.Duration
+	// AdminUsers are the user names allowed to use admin operations.
+	AdminUsers []string
+	// Lockout is the brute-force policy for logins.
+	Lockout config.Login
+	// ExchangeRates convert amounts when a cash-flow report is normalized
+	// to one currency or a transfer crosses currencies. Without rates only
+	// the base currency is known.
+	ExchangeRates config.Currency
+	// Notifier delivers password reset tokens.
+	Notifier notify.Notifier
+}
+
+// NewConfig takes the service settings from the loaded configuration;
+// notifier is the one built from its password section.
+func NewConfig(cfg config.Config, notifier notify.Notifier) Config {
+	return Config{
+		PasswordResetTTL: cfg.Password.ResetTTL.Duration,
+		AdminUsers:       cfg.Admin.Users,
+		Lockout:          cfg.Login,
+		ExchangeRates:    cfg.Currency,
+		Notifier:         notifier,
+	}
+}
+
+// DefaultConfig is the configuration used until Configure is called: the
+// config package's defaults, with reset tokens written to the log.
+func DefaultConfig() Config {
+	return NewConfig(config.Default(), notify.Log{})
+}
+
+var conf = DefaultConfig()
+
+// Configure replaces the service configuration. It must be called before
+// requests are served; tests call it again to change a setting, restoring
+// the one from CurrentConfig afterwards.
+func Configure(c Config) {
+	conf = c
+}
+
+// CurrentConfig returns the configuration in use.
+func CurrentConfig() Config {
+	return conf
+}
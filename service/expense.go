@@ -0,0 +1,300 @@
+// Package service holds the business logic shared by the REST, gRPC and GraphQL APIs.
+package service
+
+import (
+	"context"
+	"errors"
+	"expense-tracker/auth"
+	"expense-tracker/metrics"
+	"expense-tracker/model"
+	"expense-tracker/postgresql"
+	"expense-tracker/store"
+	"expense-tracker/validation"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNotFound is returned when the requested record does not exist.
+	ErrNotFound = store.ErrNotFound
+	// ErrInvalidArgument is returned when a request is missing required input.
+	ErrInvalidArgument = store.ErrInvalidArgument
+)
+
+// Expenses and Users are the stores the service reads and writes. They
+// default to the SQL database; tests swap in the in-memory stores.
+var (
+	Expenses store.ExpenseStore = postgresql.ExpenseStore{}
+	Users    store.UserStore    = postgresql.UserStore{}
+)
+
+// DefaultPageSize is used when a list request does not set a limit.
+const DefaultPageSize = store.DefaultPageSize
+
+// ExpenseFilter narrows list and summary queries. Empty fields are ignored,
+// except HouseholdID: queries without one only see personal expenses.
+type ExpenseFilter = store.ExpenseFilter
+
+// DefaultCurrency is used when an expense does not name one.
+const DefaultCurrency = "USD"
+
+// ExpenseInput is the caller-supplied part of an expense, as accepted by the
+// REST and gRPC APIs. Validate reports every field that breaks the rules in
+// its tags.
+type ExpenseInput struct {
+	Amount      float64 `json:"amount" validate:"gt=0,lte=1000000000,precision=Currency" example:"12.5"`
+	Currency    string  `json:"currency" validate:"iso4217" example:"USD"`
+	Category    string  `json:"category" validate:"required,max=64" example:"food"`
+	Description string  `json:"description" validate:"max=256" example:"lunch"`
+	// TimeStamp defaults to now.
+	TimeStamp time.Time `json:"timestamp" validate:"notfarfuture"`
+	// HouseholdID and UserID are only read on creation: they put the
+	// expense in a household ledger, attributed to a member.
+	HouseholdID string `json:"household_id,omitempty"`
+	UserID      string `json:"user_id,omitempty"`
+	// AccountID is the account the money was paid from, in the same ledger
+	// and currency; its currency is the default.
+	AccountID string `json:"account_id,omitempty"`
+	// Tags label the expense; rules may add more.
+	Tags []string `json:"tags,omitempty" validate:"max=20,dive,max=32" example:"work,travel"`
+	// bank and defaultCategory are only set by statement imports;
+	// defaultCategory is used when neither the caller, a rule nor a
+	// suggestion gives a category.
+	bank            bankDetails
+	defaultCategory string
+	// rules, when set, are the creator's rules loaded once for a whole
+	// import instead of for every expense.
+	rules *ruleSet
+}
+
+// Validate normalizes the input and checks it, returning validation.Errors
+// listing each failing field.
+func (in *ExpenseInput) Validate() error {
+	in.Currency = strings.ToUpper(strings.TrimSpace(in.Currency))
+	if in.Currency == "" {
+		in.Currency = DefaultCurrency
+	}
+	in.Category = strings.TrimSpace(in.Category)
+	in.Tags = normalizeTags(in.Tags)
+	if in.TimeStamp.IsZero() {
+		in.TimeStamp = time.Now()
+	}
+	in.TimeStamp = in.TimeStamp.UTC()
+	return validation.Struct(in)
+}
+
+func (in ExpenseInput) expense() model.Expense {
+	return model.Expense{
+		User_id:      in.UserID,
+		Household_id: in.HouseholdID,
+		Account_id:   in.AccountID,
+		External_id:  in.bank.id,
+		Payee:        in.bank.payee,
+		Memo:         in.bank.memo,
+		Amount:       in.Amount,
+		Currency:     in.Currency,
+		Category:     in.Category,
+		Description:  in.Description,
+		TimeStamp:    in.TimeStamp,
+		Tags:         in.Tags,
+	}
+}
+
+// CreateExpense runs userID's rules on in, validates it and stores it as a
+// new expense owned by userID. Rules only set the category when the caller
+// gave none. Without a category from the caller or a rule,
+// the category suggested from userID's history is used if it is confident
+// enough. Household expenses need an editor role and may be attributed to
+// another member by setting UserID; personal expenses always belong to
+// userID.
+func CreateExpense(ctx context.Context, userID string, in ExpenseInput) (model.Expense, error) {
+	accountCurrency(ctx, in.AccountID, &in.Currency)
+	// A rule or a suggestion may supply the category, so its absence alone
+	// does not stop them from running.
+	if err := in.Validate(); err != nil && !onlyMissing(err, "category") {
+		return model.Expense{}, err
+	}
+	if err := applyRules(ctx, userID, &in); err != nil {
+		return model.Expense{}, err
+	}
+	if in.Category == "" {
+		if err := suggestCategory(ctx, userID, &in); err != nil {
+			return model.Expense{}, err
+		}
+	}
+	if in.Category == "" {
+		in.Category = in.defaultCategory
+	}
+	if err := in.Validate(); err != nil {
+		return model.Expense{}, err
+	}
+	expense := in.expense()
+	if expense.Household_id != "" {
+		if _, err := authorize(ctx, expense.Household_id, userID, RoleEditor); err != nil {
+			return model.Expense{}, err
+		}
+		if expense.User_id != "" && expense.User_id != userID {
+			if _, err := authorize(ctx, expense.Household_id, expense.User_id, RoleViewer); err != nil {
+				return model.Expense{}, ErrInvalidArgument
+			}
+			userID = expense.User_id
+		}
+	}
+	if err := checkAccount(ctx, userID, expense.Account_id, expense.Household_id, expense.Currency); err != nil {
+		return model.Expense{}, err
+	}
+	expense.Id = uuid.New().String()
+	expense.User_id = userID
+	if err := Expenses.Create(ctx, expense); err != nil {
+		return model.Expense{}, err
+	}
+	metrics.ExpensesCreated.Inc()
+	learn(expense)
+	return expense, nil
+}
+
+// GetExpense loads one expense by ID. Personal expenses are only visible to
+// their owner and household expenses to members of the household; to anyone
+// else the expense does not exist.
+func GetExpense(ctx context.Context, id string) (model.Expense, error) {
+	return getExpense(ctx, id, RoleViewer)
+}
+
+func getExpense(ctx context.Context, id, minRole string) (model.Expense, error) {
+	if id == "" {
+		return model.Expense{}, ErrInvalidArgument
+	}
+	expense, err := Expenses.Get(ctx, id)
+	if err != nil {
+		return model.Expense{}, err
+	}
+	userID, _, _ := auth.UserFromContext(ctx)
+	if expense.Household_id == "" {
+		if expense.User_id != userID {
+			return model.Expense{}, ErrNotFound
+		}
+	} else if err := authorizeRecord(ctx, expense.Household_id, userID, minRole); err != nil {
+		return model.Expense{}, err
+	}
+	return expense, nil
+}
+
+// UpdateExpense validates update and replaces the editable fields of an
+// expense with it. The ID, owner and household are never changed; household
+// expenses need an editor role.
+func UpdateExpense(ctx context.Context, id string, update ExpenseInput) (model.Expense, error) {
+	accountCurrency(ctx, update.AccountID, &update.Currency)
+	if err := update.Validate(); err != nil {
+		return model.Expense{}, err
+	}
+	expense, err := getExpense(ctx, id, RoleEditor)
+	if err != nil {
+		return model.Expense{}, err
+	}
+	before := expense
+	expense.Amount = update.Amount
+	expense.Currency = update.Currency
+	expense.Category = update.Category
+	expense.Description = update.Description
+	expense.TimeStamp = update.TimeStamp
+	expense.Account_id = update.AccountID
+	expense.Tags = update.Tags
+	userID, _, _ := auth.UserFromContext(ctx)
+	if err := checkAccount(ctx, userID, expense.Account_id, expense.Household_id, expense.Currency); err != nil {
+		return model.Expense{}, err
+	}
+
+	if err := Expenses.Update(ctx, expense); err != nil {
+		return model.Expense{}, err
+	}
+	forget(before)
+	learn(expense)
+	return expense, nil
+}
+
+// DeleteExpense removes an expense. Deleting a missing expense is not an
+// error; household expenses need an editor role.
+func DeleteExpense(ctx context.Context, id string) error {
+	expense, err := getExpense(ctx, id, RoleEditor)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	if err := Expenses.Delete(ctx, id); err != nil {
+		return err
+	}
+	forget(expense)
+	return nil
+}
+
+// scopeFilter checks that the caller may read what a filter selects: the
+// household it is scoped to, or else their own personal records. A personal
+// filter without a user is limited to the caller.
+func scopeFilter(ctx context.Context, f *ExpenseFilter) error {
+	if f.HouseholdID != "" {
+		return authorizeFromContext(ctx, f.HouseholdID, RoleViewer)
+	}
+	userID, _, _ := auth.UserFromContext(ctx)
+	switch {
+	case userID == "", f.UserID != "" && f.UserID != userID:
+		return ErrForbidden
+	case f.UserID == "":
+		f.UserID = userID
+	}
+	return nil
+}
+
+// ListExpenses returns one page of expenses matching the filter, oldest first.
+func ListExpenses(ctx context.Context, f ExpenseFilter) ([]model.Expense, error) {
+	if err := scopeFilter(ctx, &f); err != nil {
+		return nil, err
+	}
+	return Expenses.List(ctx, f)
+}
+
+// StreamExpenses calls fn for every expense matching the filter, reading
+// batchSize rows at a time so large exports never sit in memory at once.
+// Limit and Offset are ignored. Returning an error from fn stops the scan.
+func StreamExpenses(ctx context.Context, f ExpenseFilter, batchSize int, fn func(model.Expense) error) error {
+	if err := scopeFilter(ctx, &f); err != nil {
+		return err
+	}
+	f.Limit, f.Offset = 0, 0
+	return Expenses.Stream(ctx, f, batchSize, fn)
+}
+
+// SummarizeExpenses totals expense amounts per category. Category, currency,
+// limit and offset in the filter are ignored.
+func SummarizeExpenses(ctx context.Context, f ExpenseFilter) (map[string]float64, error) {
+	if err := scopeFilter(ctx, &f); err != nil {
+		return nil, err
+	}
+	f.Category, f.Currency, f.Limit, f.Offset = "", "", 0, 0
+	return Expenses.Summarize(ctx, f)
+}
+
+// SummarizeByMember totals expense amounts per attributed user, which for a
+// household is the share each member recorded. The same filter fields as
+// SummarizeExpenses are ignored.
+func SummarizeByMember(ctx context.Context, f ExpenseFilter) (map[string]float64, error) {
+	if err := scopeFilter(ctx, &f); err != nil {
+		return nil, err
+	}
+	f.Category, f.Currency, f.Limit, f.Offset = "", "", 0, 0
+	return Expenses.SummarizeByUser(ctx, f)
+}
+
+// ExpensesByCategory loads the expenses matching the filter for several
+// categories in one query, keeping at most perCategory of the most recent
+// expenses for each. The filter's own category, limit and offset are ignored.
+func ExpensesByCategory(ctx context.Context, f ExpenseFilter, categories []string, perCategory int) (map[string][]model.Expense, error) {
+	if err := scopeFilter(ctx, &f); err != nil {
+		return nil, err
+	}
+	f.Category, f.Limit, f.Offset = "", 0, 0
+	return Expenses.ByCategory(ctx, f, categories, perCategory)
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"expense-tracker/auth"
+	"expense-tracker/model"
+	"expense-tracker/postgresql"
+	"expense-tracker/store"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Household roles, from least to most privileged. Viewers can read a
+// household's expenses, editors can also record and change them, and the
+// owner can also manage members and invites.
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleOwner  = "owner"
+)
+
+var roleRank = map[string]int{RoleViewer: 1, RoleEditor: 2, RoleOwner: 3}
+
+// InviteTTL is how long a household invite code stays valid.
+const InviteTTL = 7 * 24 * time.Hour
+
+var (
+	// ErrForbidden is returned when the user's household role does not allow
+	// the operation, or the user is not a member at all.
+	ErrForbidden = errors.New("forbidden")
+	// ErrAlreadyMember is returned when joining a household twice.
+	ErrAlreadyMember = errors.New("already a member")
+)
+
+// Households is the household store; tests swap in the in-memory one.
+var Households store.HouseholdStore = postgresql.HouseholdStore{}
+
+// Membership is a household together with the caller's role in it.
+type Membership struct {
+	Household model.Household
+	Role      string
+}
+
+// authorize returns the user's membership if their role is at least minRole.
+func authorize(ctx context.Context, householdID, userID, minRole string) (model.HouseholdMember, error) {
+	if userID == "" {
+		return model.HouseholdMember{}, ErrForbidden
+	}
+	member, err := Households.Member(ctx, householdID, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return model.HouseholdMember{}, ErrForbidden
+	}
+	if err != nil {
+		return model.HouseholdMember{}, err
+	}
+	if roleRank[member.Role] < roleRank[minRole] {
+		return model.HouseholdMember{}, ErrForbidden
+	}
+	return member, nil
+}
+
+// authorizeRecord is authorize for a record in a household ledger. To anyone
+// outside the household the record does not exist, as another user's
+// personal record does not, so probing IDs reveals nothing; members without
+// minRole are still forbidden.
+func authorizeRecord(ctx context.Context, householdID, userID, minRole string) error {
+	_, err := authorize(ctx, householdID, userID, minRole)
+	if errors.Is(err, ErrForbidden) && minRole != RoleViewer {
+		_, err = authorize(ctx, householdID, userID, RoleViewer)
+		if err == nil {
+			return ErrForbidden
+		}
+	}
+	if errors.Is(err, ErrForbidden) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// authorizeFromContext is authorize for the user authenticated on ctx, used
+// by the expense operations whose signatures carry no user.
+func authorizeFromContext(ctx context.Context, householdID, minRole string) error {
+	userID, _, _ := auth.UserFromContext(ctx)
+	_, err := authorize(ctx, householdID, userID, minRole)
+	return err
+}
+
+// CreateHousehold creates a household owned by userID.
+func CreateHousehold(ctx context.Context, userID, name string) (model.Household, error) {
+	name = strings.TrimSpace(name)
+	if userID == "" || name == "" {
+		return model.Household{}, ErrInvalidArgument
+	}
+	now := time.Now().UTC()
+	household := model.Household{Id: uuid.New().String(), Name: name, OwnerId: userID, CreatedAt: now}
+	owner := model.HouseholdMember{HouseholdId: household.Id, UserId: userID, Role: RoleOwner, JoinedAt: now}
+	if err := Households.Create(ctx, household, owner); err != nil {
+		return model.Household{}, err
+	}
+	return household, nil
+}
+
+// ListHouseholds returns the households userID belongs to.
+func ListHouseholds(ctx context.Context, userID string) ([]Membership, error) {
+	members, err := Households.Memberships(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Membership, 0, len(members))
+	for _, m := range members {
+		household, err := Households.Get(ctx, m.HouseholdId)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Membership{Household: household, Role: m.Role})
+	}
+	return out, nil
+}
+
+// GetHousehold returns a household and its members to any member.
+func GetHousehold(ctx context.Context, userID, householdID string) (model.Household, []model.HouseholdMember, error) {
+	if _, err := authorize(ctx, householdID, userID, RoleViewer); err != nil {
+		return model.Household{}, nil, err
+	}
+	household, err := Households.Get(ctx, householdID)
+	if err != nil {
+		return model.Household{}, nil, err
+	}
+	members, err := Households.Members(ctx, householdID)
+	if err != nil {
+		return model.Household{}, nil, err
+	}
+	return household, members, nil
+}
+
+// CreateInvite lets the owner issue a single-use code that adds its holder
+// with role, which must be editor or viewer.
+func CreateInvite(ctx context.Context, userID, householdID, role string) (model.HouseholdInvite, error) {
+	if role != RoleEditor && role != RoleViewer {
+		return model.HouseholdInvite{}, ErrInvalidArgument
+	}
+	if _, err := authorize(ctx, householdID, userID, RoleOwner); err != nil {
+		return model.HouseholdInvite{}, err
+	}
+	code, err := inviteCode()
+	if err != nil {
+		return model.HouseholdInvite{}, err
+	}
+	invite := model.HouseholdInvite{
+		Code:        code,
+		HouseholdId: householdID,
+		Role:        role,
+		CreatedBy:   userID,
+		ExpiresAt:   time.Now().UTC().Add(InviteTTL),
+	}
+	if err := Households.CreateInvite(ctx, invite); err != nil {
+		return model.HouseholdInvite{}, err
+	}
+	return invite, nil
+}
+
+// inviteCode returns 10 random base32 characters (50 bits), easy to read out.
+func inviteCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.EncodeToString(b)[:10], nil
+}
+
+// JoinHousehold redeems an invite code for userID. Unknown, used and
+// expired codes all return ErrNotFound.
+func JoinHousehold(ctx context.Context, userID, code string) (model.HouseholdMember, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if userID == "" || code == "" {
+		return model.HouseholdMember{}, ErrInvalidArgument
+	}
+	invite, err := Households.TakeInvite(ctx, code)
+	if err != nil {
+		return model.HouseholdMember{}, err
+	}
+	if time.Now().After(invite.ExpiresAt) {
+		return model.HouseholdMember{}, ErrNotFound
+	}
+	member := model.HouseholdMember{
+		HouseholdId: invite.HouseholdId,
+		UserId:      userID,
+		Role:        invite.Role,
+		JoinedAt:    time.Now().UTC(),
+	}
+	if err := Households.AddMember(ctx, member); err != nil {
+		if errors.Is(err, store.ErrDuplicate) {
+			return model.HouseholdMember{}, ErrAlreadyMember
+		}
+		return model.HouseholdMember{}, err
+	}
+	return member, nil
+}
+
+// SetMemberRole lets the owner make another member an editor or viewer.
+func SetMemberRole(ctx context.Context, actorID, householdID, memberID, role string) (model.HouseholdMember, error) {
+	if role != RoleEditor && role != RoleViewer {
+		return model.HouseholdMember{}, ErrInvalidArgument
+	}
+	if _, err := authorize(ctx, householdID, actorID, RoleOwner); err != nil {
+		return model.HouseholdMember{}, err
+	}
+	member, err := Households.Member(ctx, householdID, memberID)
+	if err != nil {
+		return model.HouseholdMember{}, err
+	}
+	if member.Role == RoleOwner {
+		return model.HouseholdMember{}, ErrForbidden
+	}
+	member.Role = role
+	if err := Households.UpdateMember(ctx, member); err != nil {
+		return model.HouseholdMember{}, err
+	}
+	return member, nil
+}
+
+// RemoveMember lets the owner remove any other member, and any member other
+// than the owner leave.
+func RemoveMember(ctx context.Context, actorID, householdID, memberID string) error {
+	if actorID != memberID {
+		if _, err := authorize(ctx, householdID, actorID, RoleOwner); err != nil {
+			return err
+		}
+	}
+	member, err := Households.Member(ctx, householdID, memberID)
+	if errors.Is(err, store.ErrNotFound) && actorID == memberID {
+		return ErrForbidden
+	}
+	if err != nil {
+		return err
+	}
+	if member.Role == RoleOwner {
+		return ErrForbidden
+	}
+	return Households.RemoveMember(ctx, householdID, memberID)
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"expense-tracker/statement"
+	"expense-tracker/store"
+	"expense-tracker/validation"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ImportCategory is given to imported expenses when the import names no
+// category and neither a rule nor a suggestion supplies one, since bank
+// statements carry none.
+const ImportCategory = "uncategorized"
+
+// bankDetails is what a statement import records about a transaction
+// besides the expense or income itself.
+type bankDetails struct {
+	id, payee, memo string
+}
+
+// ImportOptions control a statement import. Empty fields take defaults.
+type ImportOptions struct {
+	// Format is ofx, qif or camt053; empty detects it from the contents.
+	Format      string
+	HouseholdID string
+	AccountID   string
+	// Category is given to every imported expense. Without it, rules and
+	// suggestions categorize each one, falling back to ImportCategory.
+	Category string
+	// Currency is used for transactions whose statement names none (QIF);
+	// it defaults to the account's currency, then DefaultCurrency.
+	Currency string
+}
+
+// ImportedTransaction is one statement transaction and, when it was new,
+// the expense or income created for it.
+type ImportedTransaction struct {
+	// ID is the bank's transaction ID.
+	ID       string    `json:"id" example:"ofx:4411:2024030501"`
+	Type     string    `json:"type" example:"expense"`
+	RecordID string    `json:"record_id,omitempty"`
+	Date     time.Time `json:"date"`
+	Amount   float64   `json:"amount" example:"-42.17"`
+	Currency string    `json:"currency" example:"USD"`
+	Payee    string    `json:"payee,omitempty" example:"CORNER GROCERY"`
+	Memo     string    `json:"memo,omitempty" example:"POS 0305"`
+}
+
+// ImportReport says what became of every transaction in a statement.
+type ImportReport struct {
+	Format     string                `json:"format" example:"ofx"`
+	New        []ImportedTransaction `json:"new"`
+	Duplicates []ImportedTransaction `json:"duplicates"`
+	Rejected   []statement.Rejection `json:"rejected"`
+}
+
+// ImportStatement reads a bank statement and records its debits as expenses
+// and its credits as income owned by userID. Transactions whose bank ID was
+// already imported into the same ledger, or that repeat within the file, are
+// reported as duplicates; lines that cannot be read or fail validation are
+// rejected. If a store fails part way, the transactions created so far stay
+// and importing the file again picks up the rest.
+func ImportStatement(ctx context.Context, userID string, r io.Reader, opts ImportOptions) (ImportReport, error) {
+	if opts.HouseholdID != "" {
+		if _, err := authorize(ctx, opts.HouseholdID, userID, RoleEditor); err != nil {
+			return ImportReport{}, err
+		}
+	}
+	if opts.AccountID != "" {
+		if err := checkImportAccount(ctx, userID, opts.AccountID); err != nil {
+			return ImportReport{}, err
+		}
+	}
+	accountCurrency(ctx, opts.AccountID, &opts.Currency)
+	opts.Currency = strings.ToUpper(strings.TrimSpace(opts.Currency))
+	if opts.Currency == "" {
+		opts.Currency = DefaultCurrency
+	}
+
+	stmt, err := statement.Parse(r, opts.Format, opts.Currency)
+	if err != nil {
+		return ImportReport{}, fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+	report := ImportReport{
+		Format:     stmt.Format,
+		New:        []ImportedTransaction{},
+		Duplicates: []ImportedTransaction{},
+		Rejected:   append([]statement.Rejection{}, stmt.Rejected...),
+	}
+
+	ids := make([]string, len(stmt.Transactions))
+	for i, t := range stmt.Transactions {
+		ids[i] = t.ID
+	}
+	scope := ExpenseFilter{HouseholdID: opts.HouseholdID}
+	if opts.HouseholdID == "" {
+		scope.UserID = userID
+	}
+	seen, err := Expenses.ExternalIDs(ctx, scope, ids)
+	if err != nil {
+		return ImportReport{}, err
+	}
+	seenIncome, err := Incomes.ExternalIDs(ctx, scope, ids)
+	if err != nil {
+		return ImportReport{}, err
+	}
+	for id := range seenIncome {
+		seen[id] = true
+	}
+
+	rules, err := loadRules(ctx, userID)
+	if err != nil {
+		return ImportReport{}, err
+	}
+
+	for i, t := range stmt.Transactions {
+		item := ImportedTransaction{ID: t.ID, Type: EntryExpense, Date: t.Date, Amount: t.Amount,
+			Currency: t.Currency, Payee: t.Payee, Memo: t.Memo}
+		if t.Amount > 0 {
+			item.Type = EntryIncome
+		}
+		if seen[t.ID] {
+			report.Duplicates = append(report.Duplicates, item)
+			continue
+		}
+		item.RecordID, err = importTransaction(ctx, userID, t, opts, rules)
+		if errors.Is(err, store.ErrDuplicate) {
+			// An import of the same statement running alongside got there
+			// first; the ledger's unique bank IDs let only one through.
+			seen[t.ID] = true
+			report.Duplicates = append(report.Duplicates, item)
+			continue
+		}
+		var invalid validation.Errors
+		if errors.As(err, &invalid) {
+			report.Rejected = append(report.Rejected, statement.Rejection{Index: i + 1, ID: t.ID, Reason: invalid.Error()})
+			continue
+		}
+		if err != nil {
+			return ImportReport{}, err
+		}
+		seen[t.ID] = true
+		report.New = append(report.New, item)
+	}
+	return report, nil
+}
+
+// checkImportAccount rejects an import into an account the user cannot edit
+// before any transaction is read.
+func checkImportAccount(ctx context.Context, userID, accountID string) error {
+	if _, err := accountFor(ctx, userID, accountID, RoleEditor); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return validation.Errors{{Field: "account_id", Reason: "does not exist"}}
+		}
+		return err
+	}
+	return nil
+}
+
+// importTransaction creates the expense or income for t and returns its ID.
+// rules are userID's, loaded once for the whole statement.
+func importTransaction(ctx context.Context, userID string, t statement.Transaction, opts ImportOptions, rules ruleSet) (string, error) {
+	bank := bankDetails{id: t.ID, payee: t.Payee, memo: t.Memo}
+	description := truncate(firstNonEmptyString(t.Payee, t.Memo), 256)
+	if t.Amount > 0 {
+		income, err := CreateIncome(ctx, userID, IncomeInput{
+			Amount: t.Amount, Currency: t.Currency, Category: IncomeOther, Description: description,
+			TimeStamp: t.Date, HouseholdID: opts.HouseholdID, AccountID: opts.AccountID, bank: bank,
+		})
+		return income.Id, err
+	}
+	expense, err := CreateExpense(ctx, userID, ExpenseInput{
+		Amount: -t.Amount, Currency: t.Currency, Category: opts.Category, Description: description,
+		TimeStamp: t.Date, HouseholdID: opts.HouseholdID, AccountID: opts.AccountID, bank: bank,
+		defaultCategory: ImportCategory, rules: &rules,
+	})
+	return expense.Id, err
+}
+
+func firstNonEmptyString(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// truncate shortens s to at most n characters.
+func truncate(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
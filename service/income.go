@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"expense-tracker/auth"
+	"expense-tracker/model"
+	"expense-tracker/postgresql"
+	"expense-tracker/store"
+	"expense-tracker/validation"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Incomes is the store income is read from and written to.
+var Incomes store.IncomeStore = postgresql.IncomeStore{}
+
+// Income categories.
+const (
+	IncomeSalary = "salary"
+	IncomeRefund = "refund"
+	IncomeOther  = "other"
+)
+
+// IncomeInput is the caller-supplied part of an income record. It follows
+// the same rules as ExpenseInput, except that the category is one of the
+// income categories.
+type IncomeInput struct {
+	Amount      float64 `json:"amount" validate:"gt=0,lte=1000000000,precision=Currency" example:"2500"`
+	Currency    string  `json:"currency" validate:"iso4217" example:"USD"`
+	Category    string  `json:"category" validate:"oneof=salary refund other" example:"salary"`
+	Description string  `json:"description" validate:"max=256" example:"October pay"`
+	// TimeStamp defaults to now.
+	TimeStamp time.Time `json:"timestamp" validate:"notfarfuture"`
+	// HouseholdID and UserID are only read on creation, as for expenses.
+	HouseholdID string `json:"household_id,omitempty"`
+	UserID      string `json:"user_id,omitempty"`
+	// AccountID is the account the money was paid into.
+	AccountID string `json:"account_id,omitempty"`
+	// bank is only set by statement imports.
+	bank bankDetails
+}
+
+// Validate normalizes the input and checks it, returning validation.Errors
+// listing each failing field.
+func (in *IncomeInput) Validate() error {
+	in.Currency = strings.ToUpper(strings.TrimSpace(in.Currency))
+	if in.Currency == "" {
+		in.Currency = DefaultCurrency
+	}
+	in.Category = strings.ToLower(strings.TrimSpace(in.Category))
+	if in.TimeStamp.IsZero() {
+		in.TimeStamp = time.Now()
+	}
+	in.TimeStamp = in.TimeStamp.UTC()
+	return validation.Struct(in)
+}
+
+func (in IncomeInput) income() model.Income {
+	return model.Income{
+		User_id:      in.UserID,
+		Household_id: in.HouseholdID,
+		Account_id:   in.AccountID,
+		External_id:  in.bank.id,
+		Payee:        in.bank.payee,
+		Memo:         in.bank.memo,
+		Amount:       in.Amount,
+		Currency:     in.Currency,
+		Category:     in.Category,
+		Description:  in.Description,
+		TimeStamp:    in.TimeStamp,
+	}
+}
+
+// CreateIncome validates in and stores it as new income owned by userID,
+// with the same household rules as CreateExpense.
+func CreateIncome(ctx context.Context, userID string, in IncomeInput) (model.Income, error) {
+	accountCurrency(ctx, in.AccountID, &in.Currency)
+	if err := in.Validate(); err != nil {
+		return model.Income{}, err
+	}
+	income := in.income()
+	if income.Household_id != "" {
+		if _, err := authorize(ctx, income.Household_id, userID, RoleEditor); err != nil {
+			return model.Income{}, err
+		}
+		if income.User_id != "" && income.User_id != userID {
+			if _, err := authorize(ctx, income.Household_id, income.User_id, RoleViewer); err != nil {
+				return model.Income{}, ErrInvalidArgument
+			}
+			userID = income.User_id
+		}
+	}
+	if err := checkAccount(ctx, userID, income.Account_id, income.Household_id, income.Currency); err != nil {
+		return model.Income{}, err
+	}
+	income.Id = uuid.New().String()
+	income.User_id = userID
+	if err := Incomes.Create(ctx, income); err != nil {
+		return model.Income{}, err
+	}
+	return income, nil
+}
+
+// GetIncome loads one income record by ID. Personal income is only visible
+// to its owner and household income to members of the household.
+func GetIncome(ctx context.Context, id string) (model.Income, error) {
+	return getIncome(ctx, id, RoleViewer)
+}
+
+func getIncome(ctx context.Context, id, minRole string) (model.Income, error) {
+	if id == "" {
+		return model.Income{}, ErrInvalidArgument
+	}
+	income, err := Incomes.Get(ctx, id)
+	if err != nil {
+		return model.Income{}, err
+	}
+	userID, _, _ := auth.UserFromContext(ctx)
+	if income.Household_id == "" {
+		if income.User_id != userID {
+			return model.Income{}, ErrNotFound
+		}
+	} else if err := authorizeRecord(ctx, income.Household_id, userID, minRole); err != nil {
+		return model.Income{}, err
+	}
+	return income, nil
+}
+
+// UpdateIncome validates update and replaces the editable fields of an
+// income record with it. The ID, owner and household are never changed.
+func UpdateIncome(ctx context.Context, id string, update IncomeInput) (model.Income, error) {
+	accountCurrency(ctx, update.AccountID, &update.Currency)
+	if err := update.Validate(); err != nil {
+		return model.Income{}, err
+	}
+	income, err := getIncome(ctx, id, RoleEditor)
+	if err != nil {
+		return model.Income{}, err
+	}
+	income.Amount = update.Amount
+	income.Currency = update.Currency
+	income.Category = update.Category
+	income.Description = update.Description
+	income.TimeStamp = update.TimeStamp
+	income.Account_id = update.AccountID
+	userID, _, _ := auth.UserFromContext(ctx)
+	if err := checkAccount(ctx, userID, income.Account_id, income.Household_id, income.Currency); err != nil {
+		return model.Income{}, err
+	}
+
+	if err := Incomes.Update(ctx, income); err != nil {
+		return model.Income{}, err
+	}
+	return income, nil
+}
+
+// DeleteIncome removes an income record. Deleting missing income is not an
+// error; household income needs an editor role.
+func DeleteIncome(ctx context.Context, id string) error {
+	if _, err := getIncome(ctx, id, RoleEditor); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	return Incomes.Delete(ctx, id)
+}
+
+// ListIncome returns one page of income matching the filter, oldest first.
+func ListIncome(ctx context.Context, f ExpenseFilter) ([]model.Income, error) {
+	if err := scopeFilter(ctx, &f); err != nil {
+		return nil, err
+	}
+	return Incomes.List(ctx, f)
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"expense-tracker/metrics"
+	"expense-tracker/model"
+	"expense-tracker/postgresql"
+	"expense-tracker/store"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Reasons recorded for failed logins.
+const (
+	FailureUnknownUser = "unknown_user"
+	FailureBadPassword = "bad_password"
+	FailureBadCode     = "bad_code"
+)
+
+// delayAfter is the number of consecutive failures allowed before attempts
+// are spaced out.
+const delayAfter = 2
+
+var (
+	// ErrInvalidCredentials is returned by Login for an unknown user name or
+	// a wrong password alike, so callers cannot probe for user names.
+	ErrInvalidCredentials = errors.New("invalid user name or password")
+	// ErrLockedOut matches every *LockoutError.
+	ErrLockedOut = errors.New("too many failed login attempts")
+)
+
+// LockoutError is returned while a user name is locked out or must wait
+// before its next login attempt.
+type LockoutError struct {
+	Until time.Time
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("%v; retry after %s", ErrLockedOut, e.Until.UTC().Format(time.RFC3339))
+}
+
+func (e *LockoutError) Is(target error) bool { return target == ErrLockedOut }
+
+// RetryAfter is how long the caller must wait, rounded up to a second.
+func (e *LockoutError) RetryAfter() time.Duration {
+	d := time.Until(e.Until)
+	if d < time.Second {
+		return time.Second
+	}
+	return d.Round(time.Second)
+}
+
+// LoginAttempts is the failed login store; tests swap in the in-memory one.
+var LoginAttempts store.LoginAttemptStore = postgresql.LoginAttemptStore{}
+
+// throttleKey is the name failures are counted under, so that "Alice" and
+// "alice" share a budget.
+func throttleKey(userName string) string {
+	return strings.ToLower(strings.TrimSpace(userName))
+}
+
+// checkThrottle returns a *LockoutError if userName may not try to log in
+// yet: while it is locked out, or within the progressive delay that follows
+// each failure after the first few.
+func checkThrottle(ctx context.Context, userName string) error {
+	t, err := LoginAttempts.Throttle(ctx, throttleKey(userName))
+	if err != nil {
+		return err
+	}
+	until := t.LockedUntil
+	if next := t.LastFailure.Add(failureDelay(t.Failures)); next.After(until) {
+		until = next
+	}
+	if time.Now().Before(until) {
+		metrics.LoginAttempts.WithLabelValues("locked").Inc()
+		return &LockoutError{Until: until}
+	}
+	return nil
+}
+
+// failureDelay is the wait after the given number of consecutive failures:
+// nothing for the first few, then one second doubling with each failure,
+// capped at the lockout duration.
+func failureDelay(failures int) time.Duration {
+	if failures <= delayAfter {
+		return 0
+	}
+	d := time.Second << min(failures-delayAfter-1, 20)
+	return min(d, conf.Lockout.LockoutDuration.Duration)
+}
+
+// lockoutFor is the lockout imposed once failures reaches a multiple of the
+// threshold: the base duration, doubled for each earlier lockout.
+func lockoutFor(failures int) time.Duration {
+	if failures%conf.Lockout.LockoutThreshold != 0 {
+		return 0
+	}
+	d := conf.Lockout.LockoutDuration.Duration << min(failures/conf.Lockout.LockoutThreshold-1, 20)
+	return min(d, conf.Lockout.MaxLockout.Duration)
+}
+
+// recordFailure stores a failed attempt and locks the name out when it
+// reaches the threshold.
+func recordFailure(ctx context.Context, userName, userID, clientIP, reason string) error {
+	metrics.LoginAttempts.WithLabelValues("failure").Inc()
+	now := time.Now().UTC()
+	key := throttleKey(userName)
+	t, err := LoginAttempts.RecordFailure(ctx, key, model.LoginAttempt{
+		Id:        uuid.New().String(),
+		UserName:  userName,
+		UserId:    userID,
+		ClientIP:  clientIP,
+		Reason:    reason,
+		CreatedAt: now,
+	})
+	if err != nil {
+		return err
+	}
+	if d := lockoutFor(t.Failures); d > 0 {
+		log.WithFields(log.Fields{"user_name": userName, "failures": t.Failures, "lockout": d}).Warn("Locking out user name after failed logins")
+		return LoginAttempts.Lock(ctx, key, now.Add(d))
+	}
+	return nil
+}
+
+// clearFailures forgets a name's failures after a successful login. Failing
+// to do so only leaves the user throttled, so it is logged, not returned.
+func clearFailures(ctx context.Context, userName string) {
+	if err := LoginAttempts.Reset(ctx, throttleKey(userName)); err != nil {
+		log.WithField("user_name", userName).Errorf("Failed to reset login failures: %v", err)
+	}
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// equalizeTiming spends as long as checking a real password, so a login for
+// an unknown user name takes as long as one with a wrong password.
+func equalizeTiming(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("timing-equalizer"), bcrypt.DefaultCost)
+	})
+	_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
+
+// ListLoginFailures returns recent failed logins, newest first, optionally
+// for one user name. Only admins may see them.
+func ListLoginFailures(ctx context.Context, adminID string, filter store.LoginAttemptFilter) ([]model.LoginAttempt, error) {
+	if err := requireAdmin(ctx, adminID); err != nil {
+		return nil, err
+	}
+	if filter.Limit <= 0 || filter.Limit > 500 {
+		filter.Limit = 100
+	}
+	return LoginAttempts.Failures(ctx, filter)
+}
+
+// UnlockLogin lets an admin lift a lockout before it expires.
+func UnlockLogin(ctx context.Context, adminID, userName string) error {
+	if err := requireAdmin(ctx, adminID); err != nil {
+		return err
+	}
+	return LoginAttempts.Reset(ctx, throttleKey(userName))
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"expense-tracker/auth"
+	"expense-tracker/model"
+	"expense-tracker/notify"
+	"expense-tracker/store"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrWeakPassword wraps the reason a new password was rejected.
+	ErrWeakPassword = auth.ErrWeakPassword
+	// ErrInvalidResetToken is returned for unknown, used and expired reset
+	// tokens alike.
+	ErrInvalidResetToken = errors.New("invalid or expired reset token")
+)
+
+// hashPassword applies the password policy and hashes the password.
+func hashPassword(password string) (string, error) {
+	if err := auth.CheckPassword(password); err != nil {
+		return "", err
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// setPassword stores a new password and bumps the session version, which
+// revokes every token issued so far, along with outstanding reset tokens.
+func setPassword(ctx context.Context, user model.User, password string) (model.User, error) {
+	hashed, err := hashPassword(password)
+	if err != nil {
+		return model.User{}, err
+	}
+	user.Password = hashed
+	user.SessionVersion++
+	if err := Users.Update(ctx, user); err != nil {
+		return model.User{}, err
+	}
+	if err := Users.DeleteResets(ctx, user.UserId); err != nil {
+		return model.User{}, err
+	}
+	return user, nil
+}
+
+// ChangePassword replaces the password of userID after checking the current
+// one. All of the user's other sessions are revoked; the returned token
+// replaces the caller's.
+func ChangePassword(ctx context.Context, userID, current, password string) (string, error) {
+	if current == "" || password == "" {
+		return "", ErrInvalidArgument
+	}
+	user, err := Users.ByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(current)); err != nil {
+		return "", ErrInvalidPassword
+	}
+	user, err = setPassword(ctx, user, password)
+	if err != nil {
+		return "", err
+	}
+	return auth.GenerateToken(user.UserId, user.Tier, user.SessionVersion)
+}
+
+// RequestPasswordReset sends a single-use reset token to the named user.
+// Unknown user names are silently ignored so the response does not reveal
+// which accounts exist.
+func RequestPasswordReset(ctx context.Context, userName string) error {
+	if userName == "" {
+		return ErrInvalidArgument
+	}
+	user, err := Users.ByName(ctx, userName)
+	if errors.Is(err, store.ErrNotFound) {
+		log.WithField("user_name", userName).Info("Password reset requested for unknown user")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	token, err := resetToken()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	reset := model.PasswordReset{
+		TokenHash: hashToken(token),
+		UserId:    user.UserId,
+		ExpiresAt: now.Add(conf.PasswordResetTTL),
+		CreatedAt: now,
+	}
+	if err := Users.CreateReset(ctx, reset); err != nil {
+		return err
+	}
+	return conf.Notifier.Notify(ctx, notify.Message{
+		Kind:      notify.KindPasswordReset,
+		UserID:    user.UserId,
+		UserName:  user.UserName,
+		Token:     token,
+		ExpiresAt: reset.ExpiresAt,
+	})
+}
+
+// ResetPassword sets a new password using a token from RequestPasswordReset
+// and revokes all of the user's sessions. The password is checked before the
+// token is used up, so a rejected password can be retried.
+func ResetPassword(ctx context.Context, token, password string) error {
+	if token == "" || password == "" {
+		return ErrInvalidArgument
+	}
+	if err := auth.CheckPassword(password); err != nil {
+		return err
+	}
+	reset, err := Users.TakeReset(ctx, hashToken(token))
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+	if time.Now().After(reset.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+	user, err := Users.ByID(ctx, reset.UserId)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+	_, err = setPassword(ctx, user, password)
+	return err
+}
+
+// ValidateSession is the auth.SessionValidator: a token is valid while its
+// session version matches the user's.
+func ValidateSession(ctx context.Context, userID string, version int) error {
+	user, err := Users.ByID(ctx, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return auth.ErrSessionRevoked
+	}
+	if err != nil {
+		return err
+	}
+	if user.SessionVersion != version {
+		return auth.ErrSessionRevoked
+	}
+	return nil
+}
+
+// resetToken returns 32 random bytes, URL-safe base64 encoded.
+func resetToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken is the form a reset token is stored and looked up in. The token
+// has 256 bits of entropy, so an unsalted fast hash is sufficient.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,457 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"expense-tracker/model"
+	"expense-tracker/postgresql"
+	"expense-tracker/store"
+	"expense-tracker/validation"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Rules is the store categorization rules are kept in.
+var Rules store.RuleStore = postgresql.RuleStore{}
+
+// rulesBatchSize is how many expenses ApplyRules reads at a time.
+const rulesBatchSize = 500
+
+// RuleConditions are the tests an expense must pass for a rule to apply;
+// empty ones are not checked. Description matches ignore case for contains;
+// a regular expression can opt in with (?i).
+type RuleConditions struct {
+	DescriptionContains string   `json:"description_contains,omitempty" validate:"max=256" example:"starbucks"`
+	DescriptionRegex    string   `json:"description_regex,omitempty" validate:"max=256" example:"^UBER\\s+TRIP"`
+	MinAmount           *float64 `json:"min_amount,omitempty" validate:"omitempty,gte=0" example:"0"`
+	MaxAmount           *float64 `json:"max_amount,omitempty" validate:"omitempty,gte=0" example:"50"`
+	Currency            string   `json:"currency,omitempty" validate:"omitempty,iso4217" example:"USD"`
+	AccountID           string   `json:"account_id,omitempty"`
+}
+
+// RuleActions are what a rule does to a matching expense.
+type RuleActions struct {
+	Category    string   `json:"category,omitempty" validate:"max=64" example:"coffee"`
+	Tags        []string `json:"tags,omitempty" validate:"max=20,dive,max=32"`
+	Description string   `json:"description,omitempty" validate:"max=256" example:"Starbucks"`
+}
+
+// RuleInput is the caller-supplied part of a rule. At least one condition
+// and one action must be set.
+type RuleInput struct {
+	Name string `json:"name" validate:"required,max=64" example:"Coffee"`
+	// Priority orders the rules, lowest first.
+	Priority   int            `json:"priority" validate:"gte=0,lte=10000" example:"10"`
+	Conditions RuleConditions `json:"conditions"`
+	Actions    RuleActions    `json:"actions"`
+}
+
+// Validate normalizes the input and checks it, returning validation.Errors
+// listing each failing field.
+func (in *RuleInput) Validate() error {
+	c, a := &in.Conditions, &in.Actions
+	in.Name = strings.TrimSpace(in.Name)
+	c.DescriptionContains = strings.TrimSpace(c.DescriptionContains)
+	c.Currency = strings.ToUpper(strings.TrimSpace(c.Currency))
+	a.Category = strings.TrimSpace(a.Category)
+	a.Description = strings.TrimSpace(a.Description)
+	a.Tags = normalizeTags(a.Tags)
+
+	var errs validation.Errors
+	if err := validation.Struct(in); err != nil && !errors.As(err, &errs) {
+		return err
+	}
+	if c.DescriptionRegex != "" {
+		if _, err := regexp.Compile(c.DescriptionRegex); err != nil {
+			errs = append(errs, validation.FieldError{Field: "conditions.description_regex", Reason: "is not a valid regular expression"})
+		}
+	}
+	if c.MinAmount != nil && c.MaxAmount != nil && *c.MaxAmount < *c.MinAmount {
+		errs = append(errs, validation.FieldError{Field: "conditions.max_amount", Reason: "must be at least min_amount"})
+	}
+	if *c == (RuleConditions{}) {
+		errs = append(errs, validation.FieldError{Field: "conditions", Reason: "must set at least one condition"})
+	}
+	if a.Category == "" && a.Description == "" && len(a.Tags) == 0 {
+		errs = append(errs, validation.FieldError{Field: "actions", Reason: "must set at least one action"})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (in RuleInput) rule() model.Rule {
+	c, a := in.Conditions, in.Actions
+	return model.Rule{
+		Name:     in.Name,
+		Priority: in.Priority,
+		Conditions: model.RuleConditions{
+			DescriptionContains: c.DescriptionContains,
+			DescriptionRegex:    c.DescriptionRegex,
+			MinAmount:           c.MinAmount,
+			MaxAmount:           c.MaxAmount,
+			Currency:            c.Currency,
+			AccountId:           c.AccountID,
+		},
+		Actions: model.RuleActions{Category: a.Category, Tags: a.Tags, Description: a.Description},
+	}
+}
+
+// checkRuleAccount rejects a condition on an account userID cannot see.
+func checkRuleAccount(ctx context.Context, userID, accountID string) error {
+	if accountID == "" {
+		return nil
+	}
+	if _, err := accountFor(ctx, userID, accountID, RoleViewer); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return validation.Errors{{Field: "conditions.account_id", Reason: "does not exist"}}
+		}
+		return err
+	}
+	return nil
+}
+
+// CreateRule validates in and stores it as a new rule of userID's.
+func CreateRule(ctx context.Context, userID string, in RuleInput) (model.Rule, error) {
+	if err := in.Validate(); err != nil {
+		return model.Rule{}, err
+	}
+	if err := checkRuleAccount(ctx, userID, in.Conditions.AccountID); err != nil {
+		return model.Rule{}, err
+	}
+	rule := in.rule()
+	rule.Id = uuid.New().String()
+	rule.UserId = userID
+	rule.CreatedAt = time.Now().UTC()
+	if err := Rules.Create(ctx, rule); err != nil {
+		return model.Rule{}, err
+	}
+	return rule, nil
+}
+
+// GetRule loads one of userID's rules. Other users' rules are not found.
+func GetRule(ctx context.Context, userID, id string) (model.Rule, error) {
+	if id == "" {
+		return model.Rule{}, ErrInvalidArgument
+	}
+	rule, err := Rules.Get(ctx, id)
+	if err != nil {
+		return model.Rule{}, err
+	}
+	if rule.UserId != userID {
+		return model.Rule{}, ErrNotFound
+	}
+	return rule, nil
+}
+
+// ListRules returns userID's rules in the order they run.
+func ListRules(ctx context.Context, userID string) ([]model.Rule, error) {
+	rules, err := Rules.List(ctx, userID)
+	if rules == nil {
+		rules = []model.Rule{}
+	}
+	return rules, err
+}
+
+// UpdateRule validates in and replaces one of userID's rules with it.
+func UpdateRule(ctx context.Context, userID, id string, in RuleInput) (model.Rule, error) {
+	if err := in.Validate(); err != nil {
+		return model.Rule{}, err
+	}
+	existing, err := GetRule(ctx, userID, id)
+	if err != nil {
+		return model.Rule{}, err
+	}
+	if err := checkRuleAccount(ctx, userID, in.Conditions.AccountID); err != nil {
+		return model.Rule{}, err
+	}
+	rule := in.rule()
+	rule.Id, rule.UserId, rule.CreatedAt = existing.Id, existing.UserId, existing.CreatedAt
+	if err := Rules.Update(ctx, rule); err != nil {
+		return model.Rule{}, err
+	}
+	return rule, nil
+}
+
+// DeleteRule removes one of userID's rules. Deleting a missing rule is not
+// an error.
+func DeleteRule(ctx context.Context, userID, id string) error {
+	if _, err := GetRule(ctx, userID, id); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	return Rules.Delete(ctx, id)
+}
+
+// Categorization is what rules decide about an expense.
+type Categorization struct {
+	Category    string   `json:"category" example:"coffee"`
+	Description string   `json:"description" example:"Starbucks"`
+	Tags        []string `json:"tags"`
+}
+
+func categorization(e model.Expense) Categorization {
+	tags := e.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return Categorization{Category: e.Category, Description: e.Description, Tags: tags}
+}
+
+// compiledRule is a rule ready to be matched.
+type compiledRule struct {
+	model.Rule
+	contains string
+	re       *regexp.Regexp
+}
+
+func compileRule(r model.Rule) (compiledRule, error) {
+	cr := compiledRule{Rule: r, contains: strings.ToLower(r.Conditions.DescriptionContains)}
+	if r.Conditions.DescriptionRegex != "" {
+		re, err := regexp.Compile(r.Conditions.DescriptionRegex)
+		if err != nil {
+			return compiledRule{}, fmt.Errorf("rule %s: %w", r.Id, err)
+		}
+		cr.re = re
+	}
+	return cr, nil
+}
+
+func (r compiledRule) matches(e model.Expense) bool {
+	c := r.Conditions
+	switch {
+	case r.contains != "" && !strings.Contains(strings.ToLower(e.Description), r.contains),
+		r.re != nil && !r.re.MatchString(e.Description),
+		c.MinAmount != nil && e.Amount < *c.MinAmount,
+		c.MaxAmount != nil && e.Amount > *c.MaxAmount,
+		c.Currency != "" && e.Currency != c.Currency,
+		c.AccountId != "" && e.Account_id != c.AccountId:
+		return false
+	}
+	return true
+}
+
+// ruleSet is a user's rules in the order they run.
+type ruleSet []compiledRule
+
+func loadRules(ctx context.Context, userID string) (ruleSet, error) {
+	rules, err := Rules.List(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	set := make(ruleSet, 0, len(rules))
+	for _, r := range rules {
+		cr, err := compileRule(r)
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, cr)
+	}
+	return set, nil
+}
+
+// apply runs every matching rule on e and returns those that matched. Each
+// rule is tested against e as it was before any of them changed it; when
+// several set the category or description the first one wins, while all
+// their tags are added. A category e already has is kept unless
+// recategorize is set, as when rules are re-applied to stored expenses.
+func (s ruleSet) apply(e *model.Expense, recategorize bool) []model.Rule {
+	original := *e
+	var matched []model.Rule
+	category := e.Category != "" && !recategorize
+	var description bool
+	for _, r := range s {
+		if !r.matches(original) {
+			continue
+		}
+		matched = append(matched, r.Rule)
+		if a := r.Actions; a.Category != "" && !category {
+			e.Category, category = a.Category, true
+		}
+		if a := r.Actions; a.Description != "" && !description {
+			e.Description, description = a.Description, true
+		}
+		e.Tags = normalizeTags(slices.Concat(e.Tags, r.Actions.Tags))
+	}
+	return matched
+}
+
+// applyRules runs userID's rules on a validated expense about to be
+// created, loading them unless in carries them already.
+func applyRules(ctx context.Context, userID string, in *ExpenseInput) error {
+	if in.rules == nil {
+		rules, err := loadRules(ctx, userID)
+		if err != nil {
+			return err
+		}
+		in.rules = &rules
+	}
+	rules := *in.rules
+	if len(rules) == 0 {
+		return nil
+	}
+	e := in.expense()
+	rules.apply(&e, false)
+	in.Category, in.Description, in.Tags = e.Category, e.Description, e.Tags
+	return nil
+}
+
+// onlyMissing reports whether err is a validation error saying nothing but
+// that field is required.
+func onlyMissing(err error, field string) bool {
+	var errs validation.Errors
+	return errors.As(err, &errs) && len(errs) == 1 && errs[0].Field == field && errs[0].Reason == "is required"
+}
+
+func normalizeCurrency(currency string) string {
+	if currency = strings.ToUpper(strings.TrimSpace(currency)); currency == "" {
+		return DefaultCurrency
+	}
+	return currency
+}
+
+// normalizeTags lowercases and trims tags, dropping empty and repeated ones.
+func normalizeTags(tags []string) []string {
+	var out []string
+	for _, t := range tags {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" && !slices.Contains(out, t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// RuleTestInput is an expense to try rules on. Without Rule, the caller's
+// saved rules run.
+type RuleTestInput struct {
+	Rule    *RuleInput   `json:"rule,omitempty"`
+	Expense ExpenseInput `json:"expense"`
+}
+
+// RuleTestResult lists the rules that matched and what they made of the
+// expense.
+type RuleTestResult struct {
+	Matched []model.Rule   `json:"matched"`
+	Result  Categorization `json:"result"`
+}
+
+// TryRules shows what rules would do to an expense without storing
+// anything. As on creation, a category the expense has is kept.
+func TryRules(ctx context.Context, userID string, in RuleTestInput) (RuleTestResult, error) {
+	var rules ruleSet
+	if in.Rule != nil {
+		if err := in.Rule.Validate(); err != nil {
+			var errs validation.Errors
+			if errors.As(err, &errs) {
+				for i := range errs {
+					errs[i].Field = "rule." + errs[i].Field
+				}
+			}
+			return RuleTestResult{}, err
+		}
+		cr, err := compileRule(in.Rule.rule())
+		if err != nil {
+			return RuleTestResult{}, err
+		}
+		rules = ruleSet{cr}
+	} else {
+		var err error
+		if rules, err = loadRules(ctx, userID); err != nil {
+			return RuleTestResult{}, err
+		}
+	}
+	e := in.Expense.expense()
+	e.Currency = normalizeCurrency(e.Currency)
+	e.Tags = normalizeTags(e.Tags)
+	matched := rules.apply(&e, false)
+	if matched == nil {
+		matched = []model.Rule{}
+	}
+	return RuleTestResult{Matched: matched, Result: categorization(e)}, nil
+}
+
+// RuleChange is an existing expense that rules changed, or would change.
+type RuleChange struct {
+	ExpenseID string `json:"expense_id"`
+	// Rules are the IDs of the rules that matched.
+	Rules  []string       `json:"rules"`
+	Before Categorization `json:"before"`
+	After  Categorization `json:"after"`
+}
+
+// RuleApplyReport summarizes a run of rules over existing expenses.
+type RuleApplyReport struct {
+	DryRun  bool         `json:"dry_run"`
+	Checked int          `json:"checked" example:"120"`
+	Changed []RuleChange `json:"changed"`
+}
+
+// ApplyRules runs userID's rules over the existing expenses matching the
+// filter and saves those they change, unless dryRun is set. Unlike on
+// creation, a matching rule replaces the category an expense has. Personal
+// expenses are limited to userID's own; household expenses need an editor
+// role. Limit and offset are ignored. Changes are saved a batch at a time,
+// so if saving fails part way the batches saved before stay.
+func ApplyRules(ctx context.Context, userID string, f ExpenseFilter, dryRun bool) (RuleApplyReport, error) {
+	if f.HouseholdID == "" {
+		f.UserID = userID
+	} else if _, err := authorize(ctx, f.HouseholdID, userID, RoleEditor); err != nil {
+		return RuleApplyReport{}, err
+	}
+	f.Limit, f.Offset = 0, 0
+	rules, err := loadRules(ctx, userID)
+	if err != nil {
+		return RuleApplyReport{}, err
+	}
+
+	report := RuleApplyReport{DryRun: dryRun, Changed: []RuleChange{}}
+	var originals, changed []model.Expense
+	save := func() error {
+		for i, e := range changed {
+			if err := Expenses.Update(ctx, e); err != nil {
+				return err
+			}
+			forget(originals[i])
+			learn(e)
+		}
+		originals, changed = originals[:0], changed[:0]
+		return nil
+	}
+	err = Expenses.Stream(ctx, f, rulesBatchSize, func(e model.Expense) error {
+		report.Checked++
+		before := e
+		matched := rules.apply(&e, true)
+		if e.Category == before.Category && e.Description == before.Description && slices.Equal(e.Tags, before.Tags) {
+			return nil
+		}
+		ids := make([]string, len(matched))
+		for i, r := range matched {
+			ids[i] = r.Id
+		}
+		report.Changed = append(report.Changed, RuleChange{ExpenseID: e.Id, Rules: ids,
+			Before: categorization(before), After: categorization(e)})
+		if dryRun {
+			return nil
+		}
+		originals, changed = append(originals, before), append(changed, e)
+		if len(changed) == rulesBatchSize {
+			return save()
+		}
+		return nil
+	})
+	if err == nil {
+		err = save()
+	}
+	if err != nil {
+		return RuleApplyReport{}, err
+	}
+	return report, nil
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"expense-tracker/auth"
+	"expense-tracker/metrics"
+	"expense-tracker/model"
+	"expense-tracker/oidc"
+	"expense-tracker/store"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrSSODisabled is returned when no OIDC provider is configured.
+	ErrSSODisabled = errors.New("single sign-on is not configured")
+	// ErrSSOFailed is returned when the provider's response does not check
+	// out: a stale or forged state, a bad code or an invalid ID token.
+	ErrSSOFailed = errors.New("single sign-on failed")
+	// ErrIdentityLinked is returned when linking an identity that already
+	// belongs to another user.
+	ErrIdentityLinked = errors.New("identity is linked to another account")
+	// ErrNoAccount is returned for an unlinked identity when accounts are not
+	// provisioned automatically.
+	ErrNoAccount = errors.New("no account is linked to this identity")
+	// ErrAccountExists is returned when a new identity's user name is taken;
+	// its owner must sign in and link the identity instead.
+	ErrAccountExists = errors.New("an account with this user name already exists")
+)
+
+// SSO is the OpenID Connect provider; nil disables single sign-on.
+var SSO *oidc.Provider
+
+// BeginSSO starts a sign-in at the provider. It returns the URL to send the
+// user to and a state token to hand back to CompleteSSO. A non-empty
+// linkUserID links the identity to that signed-in user instead.
+func BeginSSO(ctx context.Context, linkUserID string) (authURL, stateToken string, err error) {
+	if SSO == nil {
+		return "", "", ErrSSODisabled
+	}
+	var s auth.OIDCState
+	for _, v := range []*string{&s.State, &s.Nonce, &s.Verifier} {
+		if *v, err = oidc.RandomString(); err != nil {
+			return "", "", err
+		}
+	}
+	s.LinkUserID = linkUserID
+	if stateToken, err = auth.GenerateOIDCStateToken(s); err != nil {
+		return "", "", err
+	}
+	return SSO.AuthCodeURL(s.State, s.Nonce, s.Verifier), stateToken, nil
+}
+
+// CompleteSSO handles the provider's redirect: it checks state against the
+// state token, redeems code, verifies the ID token and signs in the linked
+// user, provisioning one if allowed. Like Login it returns
+// ErrTwoFactorRequired and a challenge token for users with 2FA.
+func CompleteSSO(ctx context.Context, stateToken, state, code string) (model.User, string, error) {
+	if SSO == nil {
+		return model.User{}, "", ErrSSODisabled
+	}
+	s, err := auth.ParseOIDCStateToken(stateToken)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(s.State), []byte(state)) != 1 {
+		metrics.LoginAttempts.WithLabelValues("failure").Inc()
+		return model.User{}, "", fmt.Errorf("%w: state mismatch", ErrSSOFailed)
+	}
+	rawIDToken, err := SSO.Exchange(ctx, code, s.Verifier)
+	if err != nil {
+		metrics.LoginAttempts.WithLabelValues("failure").Inc()
+		return model.User{}, "", fmt.Errorf("%w: %v", ErrSSOFailed, err)
+	}
+	claims, err := SSO.Verify(ctx, rawIDToken, s.Nonce)
+	if err != nil {
+		metrics.LoginAttempts.WithLabelValues("failure").Inc()
+		return model.User{}, "", fmt.Errorf("%w: %v", ErrSSOFailed, err)
+	}
+	user, err := ssoUser(ctx, claims, s.LinkUserID)
+	if err != nil {
+		return model.User{}, "", err
+	}
+	return startSession(ctx, user)
+}
+
+// ssoUser resolves the user an identity signs in as.
+func ssoUser(ctx context.Context, claims oidc.Claims, linkUserID string) (model.User, error) {
+	issuer := SSO.Issuer
+	identity, err := Users.Identity(ctx, issuer, claims.Subject)
+	switch {
+	case err == nil:
+		if linkUserID != "" && identity.UserId != linkUserID {
+			return model.User{}, ErrIdentityLinked
+		}
+		return Users.ByID(ctx, identity.UserId)
+	case !errors.Is(err, store.ErrNotFound):
+		return model.User{}, err
+	}
+
+	var user model.User
+	switch {
+	case linkUserID != "":
+		if user, err = Users.ByID(ctx, linkUserID); err != nil {
+			return model.User{}, err
+		}
+	case !SSO.Config().AutoProvision:
+		return model.User{}, ErrNoAccount
+	default:
+		if user, err = provisionUser(ctx, claims); err != nil {
+			return model.User{}, err
+		}
+	}
+	err = Users.LinkIdentity(ctx, model.ExternalIdentity{
+		Issuer:    issuer,
+		Subject:   claims.Subject,
+		UserId:    user.UserId,
+		Email:     claims.Email,
+		CreatedAt: time.Now().UTC(),
+	})
+	if errors.Is(err, store.ErrDuplicate) {
+		// A concurrent sign-in linked it first.
+		return model.User{}, ErrIdentityLinked
+	}
+	return user, err
+}
+
+// provisionUser creates an account for a first-time identity. It has a
+// random password, so it can only sign in through the provider until the
+// user resets it.
+func provisionUser(ctx context.Context, claims oidc.Claims) (model.User, error) {
+	name := claims.String(SSO.Config().UsernameClaim)
+	if name == "" {
+		name = claims.Email
+	}
+	if name == "" {
+		name = claims.Subject
+	}
+	name = strings.TrimSpace(name)
+	if _, err := Users.ByName(ctx, name); err == nil {
+		return model.User{}, ErrAccountExists
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return model.User{}, err
+	}
+	password, err := oidc.RandomString()
+	if err != nil {
+		return model.User{}, err
+	}
+	// The policy is skipped: nobody will ever type this password.
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return model.User{}, err
+	}
+	user := model.User{
+		UserId:   uuid.New().String(),
+		UserName: name,
+		Password: string(hashed),
+		Tier:     auth.DefaultTier,
+	}
+	if err := Users.Create(ctx, user); err != nil {
+		return model.User{}, err
+	}
+	return user, nil
+}
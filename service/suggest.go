@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"expense-tracker/classify"
+	"expense-tracker/model"
+	"sync"
+	"time"
+)
+
+const (
+	// SuggestionThreshold is the confidence a suggestion needs to be used
+	// for an expense created without a category.
+	SuggestionThreshold = 0.6
+	// minSuggestionExamples and minSuggestionLabels are how much history a
+	// model needs before its suggestions are used: with a single category
+	// every suggestion is certain, and with a handful of expenses it is
+	// mostly chance.
+	minSuggestionExamples = 5
+	minSuggestionLabels   = 2
+	// maxSuggestionModels bounds how many users' models are kept in memory;
+	// a dropped model is retrained from the store when next needed.
+	maxSuggestionModels = 10000
+	// suggestionBatchSize is how many expenses are read at a time to train.
+	suggestionBatchSize = 500
+	// suggestionModelTTL is how long a model is used before it is retrained
+	// from the store. Models live in each process and only follow the
+	// expenses written through it, so this bounds how long changes made
+	// through another replica go unseen.
+	suggestionModelTTL = 15 * time.Minute
+)
+
+// CategorySuggestion is a category with the confidence, between 0 and 1,
+// that it fits a description.
+type CategorySuggestion struct {
+	Category   string  `json:"category" example:"coffee"`
+	Confidence float64 `json:"confidence" example:"0.82"`
+}
+
+// suggestionModel is one user's classifier, trained from their personal
+// expenses and kept up to date as they change.
+type suggestionModel struct {
+	mu      sync.Mutex
+	nb      *classify.NaiveBayes
+	trained time.Time
+}
+
+func (m *suggestionModel) fresh() bool {
+	return m != nil && time.Since(m.trained) < suggestionModelTTL
+}
+
+// suggestionModels holds the trained models by user ID.
+var suggestionModels = struct {
+	sync.Mutex
+	byUser map[string]*suggestionModel
+}{byUser: map[string]*suggestionModel{}}
+
+// learnable reports whether an expense should teach its owner's model:
+// household expenses are not part of the owner's own history, and the
+// placeholder category of imports is not a category anyone chose.
+func learnable(e model.Expense) bool {
+	return e.Household_id == "" && e.Category != "" && e.Category != ImportCategory
+}
+
+// suggestionModelFor returns userID's model, training it from the store the
+// first time and again once it is older than suggestionModelTTL. An expense
+// written while a model trains may be counted twice or missed; suggestions
+// are only hints, so that is tolerated.
+func suggestionModelFor(ctx context.Context, userID string) (*suggestionModel, error) {
+	suggestionModels.Lock()
+	m := suggestionModels.byUser[userID]
+	suggestionModels.Unlock()
+	if m.fresh() {
+		return m, nil
+	}
+
+	trained := time.Now()
+	nb := classify.NewNaiveBayes()
+	err := Expenses.Stream(ctx, ExpenseFilter{UserID: userID}, suggestionBatchSize, func(e model.Expense) error {
+		if learnable(e) {
+			nb.Add(e.Description, e.Category)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	suggestionModels.Lock()
+	defer suggestionModels.Unlock()
+	if m := suggestionModels.byUser[userID]; m.fresh() {
+		return m, nil
+	}
+	if _, ok := suggestionModels.byUser[userID]; !ok && len(suggestionModels.byUser) >= maxSuggestionModels {
+		for id := range suggestionModels.byUser {
+			delete(suggestionModels.byUser, id)
+			break
+		}
+	}
+	m = &suggestionModel{nb: nb, trained: trained}
+	suggestionModels.byUser[userID] = m
+	return m, nil
+}
+
+// learn updates the owner's model, if it is trained, when an expense is
+// stored; forget when one is deleted. An update is a forget and a learn.
+func learn(e model.Expense)  { updateSuggestions(e, (*classify.NaiveBayes).Add) }
+func forget(e model.Expense) { updateSuggestions(e, (*classify.NaiveBayes).Remove) }
+
+func updateSuggestions(e model.Expense, update func(nb *classify.NaiveBayes, text, label string)) {
+	if !learnable(e) {
+		return
+	}
+	suggestionModels.Lock()
+	m := suggestionModels.byUser[e.User_id]
+	suggestionModels.Unlock()
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	update(m.nb, e.Description, e.Category)
+	m.mu.Unlock()
+}
+
+// SuggestCategory ranks the categories userID has used by how well they fit
+// description, judged from the descriptions of their personal expenses, and
+// returns up to limit of them. It returns none when the description shares
+// no word with that history.
+func SuggestCategory(ctx context.Context, userID, description string, limit int) ([]CategorySuggestion, error) {
+	m, err := suggestionModelFor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	predictions := m.nb.Predict(description)
+	m.mu.Unlock()
+
+	suggestions := []CategorySuggestion{}
+	for _, p := range predictions {
+		if len(suggestions) == limit {
+			break
+		}
+		suggestions = append(suggestions, CategorySuggestion{Category: p.Label, Confidence: roundTo(p.Confidence, 4)})
+	}
+	return suggestions, nil
+}
+
+// suggestCategory fills in the category of an expense about to be created
+// without one when userID has enough history and the best suggestion is
+// confident enough.
+func suggestCategory(ctx context.Context, userID string, in *ExpenseInput) error {
+	m, err := suggestionModelFor(ctx, userID)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	enough := m.nb.Examples() >= minSuggestionExamples && m.nb.Labels() >= minSuggestionLabels
+	m.mu.Unlock()
+	if !enough {
+		return nil
+	}
+	suggestions, err := SuggestCategory(ctx, userID, in.Description, 1)
+	if err != nil {
+		return err
+	}
+	if len(suggestions) > 0 && suggestions[0].Confidence >= SuggestionThreshold {
+		in.Category = suggestions[0].Category
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"expense-tracker/model"
+	"expense-tracker/validation"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TransferInput is the caller-supplied part of a transfer. Amount leaves
+// the source account in its currency; ToAmount is what arrives when the
+// destination uses another currency, and defaults to Amount converted with
+// the configured exchange rates.
+type TransferInput struct {
+	FromAccountID string  `json:"from_account_id" validate:"required"`
+	ToAccountID   string  `json:"to_account_id" validate:"required,nefield=FromAccountID"`
+	Amount        float64 `json:"amount" validate:"gt=0,lte=1000000000" example:"200"`
+	ToAmount      float64 `json:"to_amount,omitempty" validate:"gte=0,lte=1000000000" example:"185.2"`
+	Description   string  `json:"description" validate:"max=256" example:"Savings"`
+	// TimeStamp defaults to now.
+	TimeStamp time.Time `json:"timestamp" validate:"notfarfuture"`
+}
+
+// Validate normalizes the input and checks it, returning validation.Errors
+// listing each failing field.
+func (in *TransferInput) Validate() error {
+	if in.TimeStamp.IsZero() {
+		in.TimeStamp = time.Now()
+	}
+	in.TimeStamp = in.TimeStamp.UTC()
+	return validation.Struct(in)
+}
+
+// transferAccount loads one end of a transfer, reporting an unusable
+// account against field.
+func transferAccount(ctx context.Context, userID, id, field string) (model.Account, error) {
+	account, err := accountFor(ctx, userID, id, RoleEditor)
+	if errors.Is(err, ErrNotFound) {
+		return model.Account{}, validation.Errors{{Field: field, Reason: "does not exist"}}
+	}
+	return account, err
+}
+
+// CreateTransfer moves money between two accounts userID may edit. It is
+// recorded on both accounts but is neither income nor an expense.
+func CreateTransfer(ctx context.Context, userID string, in TransferInput) (model.Transfer, error) {
+	if err := in.Validate(); err != nil {
+		return model.Transfer{}, err
+	}
+	from, err := transferAccount(ctx, userID, in.FromAccountID, "from_account_id")
+	if err != nil {
+		return model.Transfer{}, err
+	}
+	to, err := transferAccount(ctx, userID, in.ToAccountID, "to_account_id")
+	if err != nil {
+		return model.Transfer{}, err
+	}
+	if roundTo(in.Amount, validation.MinorUnits(from.Currency)) != in.Amount {
+		return model.Transfer{}, validation.Errors{{Field: "amount", Reason: "has more decimal places than the currency allows"}}
+	}
+	toAmount := in.ToAmount
+	switch {
+	case from.Currency == to.Currency:
+		toAmount = in.Amount
+	case toAmount == 0:
+		converted, err := Convert(in.Amount, from.Currency, to.Currency)
+		if err != nil {
+			return model.Transfer{}, err
+		}
+		toAmount = roundTo(converted, validation.MinorUnits(to.Currency))
+	}
+
+	transfer := model.Transfer{
+		Id:            uuid.New().String(),
+		UserId:        userID,
+		FromAccountId: from.Id,
+		ToAccountId:   to.Id,
+		Amount:        in.Amount,
+		ToAmount:      toAmount,
+		Description:   in.Description,
+		TimeStamp:     in.TimeStamp,
+	}
+	if err := Transfers.Create(ctx, transfer); err != nil {
+		return model.Transfer{}, err
+	}
+	return transfer, nil
+}
+
+// ListTransfers returns the transfers into or out of an account, oldest first.
+func ListTransfers(ctx context.Context, userID, accountID string) ([]model.Transfer, error) {
+	if _, err := accountFor(ctx, userID, accountID, RoleViewer); err != nil {
+		return nil, err
+	}
+	return Transfers.List(ctx, accountID)
+}
+
+// DeleteTransfer removes a transfer, which needs edit access to both
+// accounts. Deleting a missing transfer is not an error.
+func DeleteTransfer(ctx context.Context, userID, id string) error {
+	transfer, err := Transfers.Get(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, accountID := range []string{transfer.FromAccountId, transfer.ToAccountId} {
+		if _, err := accountFor(ctx, userID, accountID, RoleEditor); err != nil {
+			return err
+		}
+	}
+	return Transfers.Delete(ctx, id)
+}
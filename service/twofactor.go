@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"expense-tracker/auth"
+	"expense-tracker/model"
+	"expense-tracker/postgresql"
+	"expense-tracker/store"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TOTPIssuer names the service in authenticator apps.
+const TOTPIssuer = "Expense Tracker"
+
+// RecoveryCodeCount is how many recovery codes are issued on enrolment.
+const RecoveryCodeCount = 10
+
+var (
+	// ErrTwoFactorRequired is returned by Login, together with a challenge
+	// token in place of the session token, for users with two-factor
+	// authentication enabled. CompleteLogin exchanges it for a session.
+	ErrTwoFactorRequired = errors.New("two-factor authentication required")
+	// ErrTwoFactorEnabled is returned when enrolling twice.
+	ErrTwoFactorEnabled = errors.New("two-factor authentication already enabled")
+	// ErrTwoFactorNotEnabled is returned when confirming or disabling
+	// without an enrolment.
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication not enabled")
+	// ErrInvalidCode is returned for wrong, reused and expired codes.
+	ErrInvalidCode = errors.New("invalid authentication code")
+)
+
+// TwoFactors is the two-factor store; tests swap in the in-memory one.
+var TwoFactors store.TwoFactorStore = postgresql.TwoFactorStore{}
+
+// TwoFactorStatus reports whether a user has two-factor authentication on.
+type TwoFactorStatus struct {
+	Enabled       bool `json:"enabled"`
+	RecoveryCodes int  `json:"recovery_codes_remaining"`
+}
+
+// requireAdmin returns ErrForbidden unless userID is one of the configured
+// admin users.
+func requireAdmin(ctx context.Context, userID string) error {
+	user, err := Users.ByID(ctx, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrForbidden
+	}
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(conf.AdminUsers, user.UserName) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// twoFactorEnabled returns the user's confirmed enrolment, if any.
+func twoFactorEnabled(ctx context.Context, userID string) (model.TwoFactor, bool, error) {
+	tf, err := TwoFactors.Get(ctx, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return model.TwoFactor{}, false, nil
+	}
+	if err != nil {
+		return model.TwoFactor{}, false, err
+	}
+	return tf, tf.EnabledAt != nil, nil
+}
+
+// GetTwoFactorStatus returns the caller's two-factor status.
+func GetTwoFactorStatus(ctx context.Context, userID string) (TwoFactorStatus, error) {
+	_, enabled, err := twoFactorEnabled(ctx, userID)
+	if err != nil || !enabled {
+		return TwoFactorStatus{}, err
+	}
+	n, err := TwoFactors.RecoveryCodes(ctx, userID)
+	if err != nil {
+		return TwoFactorStatus{}, err
+	}
+	return TwoFactorStatus{Enabled: true, RecoveryCodes: n}, nil
+}
+
+// EnrollTwoFactor starts enrolment with a new TOTP secret, returned along
+// with its otpauth:// URI. Enrolment takes effect once ConfirmTwoFactor
+// receives a code generated from the secret; enrolling again before that
+// replaces the secret.
+func EnrollTwoFactor(ctx context.Context, userID string) (secret, uri string, err error) {
+	user, err := Users.ByID(ctx, userID)
+	if err != nil {
+		return "", "", err
+	}
+	if _, enabled, err := twoFactorEnabled(ctx, userID); err != nil {
+		return "", "", err
+	} else if enabled {
+		return "", "", ErrTwoFactorEnabled
+	}
+	if secret, err = auth.NewTOTPSecret(); err != nil {
+		return "", "", err
+	}
+	if err := TwoFactors.Save(ctx, model.TwoFactor{UserId: userID, Secret: secret}); err != nil {
+		return "", "", err
+	}
+	return secret, auth.TOTPURI(TOTPIssuer, user.UserName, secret), nil
+}
+
+// ConfirmTwoFactor enables two-factor authentication once the user proves
+// their authenticator works, and returns the recovery codes. They are shown
+// only this once.
+func ConfirmTwoFactor(ctx context.Context, userID, code string) ([]string, error) {
+	tf, err := TwoFactors.Get(ctx, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tf.EnabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+	step, ok := auth.VerifyTOTP(tf.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+	now := time.Now().UTC()
+	tf.EnabledAt, tf.LastStep = &now, step
+	if err := TwoFactors.Save(ctx, tf); err != nil {
+		return nil, err
+	}
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		if codes[i], err = recoveryCode(); err != nil {
+			return nil, err
+		}
+		hashes[i] = hashToken(normalizeRecoveryCode(codes[i]))
+	}
+	if err := TwoFactors.SetRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTwoFactor turns two-factor authentication off. It asks for both the
+// password and a current code, so a stolen session alone cannot do it.
+func DisableTwoFactor(ctx context.Context, userID, password, code string) error {
+	user, err := Users.ByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return ErrInvalidPassword
+	}
+	tf, enabled, err := twoFactorEnabled(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return ErrTwoFactorNotEnabled
+	}
+	if err := verifySecondFactor(ctx, tf, code); err != nil {
+		return err
+	}
+	return TwoFactors.Delete(ctx, userID)
+}
+
+// ResetTwoFactor lets an admin turn off a user's two-factor authentication,
+// for example after they lost both their device and recovery codes.
+func ResetTwoFactor(ctx context.Context, adminID, userID string) error {
+	if err := requireAdmin(ctx, adminID); err != nil {
+		return err
+	}
+	if _, err := Users.ByID(ctx, userID); err != nil {
+		return err
+	}
+	return TwoFactors.Delete(ctx, userID)
+}
+
+// CompleteLogin exchanges a challenge token from Login and a TOTP or
+// recovery code for a session token.
+func CompleteLogin(ctx context.Context, challengeToken, code, clientIP string) (model.User, string, error) {
+	if challengeToken == "" || code == "" {
+		return model.User{}, "", ErrInvalidArgument
+	}
+	userID, version, err := auth.ParseChallengeToken(challengeToken)
+	if err != nil {
+		return model.User{}, "", ErrInvalidCode
+	}
+	user, err := Users.ByID(ctx, userID)
+	if errors.Is(err, store.ErrNotFound) {
+		return model.User{}, "", ErrInvalidCode
+	}
+	if err != nil {
+		return model.User{}, "", err
+	}
+	if user.SessionVersion != version {
+		return model.User{}, "", ErrInvalidCode
+	}
+	// Codes count against the same budget as passwords, so a stolen
+	// password does not buy unlimited guesses at the second factor.
+	if err := checkThrottle(ctx, user.UserName); err != nil {
+		return model.User{}, "", err
+	}
+	tf, enabled, err := twoFactorEnabled(ctx, userID)
+	if err != nil {
+		return model.User{}, "", err
+	}
+	if enabled {
+		if err := verifySecondFactor(ctx, tf, code); errors.Is(err, ErrInvalidCode) {
+			if err := recordFailure(ctx, user.UserName, user.UserId, clientIP, FailureBadCode); err != nil {
+				return model.User{}, "", err
+			}
+			return model.User{}, "", ErrInvalidCode
+		} else if err != nil {
+			return model.User{}, "", err
+		}
+	}
+	clearFailures(ctx, user.UserName)
+	return issueSession(user)
+}
+
+// verifySecondFactor accepts an unused TOTP code or recovery code.
+func verifySecondFactor(ctx context.Context, tf model.TwoFactor, code string) error {
+	if step, ok := auth.VerifyTOTP(tf.Secret, code, time.Now()); ok {
+		err := TwoFactors.UseStep(ctx, tf.UserId, step)
+		if errors.Is(err, store.ErrDuplicate) {
+			return ErrInvalidCode
+		}
+		return err
+	}
+	err := TwoFactors.UseRecoveryCode(ctx, tf.UserId, hashToken(normalizeRecoveryCode(code)))
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrInvalidCode
+	}
+	return err
+}
+
+// recoveryCode returns 80 random bits as four dash-separated groups of four
+// base32 characters.
+func recoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	s := base32.StdEncoding.EncodeToString(b)
+	return s[0:4] + "-" + s[4:8] + "-" + s[8:12] + "-" + s[12:16], nil
+}
+
+// normalizeRecoveryCode lets users type codes in any case, with or without
+// the dashes.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
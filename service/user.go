@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"expense-tracker/auth"
+	"expense-tracker/metrics"
+	"expense-tracker/model"
+	"expense-tracker/store"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidPassword is returned when a password given to confirm an
+// account change does not match.
+var ErrInvalidPassword = errors.New("invalid password")
+
+// CreateUser stores a new user with a bcrypt-hashed password, which must
+// satisfy the password policy.
+func CreateUser(ctx context.Context, userName, password string) (model.User, error) {
+	if userName == "" || password == "" {
+		return model.User{}, ErrInvalidArgument
+	}
+	hashed, err := hashPassword(password)
+	if err != nil {
+		return model.User{}, err
+	}
+	user := model.User{
+		UserId:   uuid.New().String(),
+		UserName: userName,
+		Password: string(hashed),
+		Tier:     auth.DefaultTier,
+	}
+	if err := Users.Create(ctx, user); err != nil {
+		return model.User{}, err
+	}
+	return user, nil
+}
+
+// SignUp creates a user and issues a token for it.
+func SignUp(ctx context.Context, userName, password string) (model.User, string, error) {
+	user, err := CreateUser(ctx, userName, password)
+	if err != nil {
+		return model.User{}, "", err
+	}
+	token, err := auth.GenerateToken(user.UserId, user.Tier, user.SessionVersion)
+	if err != nil {
+		return model.User{}, "", err
+	}
+	return user, token, nil
+}
+
+// Login checks the credentials and issues a token. For users with two-factor
+// authentication it returns ErrTwoFactorRequired and a challenge token.
+// Unknown names and wrong passwords both return ErrInvalidCredentials after
+// the same bcrypt work; repeated failures for a name return a
+// *LockoutError. clientIP is recorded with failed attempts.
+func Login(ctx context.Context, userName, password, clientIP string) (model.User, string, error) {
+	if userName == "" || password == "" {
+		return model.User{}, "", ErrInvalidArgument
+	}
+	if err := checkThrottle(ctx, userName); err != nil {
+		return model.User{}, "", err
+	}
+	user, err := Users.ByName(ctx, userName)
+	if errors.Is(err, store.ErrNotFound) {
+		equalizeTiming(password)
+		if err := recordFailure(ctx, userName, "", clientIP, FailureUnknownUser); err != nil {
+			return model.User{}, "", err
+		}
+		return model.User{}, "", ErrInvalidCredentials
+	}
+	if err != nil {
+		return model.User{}, "", err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		if err := recordFailure(ctx, userName, user.UserId, clientIP, FailureBadPassword); err != nil {
+			return model.User{}, "", err
+		}
+		return model.User{}, "", ErrInvalidCredentials
+	}
+	user, token, err := startSession(ctx, user)
+	if err == nil {
+		// With 2FA the failures are cleared once the code is accepted.
+		clearFailures(ctx, userName)
+	}
+	return user, token, err
+}
+
+// startSession finishes a login whose first factor has been checked: it
+// issues a session token, or a challenge token and ErrTwoFactorRequired if
+// the user has two-factor authentication on.
+func startSession(ctx context.Context, user model.User) (model.User, string, error) {
+	if _, enabled, err := twoFactorEnabled(ctx, user.UserId); err != nil {
+		return model.User{}, "", err
+	} else if enabled {
+		challenge, err := auth.GenerateChallengeToken(user.UserId, user.SessionVersion)
+		if err != nil {
+			return model.User{}, "", err
+		}
+		return user, challenge, ErrTwoFactorRequired
+	}
+	return issueSession(user)
+}
+
+// issueSession issues the session token that completes a login.
+func issueSession(user model.User) (model.User, string, error) {
+	token, err := auth.GenerateToken(user.UserId, user.Tier, user.SessionVersion)
+	if err != nil {
+		return model.User{}, "", err
+	}
+	metrics.LoginAttempts.WithLabelValues("success").Inc()
+	return user, token, nil
+}
+
+// UsersByID loads several users in one query, keyed by user ID.
+func UsersByID(ctx context.Context, ids []string) (map[string]model.User, error) {
+	return Users.ByIDs(ctx, ids)
+}
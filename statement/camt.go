@@ -0,0 +1,174 @@
+package statement
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// camtDocument is the part of an ISO 20022 camt.053 bank-to-customer
+// statement that is read. Elements are matched by local name, so every
+// version of the schema (001.02 to 001.08 and later) parses the same way.
+type camtDocument struct {
+	Statements []struct {
+		Account struct {
+			IBAN     string `xml:"Id>IBAN"`
+			Other    string `xml:"Id>Othr>Id"`
+			Currency string `xml:"Ccy"`
+		} `xml:"Acct"`
+		Entries []camtEntry `xml:"Ntry"`
+	} `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtEntry struct {
+	Amount struct {
+		Value    string `xml:",chardata"`
+		Currency string `xml:"Ccy,attr"`
+	} `xml:"Amt"`
+	CreditDebit string `xml:"CdtDbtInd"`
+	// Status is a plain code up to version 001.08 and nested in Cd after.
+	Status struct {
+		Value string `xml:",chardata"`
+		Code  string `xml:"Cd"`
+	} `xml:"Sts"`
+	BookedDate string `xml:"BookgDt>Dt"`
+	BookedTime string `xml:"BookgDt>DtTm"`
+	ValueDate  string `xml:"ValDt>Dt"`
+	ServicerID string `xml:"AcctSvcrRef"`
+	EntryRef   string `xml:"NtryRef"`
+	Info       string `xml:"AddtlNtryInf"`
+	Details    []struct {
+		ServicerID string   `xml:"Refs>AcctSvcrRef"`
+		EndToEndID string   `xml:"Refs>EndToEndId"`
+		TxID       string   `xml:"Refs>TxId"`
+		Creditor   string   `xml:"RltdPties>Cdtr>Nm"`
+		CreditorV8 string   `xml:"RltdPties>Cdtr>Pty>Nm"`
+		Debtor     string   `xml:"RltdPties>Dbtr>Nm"`
+		DebtorV8   string   `xml:"RltdPties>Dbtr>Pty>Nm"`
+		Remittance []string `xml:"RmtInf>Ustrd"`
+		Info       string   `xml:"AddtlTxInf"`
+	} `xml:"NtryDtls>TxDtls"`
+}
+
+// parseCAMT reads the booked entries of a camt.053 statement. Each entry
+// is one transaction, even when it batches several payments; pending
+// entries are rejected since their amount and reference may still change.
+func parseCAMT(data []byte) (Statement, error) {
+	var doc camtDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return Statement{}, fmt.Errorf("camt.053: %w", err)
+	}
+	s := Statement{Format: CAMT053}
+	index := 0
+	for _, stmt := range doc.Statements {
+		account := stmt.Account.IBAN
+		if account == "" {
+			account = stmt.Account.Other
+		}
+		for _, e := range stmt.Entries {
+			index++
+			s.addCAMT(index, account, stmt.Account.Currency, e)
+		}
+	}
+	return s, nil
+}
+
+func (s *Statement) addCAMT(index int, account, currency string, e camtEntry) {
+	ref := e.ServicerID
+	for _, d := range e.Details {
+		if ref != "" {
+			break
+		}
+		switch {
+		case d.ServicerID != "":
+			ref = d.ServicerID
+		case d.TxID != "":
+			ref = d.TxID
+		case d.EndToEndID != "" && d.EndToEndID != "NOTPROVIDED":
+			ref = d.EndToEndID
+		}
+	}
+	if ref == "" {
+		ref = e.EntryRef
+	}
+	if ref == "" {
+		s.reject(index, "", "no bank reference (AcctSvcrRef, TxId, EndToEndId or NtryRef)")
+		return
+	}
+	id := "camt:" + account + ":" + ref
+
+	status := firstNonEmpty(e.Status.Code, e.Status.Value)
+	if status != "" && status != "BOOK" {
+		s.reject(index, id, "entry status is %s, not booked", status)
+		return
+	}
+	amount, err := parseAmount(e.Amount.Value)
+	if err != nil || amount <= 0 {
+		s.reject(index, id, "invalid amount %q", e.Amount.Value)
+		return
+	}
+	switch e.CreditDebit {
+	case "DBIT":
+		amount = -amount
+	case "CRDT":
+	default:
+		s.reject(index, id, "invalid CdtDbtInd %q", e.CreditDebit)
+		return
+	}
+	date, err := camtDate(e)
+	if err != nil {
+		s.reject(index, id, "%v", err)
+		return
+	}
+	if e.Amount.Currency != "" {
+		currency = e.Amount.Currency
+	}
+
+	txn := Transaction{ID: id, Date: date, Amount: amount, Currency: strings.ToUpper(currency), Memo: e.Info}
+	if len(e.Details) > 0 {
+		d := e.Details[0]
+		// The payee is the other party: the creditor of a debit, the
+		// debtor of a credit.
+		txn.Payee = firstNonEmpty(d.Creditor, d.CreditorV8)
+		if amount > 0 {
+			txn.Payee = firstNonEmpty(d.Debtor, d.DebtorV8)
+		}
+		if memo := strings.Join(d.Remittance, " "); memo != "" {
+			txn.Memo = memo
+		} else if txn.Memo == "" {
+			txn.Memo = d.Info
+		}
+	}
+	s.Transactions = append(s.Transactions, txn)
+}
+
+// camtDate is the booking date of an entry, falling back to its value date.
+func camtDate(e camtEntry) (time.Time, error) {
+	if e.BookedTime != "" {
+		t, err := time.Parse(time.RFC3339, e.BookedTime)
+		if err != nil {
+			// ISO 20022 allows local times without an offset.
+			t, err = time.Parse("2006-01-02T15:04:05", e.BookedTime)
+		}
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid booking time %q", e.BookedTime)
+		}
+		return t.UTC(), nil
+	}
+	date := firstNonEmpty(e.BookedDate, e.ValueDate)
+	t, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid booking date %q", date)
+	}
+	return t, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,140 @@
+package statement
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var xmlEntities = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'", "&nbsp;", " ", "&amp;", "&")
+
+// parseOFX reads OFX 1.x (SGML, where leaf elements are not closed) and 2.x
+// (XML) alike by treating every element followed by text as a leaf. QFX is
+// OFX with extra Intuit elements, which are ignored.
+func parseOFX(data []byte) (Statement, error) {
+	start := bytes.Index(bytes.ToUpper(data), []byte("<OFX>"))
+	if start < 0 {
+		return Statement{}, errors.New("ofx: no <OFX> element")
+	}
+	s := Statement{Format: OFX}
+	var (
+		account, currency string
+		txn               map[string]string
+		index             int
+	)
+	body := data[start:]
+	for len(body) > 0 {
+		open := bytes.IndexByte(body, '<')
+		if open < 0 {
+			break
+		}
+		end := bytes.IndexByte(body[open:], '>')
+		if end < 0 {
+			return Statement{}, errors.New("ofx: unterminated element")
+		}
+		tag := strings.ToUpper(strings.TrimSpace(string(body[open+1 : open+end])))
+		body = body[open+end+1:]
+		next := bytes.IndexByte(body, '<')
+		if next < 0 {
+			next = len(body)
+		}
+		value := strings.TrimSpace(xmlEntities.Replace(string(body[:next])))
+
+		switch {
+		case tag == "" || tag[0] == '?' || tag[0] == '!':
+		case tag == "STMTRS" || tag == "CCSTMTRS":
+			account, currency = "", ""
+		case tag == "STMTTRN":
+			txn = map[string]string{}
+		case tag == "/STMTTRN":
+			if txn != nil {
+				index++
+				s.addOFX(index, account, currency, txn)
+				txn = nil
+			}
+		case tag[0] == '/' || value == "":
+			// Closing tags and aggregates carry no value.
+		case txn != nil:
+			if _, seen := txn[tag]; !seen {
+				txn[tag] = value
+			}
+		case tag == "CURDEF":
+			currency = strings.ToUpper(value)
+		case tag == "BANKID":
+			account = value + "/" + account
+		case tag == "ACCTID":
+			account += value
+		}
+	}
+	return s, nil
+}
+
+func (s *Statement) addOFX(index int, account, currency string, txn map[string]string) {
+	fitID := txn["FITID"]
+	if fitID == "" {
+		s.reject(index, "", "no FITID")
+		return
+	}
+	id := "ofx:" + account + ":" + fitID
+	amount, err := parseAmount(txn["TRNAMT"])
+	if err != nil {
+		s.reject(index, id, "invalid TRNAMT %q", txn["TRNAMT"])
+		return
+	}
+	if amount == 0 {
+		s.reject(index, id, "amount is zero")
+		return
+	}
+	posted := txn["DTPOSTED"]
+	if posted == "" {
+		posted = txn["DTUSER"]
+	}
+	date, err := parseOFXDate(posted)
+	if err != nil {
+		s.reject(index, id, "%v", err)
+		return
+	}
+	if cur := txn["CURSYM"]; cur != "" {
+		currency = strings.ToUpper(cur)
+	}
+	s.Transactions = append(s.Transactions, Transaction{
+		ID:       id,
+		Date:     date,
+		Amount:   amount,
+		Currency: currency,
+		Payee:    txn["NAME"],
+		Memo:     txn["MEMO"],
+	})
+}
+
+// parseOFXDate reads YYYYMMDD[HHMMSS[.XXX]][[offset[:TZ]]], where the
+// offset is in hours (e.g. [-5:EST] or [5.30:IST]); without one the time
+// is UTC.
+func parseOFXDate(s string) (time.Time, error) {
+	value, zone, _ := strings.Cut(s, "[")
+	value, _, _ = strings.Cut(value, ".")
+	loc := time.UTC
+	if zone != "" {
+		offset, _, _ := strings.Cut(strings.TrimSuffix(zone, "]"), ":")
+		hours, err := strconv.ParseFloat(offset, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q", s)
+		}
+		loc = time.FixedZone(offset, int(hours*3600))
+	}
+	layout := "20060102150405"
+	switch {
+	case len(value) == 8:
+		layout = "20060102"
+	case len(value) == 12:
+		layout = "200601021504"
+	}
+	t, err := time.ParseInLocation(layout, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return t.UTC(), nil
+}
@@ -0,0 +1,145 @@
+package statement
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseQIF reads the bank, cash and credit card sections of a QIF file.
+// QIF has no transaction IDs, so each transaction's ID hashes its date,
+// amount, payee, memo and check number, numbered when the file holds
+// several identical ones: re-exporting an overlapping period then gives
+// the same IDs.
+func parseQIF(data []byte) (Statement, error) {
+	s := Statement{Format: QIF}
+	var (
+		account   string
+		inAccount bool
+		inTxns    bool
+		fields    = map[byte]string{}
+		index     int
+		seen      = map[string]int{}
+	)
+	for _, line := range lines(data) {
+		if line[0] == '!' {
+			header := strings.ToLower(line)
+			switch {
+			case strings.HasPrefix(header, "!account"):
+				inAccount, inTxns = true, false
+			case strings.HasPrefix(header, "!type:invst"):
+				return Statement{}, errors.New("qif: investment accounts are not supported")
+			case strings.HasPrefix(header, "!type:"):
+				inAccount, inTxns = false, true
+			default:
+				// Option lines and lists (categories, classes) are skipped.
+				inAccount, inTxns = false, false
+			}
+			fields = map[byte]string{}
+			continue
+		}
+		if line == "^" {
+			switch {
+			case inAccount:
+				account = fields['N']
+			case inTxns && len(fields) > 0:
+				index++
+				s.addQIF(index, account, fields, seen)
+			}
+			fields = map[byte]string{}
+			continue
+		}
+		// Split lines (S, E, $) repeat; only the first of each code is kept.
+		if _, ok := fields[line[0]]; !ok {
+			fields[line[0]] = strings.TrimSpace(line[1:])
+		}
+	}
+	return s, nil
+}
+
+func (s *Statement) addQIF(index int, account string, fields map[byte]string, seen map[string]int) {
+	raw := fields['T']
+	if raw == "" {
+		raw = fields['U']
+	}
+	amount, err := parseAmount(raw)
+	if err != nil {
+		s.reject(index, "", "invalid amount %q", raw)
+		return
+	}
+	if amount == 0 {
+		s.reject(index, "", "amount is zero")
+		return
+	}
+	date, err := parseQIFDate(fields['D'])
+	if err != nil {
+		s.reject(index, "", "%v", err)
+		return
+	}
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		account, date.Format(time.DateOnly), strconv.FormatFloat(amount, 'f', -1, 64), fields['P'], fields['M'], fields['N'],
+	}, "\x00")))
+	key := hex.EncodeToString(sum[:12])
+	seen[key]++
+	s.Transactions = append(s.Transactions, Transaction{
+		ID:     fmt.Sprintf("qif:%s:%d", key, seen[key]),
+		Date:   date,
+		Amount: amount,
+		Payee:  fields['P'],
+		Memo:   fields['M'],
+	})
+}
+
+// parseQIFDate reads the date styles QIF exporters write: US month/day/year
+// (with Quicken's ' before years from 2000, e.g. 3/ 1'24), day.month.year,
+// and ISO year-month-day. Two-digit years before 70 are taken as 20xx.
+func parseQIFDate(s string) (time.Time, error) {
+	invalid := fmt.Errorf("invalid date %q", s)
+	clean := strings.ReplaceAll(s, " ", "")
+	if t, err := time.Parse(time.DateOnly, clean); err == nil {
+		return t, nil
+	}
+	millennium := strings.Contains(clean, "'")
+	parts := strings.FieldsFunc(clean, func(r rune) bool { return r == '/' || r == '\'' || r == '.' || r == '-' })
+	if len(parts) != 3 {
+		return time.Time{}, invalid
+	}
+	var n [3]int
+	for i, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil {
+			return time.Time{}, invalid
+		}
+		n[i] = v
+	}
+	month, day, year := n[0], n[1], n[2]
+	if strings.Contains(clean, ".") {
+		day, month = n[0], n[1]
+	}
+	if year < 100 {
+		if millennium || year < 70 {
+			year += 2000
+		} else {
+			year += 1900
+		}
+	}
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if t.Month() != time.Month(month) || t.Day() != day {
+		return time.Time{}, invalid
+	}
+	return t, nil
+}
+
+// parseAmount reads a signed decimal with optional thousands separators.
+// A lone comma followed by two digits is taken as a decimal comma.
+func parseAmount(s string) (float64, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), " ", "")
+	if i := strings.LastIndexByte(s, ','); i >= 0 && !strings.Contains(s, ".") && len(s)-i-1 == 2 {
+		s = s[:i] + "." + s[i+1:]
+	}
+	return strconv.ParseFloat(strings.ReplaceAll(s, ",", ""), 64)
+}
@@ -0,0 +1,127 @@
+// Package statement parses bank statement exports (OFX/QFX, QIF and ISO
+// 20022 CAMT.053) into transactions with stable IDs, so that importing the
+// same statement twice can be detected.
+package statement
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Formats.
+const (
+	OFX     = "ofx"
+	QIF     = "qif"
+	CAMT053 = "camt053"
+)
+
+// ErrUnknownFormat is returned when a file is in none of the formats.
+var ErrUnknownFormat = errors.New("unknown statement format")
+
+// Transaction is one booked statement line. Amount is signed: negative for
+// money leaving the account.
+type Transaction struct {
+	// ID identifies the transaction across exports of the same account: the
+	// bank's ID (FITID, AcctSvcrRef) prefixed with the account, or for QIF,
+	// which has none, a hash of the transaction's contents.
+	ID       string
+	Date     time.Time
+	Amount   float64
+	Currency string
+	// Payee and Memo are kept as the bank wrote them.
+	Payee string
+	Memo  string
+}
+
+// Rejection is a statement line that could not be read. Index counts the
+// transactions in the file from 1.
+type Rejection struct {
+	Index  int    `json:"index"`
+	ID     string `json:"id,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// Statement is the result of parsing a file.
+type Statement struct {
+	Format       string
+	Transactions []Transaction
+	Rejected     []Rejection
+}
+
+func (s *Statement) reject(index int, id, format string, args ...interface{}) {
+	s.Rejected = append(s.Rejected, Rejection{Index: index, ID: id, Reason: fmt.Sprintf(format, args...)})
+}
+
+// Parse reads a statement in format, or in the format detected from its
+// contents when format is empty. Malformed transactions are listed in
+// Rejected; an error means the file as a whole could not be read.
+// Transactions without a currency get defaultCurrency.
+func Parse(r io.Reader, format, defaultCurrency string) (Statement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Statement{}, err
+	}
+	if format == "" {
+		if format = Detect(data); format == "" {
+			return Statement{}, ErrUnknownFormat
+		}
+	}
+	var s Statement
+	switch strings.ToLower(format) {
+	case OFX, "qfx":
+		s, err = parseOFX(data)
+	case QIF:
+		s, err = parseQIF(data)
+	case CAMT053, "camt.053":
+		s, err = parseCAMT(data)
+	default:
+		return Statement{}, fmt.Errorf("%w %q (want ofx, qif or camt053)", ErrUnknownFormat, format)
+	}
+	if err != nil {
+		return Statement{}, err
+	}
+	for i := range s.Transactions {
+		if s.Transactions[i].Currency == "" {
+			s.Transactions[i].Currency = defaultCurrency
+		}
+	}
+	return s, nil
+}
+
+// Detect guesses the format of a statement from its first bytes, returning
+// "" if it is none of them.
+func Detect(data []byte) string {
+	head := data
+	if len(head) > 4096 {
+		head = head[:4096]
+	}
+	head = bytes.TrimLeft(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf")), " \t\r\n")
+	upper := bytes.ToUpper(head)
+	switch {
+	case bytes.HasPrefix(upper, []byte("OFXHEADER")), bytes.Contains(upper, []byte("<OFX>")):
+		return OFX
+	case bytes.HasPrefix(upper, []byte("!TYPE:")), bytes.HasPrefix(upper, []byte("!ACCOUNT")):
+		return QIF
+	case bytes.Contains(head, []byte("camt.053")), bytes.Contains(head, []byte("<BkToCstmrStmt")):
+		return CAMT053
+	}
+	return ""
+}
+
+// lines splits data into trimmed lines, accepting any line ending.
+func lines(data []byte) []string {
+	var out []string
+	sc := bufio.NewScanner(bytes.NewReader(bytes.ReplaceAll(data, []byte("\r"), []byte("\n"))))
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
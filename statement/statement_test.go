@@ -0,0 +1,166 @@
+package statement
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ofxSGML = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+CHARSET:1252
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>USD
+<BANKACCTFROM><BANKID>121000248<ACCTID>4411<ACCTTYPE>CHECKING</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20240301<DTEND>20240331
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240305120000.000[-5:EST]
+<TRNAMT>-42.17
+<FITID>2024030501
+<NAME>CORNER GROCERY &amp; DELI
+<MEMO>POS 0305
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240315
+<TRNAMT>2500.00
+<FITID>2024031501
+<NAME>ACME PAYROLL
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240316
+<TRNAMT>-5.00
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+const ofxXML = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX><CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS>
+<CURDEF>EUR</CURDEF>
+<CCACCTFROM><ACCTID>5500</ACCTID></CCACCTFROM>
+<BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20240402</DTPOSTED><TRNAMT>-12,50</TRNAMT><FITID>A1</FITID>
+<PAYEE><NAME>Cafe</NAME></PAYEE></STMTTRN>
+</BANKTRANLIST>
+</CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1></OFX>`
+
+func TestParse_OFX(t *testing.T) {
+	s, err := Parse(strings.NewReader(ofxSGML), "", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, OFX, s.Format)
+	require.Len(t, s.Transactions, 2)
+	assert.Equal(t, Transaction{
+		ID:       "ofx:121000248/4411:2024030501",
+		Date:     time.Date(2024, 3, 5, 17, 0, 0, 0, time.UTC),
+		Amount:   -42.17,
+		Currency: "USD",
+		Payee:    "CORNER GROCERY & DELI",
+		Memo:     "POS 0305",
+	}, s.Transactions[0])
+	assert.Equal(t, 2500.0, s.Transactions[1].Amount)
+	assert.Equal(t, []Rejection{{Index: 3, Reason: "no FITID"}}, s.Rejected)
+
+	s, err = Parse(strings.NewReader(ofxXML), "qfx", "USD")
+	require.NoError(t, err)
+	require.Len(t, s.Transactions, 1)
+	assert.Equal(t, "ofx:5500:A1", s.Transactions[0].ID)
+	assert.Equal(t, -12.5, s.Transactions[0].Amount)
+	assert.Equal(t, "EUR", s.Transactions[0].Currency)
+	assert.Equal(t, "Cafe", s.Transactions[0].Payee)
+}
+
+func TestParse_QIF(t *testing.T) {
+	in := "!Account\nNChecking\nTBank\n^\n!Type:Bank\n" +
+		"D3/ 1'24\nT-1,234.56\nPLandlord\nMMarch rent\n^\n" +
+		"D03/02/2024\nT-4.50\nPCoffee\n^\n" +
+		"D03/02/2024\nT-4.50\nPCoffee\n^\n" +
+		"D31.12.2023\nT10.00\nPRefund\n^\n" +
+		"D13/45/2024\nT-1\n^\n"
+	s, err := Parse(strings.NewReader(in), "", "GBP")
+	require.NoError(t, err)
+	assert.Equal(t, QIF, s.Format)
+	require.Len(t, s.Transactions, 4)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), s.Transactions[0].Date)
+	assert.Equal(t, -1234.56, s.Transactions[0].Amount)
+	assert.Equal(t, "March rent", s.Transactions[0].Memo)
+	assert.Equal(t, "GBP", s.Transactions[0].Currency)
+	assert.Equal(t, time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC), s.Transactions[3].Date)
+
+	coffee1, coffee2 := s.Transactions[1].ID, s.Transactions[2].ID
+	assert.NotEqual(t, coffee1, coffee2, "identical lines are numbered")
+	assert.True(t, strings.HasSuffix(coffee2, ":2"))
+	require.Len(t, s.Rejected, 1)
+	assert.Equal(t, 5, s.Rejected[0].Index)
+
+	again, err := Parse(strings.NewReader(in), QIF, "GBP")
+	require.NoError(t, err)
+	assert.Equal(t, s.Transactions, again.Transactions, "IDs are stable across imports")
+
+	_, err = Parse(strings.NewReader("!Type:Invst\nD1/1/24\n^\n"), "", "USD")
+	assert.ErrorContains(t, err, "investment")
+}
+
+const camt = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+<BkToCstmrStmt><Stmt>
+<Acct><Id><IBAN>DE89370400440532013000</IBAN></Id><Ccy>EUR</Ccy></Acct>
+<Ntry>
+  <Amt Ccy="EUR">89.90</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts>BOOK</Sts>
+  <BookgDt><Dt>2024-05-02</Dt></BookgDt><AcctSvcrRef>2024050200012</AcctSvcrRef>
+  <NtryDtls><TxDtls>
+    <Refs><EndToEndId>NOTPROVIDED</EndToEndId></Refs>
+    <RltdPties><Cdtr><Nm>Stadtwerke</Nm></Cdtr></RltdPties>
+    <RmtInf><Ustrd>Abschlag Mai</Ustrd><Ustrd>Kd 4711</Ustrd></RmtInf>
+  </TxDtls></NtryDtls>
+</Ntry>
+<Ntry>
+  <Amt Ccy="EUR">3100.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Sts>BOOK</Sts>
+  <BookgDt><Dt>2024-05-03</Dt></BookgDt>
+  <NtryDtls><TxDtls>
+    <Refs><TxId>SAL-0503</TxId></Refs>
+    <RltdPties><Dbtr><Nm>ACME GmbH</Nm></Dbtr><Cdtr><Nm>Me</Nm></Cdtr></RltdPties>
+  </TxDtls></NtryDtls>
+</Ntry>
+<Ntry>
+  <Amt Ccy="EUR">10.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts><Cd>PDNG</Cd></Sts>
+  <BookgDt><Dt>2024-05-04</Dt></BookgDt><AcctSvcrRef>P1</AcctSvcrRef>
+</Ntry>
+</Stmt></BkToCstmrStmt></Document>`
+
+func TestParse_CAMT053(t *testing.T) {
+	s, err := Parse(strings.NewReader(camt), "", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, CAMT053, s.Format)
+	require.Len(t, s.Transactions, 2)
+	assert.Equal(t, Transaction{
+		ID:       "camt:DE89370400440532013000:2024050200012",
+		Date:     time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+		Amount:   -89.9,
+		Currency: "EUR",
+		Payee:    "Stadtwerke",
+		Memo:     "Abschlag Mai Kd 4711",
+	}, s.Transactions[0])
+	assert.Equal(t, "camt:DE89370400440532013000:SAL-0503", s.Transactions[1].ID)
+	assert.Equal(t, "ACME GmbH", s.Transactions[1].Payee, "the payee of a credit is the debtor")
+	require.Len(t, s.Rejected, 1)
+	assert.Equal(t, "entry status is PDNG, not booked", s.Rejected[0].Reason)
+}
+
+func TestParse_UnknownFormat(t *testing.T) {
+	_, err := Parse(strings.NewReader("date,amount\n"), "", "USD")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+	_, err = Parse(strings.NewReader(camt), "mt940", "USD")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
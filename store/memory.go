@@ -0,0 +1,1002 @@
+package store
+
+import (
+	"context"
+	"expense-tracker/model"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryExpenses is an ExpenseStore backed by a map. It is safe for
+// concurrent use and is meant for tests and local experiments.
+type MemoryExpenses struct {
+	mu       sync.RWMutex
+	expenses map[string]model.Expense
+}
+
+// NewMemoryExpenses returns an empty in-memory expense store.
+func NewMemoryExpenses() *MemoryExpenses {
+	return &MemoryExpenses{expenses: map[string]model.Expense{}}
+}
+
+func (m *MemoryExpenses) Create(_ context.Context, expense model.Expense) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.expenses[expense.Id]; ok {
+		return ErrDuplicate
+	}
+	if key := externalKey(expense.External_id, expense.User_id, expense.Household_id); key != "" {
+		for _, e := range m.expenses {
+			if externalKey(e.External_id, e.User_id, e.Household_id) == key {
+				return ErrDuplicate
+			}
+		}
+	}
+	m.expenses[expense.Id] = expense
+	return nil
+}
+
+func (m *MemoryExpenses) Get(_ context.Context, id string) (model.Expense, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	expense, ok := m.expenses[id]
+	if !ok {
+		return model.Expense{}, ErrNotFound
+	}
+	return expense, nil
+}
+
+func (m *MemoryExpenses) Update(_ context.Context, expense model.Expense) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.expenses[expense.Id]; !ok {
+		return ErrNotFound
+	}
+	m.expenses[expense.Id] = expense
+	return nil
+}
+
+func (m *MemoryExpenses) Delete(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.expenses, id)
+	return nil
+}
+
+// match returns the expenses matching f sorted by time, then ID.
+func (m *MemoryExpenses) match(f ExpenseFilter) ([]model.Expense, error) {
+	from, to, err := f.Bounds()
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	var out []model.Expense
+	for _, e := range m.expenses {
+		switch {
+		case f.UserID != "" && e.User_id != f.UserID,
+			e.Household_id != f.HouseholdID,
+			f.Category != "" && e.Category != f.Category,
+			f.Currency != "" && e.Currency != f.Currency,
+			f.AccountID != "" && e.Account_id != f.AccountID,
+			!from.IsZero() && e.TimeStamp.Before(from),
+			!to.IsZero() && e.TimeStamp.After(to):
+			continue
+		}
+		out = append(out, e)
+	}
+	m.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].TimeStamp.Equal(out[j].TimeStamp) {
+			return out[i].TimeStamp.Before(out[j].TimeStamp)
+		}
+		return out[i].Id < out[j].Id
+	})
+	return out, nil
+}
+
+func (m *MemoryExpenses) List(_ context.Context, f ExpenseFilter) ([]model.Expense, error) {
+	all, err := m.match(f)
+	if err != nil {
+		return nil, err
+	}
+	start := min(max(f.Offset, 0), len(all))
+	end := min(start+f.PageSize(), len(all))
+	return all[start:end], nil
+}
+
+func (m *MemoryExpenses) Stream(ctx context.Context, f ExpenseFilter, _ int, fn func(model.Expense) error) error {
+	all, err := m.match(f)
+	if err != nil {
+		return err
+	}
+	for _, e := range all {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemoryExpenses) Summarize(_ context.Context, f ExpenseFilter) (map[string]float64, error) {
+	all, err := m.match(f)
+	if err != nil {
+		return nil, err
+	}
+	summary := map[string]float64{}
+	for _, e := range all {
+		summary[e.Category] += e.Amount
+	}
+	return summary, nil
+}
+
+func (m *MemoryExpenses) SummarizeByUser(_ context.Context, f ExpenseFilter) (map[string]float64, error) {
+	all, err := m.match(f)
+	if err != nil {
+		return nil, err
+	}
+	summary := map[string]float64{}
+	for _, e := range all {
+		summary[e.User_id] += e.Amount
+	}
+	return summary, nil
+}
+
+func (m *MemoryExpenses) ByCategory(_ context.Context, f ExpenseFilter, categories []string, perCategory int) (map[string][]model.Expense, error) {
+	all, err := m.match(f)
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(categories))
+	for _, c := range categories {
+		wanted[c] = true
+	}
+	grouped := make(map[string][]model.Expense, len(categories))
+	for i := len(all) - 1; i >= 0; i-- {
+		e := all[i]
+		if wanted[e.Category] && len(grouped[e.Category]) < perCategory {
+			grouped[e.Category] = append(grouped[e.Category], e)
+		}
+	}
+	return grouped, nil
+}
+
+// MemoryIncomes is an IncomeStore backed by a map. It is safe for
+// concurrent use.
+type MemoryIncomes struct {
+	mu      sync.RWMutex
+	incomes map[string]model.Income
+}
+
+// NewMemoryIncomes returns an empty in-memory income store.
+func NewMemoryIncomes() *MemoryIncomes {
+	return &MemoryIncomes{incomes: map[string]model.Income{}}
+}
+
+func (m *MemoryIncomes) Create(_ context.Context, income model.Income) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.incomes[income.Id]; ok {
+		return ErrDuplicate
+	}
+	if key := externalKey(income.External_id, income.User_id, income.Household_id); key != "" {
+		for _, i := range m.incomes {
+			if externalKey(i.External_id, i.User_id, i.Household_id) == key {
+				return ErrDuplicate
+			}
+		}
+	}
+	m.incomes[income.Id] = income
+	return nil
+}
+
+func (m *MemoryIncomes) Get(_ context.Context, id string) (model.Income, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	income, ok := m.incomes[id]
+	if !ok {
+		return model.Income{}, ErrNotFound
+	}
+	return income, nil
+}
+
+func (m *MemoryIncomes) Update(_ context.Context, income model.Income) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.incomes[income.Id]; !ok {
+		return ErrNotFound
+	}
+	m.incomes[income.Id] = income
+	return nil
+}
+
+func (m *MemoryIncomes) Delete(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.incomes, id)
+	return nil
+}
+
+// match returns the income matching f sorted by time, then ID.
+func (m *MemoryIncomes) match(f ExpenseFilter) ([]model.Income, error) {
+	from, to, err := f.Bounds()
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	var out []model.Income
+	for _, i := range m.incomes {
+		switch {
+		case f.UserID != "" && i.User_id != f.UserID,
+			i.Household_id != f.HouseholdID,
+			f.Category != "" && i.Category != f.Category,
+			f.Currency != "" && i.Currency != f.Currency,
+			f.AccountID != "" && i.Account_id != f.AccountID,
+			!from.IsZero() && i.TimeStamp.Before(from),
+			!to.IsZero() && i.TimeStamp.After(to):
+			continue
+		}
+		out = append(out, i)
+	}
+	m.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].TimeStamp.Equal(out[j].TimeStamp) {
+			return out[i].TimeStamp.Before(out[j].TimeStamp)
+		}
+		return out[i].Id < out[j].Id
+	})
+	return out, nil
+}
+
+func (m *MemoryIncomes) List(_ context.Context, f ExpenseFilter) ([]model.Income, error) {
+	all, err := m.match(f)
+	if err != nil {
+		return nil, err
+	}
+	start := min(max(f.Offset, 0), len(all))
+	end := min(start+f.PageSize(), len(all))
+	return all[start:end], nil
+}
+
+func (m *MemoryIncomes) Stream(ctx context.Context, f ExpenseFilter, _ int, fn func(model.Income) error) error {
+	all, err := m.match(f)
+	if err != nil {
+		return err
+	}
+	for _, i := range all {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemoryIncomes) ExternalIDs(_ context.Context, f ExpenseFilter, ids []string) (map[string]bool, error) {
+	all, err := m.match(f)
+	if err != nil {
+		return nil, err
+	}
+	return externalIDs(ids, len(all), func(i int) string { return all[i].External_id }), nil
+}
+
+// MemoryAccounts is an AccountStore backed by a map. It is safe for
+// concurrent use.
+type MemoryAccounts struct {
+	mu       sync.RWMutex
+	accounts map[string]model.Account
+}
+
+// NewMemoryAccounts returns an empty in-memory account store.
+func NewMemoryAccounts() *MemoryAccounts {
+	return &MemoryAccounts{accounts: map[string]model.Account{}}
+}
+
+func (m *MemoryAccounts) Create(_ context.Context, account model.Account) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.accounts[account.Id]; ok {
+		return ErrDuplicate
+	}
+	m.accounts[account.Id] = account
+	return nil
+}
+
+func (m *MemoryAccounts) Get(_ context.Context, id string) (model.Account, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	account, ok := m.accounts[id]
+	if !ok {
+		return model.Account{}, ErrNotFound
+	}
+	return account, nil
+}
+
+func (m *MemoryAccounts) Update(_ context.Context, account model.Account) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.accounts[account.Id]; !ok {
+		return ErrNotFound
+	}
+	m.accounts[account.Id] = account
+	return nil
+}
+
+func (m *MemoryAccounts) List(_ context.Context, userID, householdID string) ([]model.Account, error) {
+	m.mu.RLock()
+	var out []model.Account
+	for _, a := range m.accounts {
+		if a.HouseholdId == householdID && (householdID != "" || a.UserId == userID) {
+			out = append(out, a)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].Id < out[j].Id
+	})
+	return out, nil
+}
+
+// MemoryTransfers is a TransferStore backed by a map. It is safe for
+// concurrent use.
+type MemoryTransfers struct {
+	mu        sync.RWMutex
+	transfers map[string]model.Transfer
+}
+
+// NewMemoryTransfers returns an empty in-memory transfer store.
+func NewMemoryTransfers() *MemoryTransfers {
+	return &MemoryTransfers{transfers: map[string]model.Transfer{}}
+}
+
+func (m *MemoryTransfers) Create(_ context.Context, transfer model.Transfer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.transfers[transfer.Id]; ok {
+		return ErrDuplicate
+	}
+	m.transfers[transfer.Id] = transfer
+	return nil
+}
+
+func (m *MemoryTransfers) Get(_ context.Context, id string) (model.Transfer, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	transfer, ok := m.transfers[id]
+	if !ok {
+		return model.Transfer{}, ErrNotFound
+	}
+	return transfer, nil
+}
+
+func (m *MemoryTransfers) Delete(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.transfers, id)
+	return nil
+}
+
+func (m *MemoryTransfers) List(_ context.Context, accountID string) ([]model.Transfer, error) {
+	m.mu.RLock()
+	var out []model.Transfer
+	for _, t := range m.transfers {
+		if t.FromAccountId == accountID || t.ToAccountId == accountID {
+			out = append(out, t)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].TimeStamp.Equal(out[j].TimeStamp) {
+			return out[i].TimeStamp.Before(out[j].TimeStamp)
+		}
+		return out[i].Id < out[j].Id
+	})
+	return out, nil
+}
+
+func (m *MemoryExpenses) ExternalIDs(_ context.Context, f ExpenseFilter, ids []string) (map[string]bool, error) {
+	all, err := m.match(f)
+	if err != nil {
+		return nil, err
+	}
+	return externalIDs(ids, len(all), func(i int) string { return all[i].External_id }), nil
+}
+
+// externalKey identifies a bank ID within its ledger, a household's or else
+// the owner's personal one, as the SQL stores' unique indexes do. It is empty
+// for records without a bank ID.
+func externalKey(externalID, userID, householdID string) string {
+	switch {
+	case externalID == "":
+		return ""
+	case householdID != "":
+		return "household\x00" + householdID + "\x00" + externalID
+	default:
+		return "user\x00" + userID + "\x00" + externalID
+	}
+}
+
+// externalIDs returns which of ids are among the n values of externalID.
+func externalIDs(ids []string, n int, externalID func(int) string) map[string]bool {
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	found := map[string]bool{}
+	for i := 0; i < n; i++ {
+		if id := externalID(i); id != "" && wanted[id] {
+			found[id] = true
+		}
+	}
+	return found
+}
+
+// MemoryRules is a RuleStore backed by a map. It is safe for concurrent use.
+type MemoryRules struct {
+	mu    sync.RWMutex
+	rules map[string]model.Rule
+}
+
+// NewMemoryRules returns an empty in-memory rule store.
+func NewMemoryRules() *MemoryRules {
+	return &MemoryRules{rules: map[string]model.Rule{}}
+}
+
+func (m *MemoryRules) Create(_ context.Context, rule model.Rule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.rules[rule.Id]; ok {
+		return ErrDuplicate
+	}
+	m.rules[rule.Id] = rule
+	return nil
+}
+
+func (m *MemoryRules) Get(_ context.Context, id string) (model.Rule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	rule, ok := m.rules[id]
+	if !ok {
+		return model.Rule{}, ErrNotFound
+	}
+	return rule, nil
+}
+
+func (m *MemoryRules) Update(_ context.Context, rule model.Rule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.rules[rule.Id]; !ok {
+		return ErrNotFound
+	}
+	m.rules[rule.Id] = rule
+	return nil
+}
+
+func (m *MemoryRules) Delete(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.rules, id)
+	return nil
+}
+
+func (m *MemoryRules) List(_ context.Context, userID string) ([]model.Rule, error) {
+	m.mu.RLock()
+	var out []model.Rule
+	for _, r := range m.rules {
+		if r.UserId == userID {
+			out = append(out, r)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool {
+		switch {
+		case out[i].Priority != out[j].Priority:
+			return out[i].Priority < out[j].Priority
+		case !out[i].CreatedAt.Equal(out[j].CreatedAt):
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].Id < out[j].Id
+	})
+	return out, nil
+}
+
+// MemoryUsers is a UserStore backed by a map. It is safe for concurrent use.
+type MemoryUsers struct {
+	mu         sync.RWMutex
+	users      map[string]model.User
+	resets     map[string]model.PasswordReset
+	identities map[[2]string]model.ExternalIdentity
+}
+
+// NewMemoryUsers returns an empty in-memory user store.
+func NewMemoryUsers() *MemoryUsers {
+	return &MemoryUsers{
+		users:      map[string]model.User{},
+		resets:     map[string]model.PasswordReset{},
+		identities: map[[2]string]model.ExternalIdentity{},
+	}
+}
+
+func (m *MemoryUsers) Create(_ context.Context, user model.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.users {
+		if u.UserId == user.UserId || u.UserName == user.UserName {
+			return ErrDuplicate
+		}
+	}
+	m.users[user.UserId] = user
+	return nil
+}
+
+func (m *MemoryUsers) ByName(_ context.Context, userName string) (model.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, u := range m.users {
+		if u.UserName == userName {
+			return u, nil
+		}
+	}
+	return model.User{}, ErrNotFound
+}
+
+func (m *MemoryUsers) ByID(_ context.Context, id string) (model.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	user, ok := m.users[id]
+	if !ok {
+		return model.User{}, ErrNotFound
+	}
+	return user, nil
+}
+
+func (m *MemoryUsers) ByIDs(_ context.Context, ids []string) (map[string]model.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	byID := make(map[string]model.User, len(ids))
+	for _, id := range ids {
+		if u, ok := m.users[id]; ok {
+			byID[id] = u
+		}
+	}
+	return byID, nil
+}
+
+func (m *MemoryUsers) Update(_ context.Context, user model.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[user.UserId]; !ok {
+		return ErrNotFound
+	}
+	m.users[user.UserId] = user
+	return nil
+}
+
+func (m *MemoryUsers) CreateReset(_ context.Context, reset model.PasswordReset) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleteResets(reset.UserId)
+	m.resets[reset.TokenHash] = reset
+	return nil
+}
+
+func (m *MemoryUsers) TakeReset(_ context.Context, tokenHash string) (model.PasswordReset, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	reset, ok := m.resets[tokenHash]
+	if !ok {
+		return model.PasswordReset{}, ErrNotFound
+	}
+	delete(m.resets, tokenHash)
+	return reset, nil
+}
+
+func (m *MemoryUsers) DeleteResets(_ context.Context, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleteResets(userID)
+	return nil
+}
+
+func (m *MemoryUsers) Identity(_ context.Context, issuer, subject string) (model.ExternalIdentity, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	identity, ok := m.identities[[2]string{issuer, subject}]
+	if !ok {
+		return model.ExternalIdentity{}, ErrNotFound
+	}
+	return identity, nil
+}
+
+func (m *MemoryUsers) LinkIdentity(_ context.Context, identity model.ExternalIdentity) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := [2]string{identity.Issuer, identity.Subject}
+	if _, ok := m.identities[key]; ok {
+		return ErrDuplicate
+	}
+	if identity.CreatedAt.IsZero() {
+		identity.CreatedAt = time.Now()
+	}
+	m.identities[key] = identity
+	return nil
+}
+
+// deleteResets must be called with mu held.
+func (m *MemoryUsers) deleteResets(userID string) {
+	for hash, r := range m.resets {
+		if r.UserId == userID {
+			delete(m.resets, hash)
+		}
+	}
+}
+
+type memberKey struct{ household, user string }
+
+// MemoryHouseholds is a HouseholdStore backed by maps. It is safe for
+// concurrent use.
+type MemoryHouseholds struct {
+	mu         sync.RWMutex
+	households map[string]model.Household
+	members    map[memberKey]model.HouseholdMember
+	invites    map[string]model.HouseholdInvite
+}
+
+// NewMemoryHouseholds returns an empty in-memory household store.
+func NewMemoryHouseholds() *MemoryHouseholds {
+	return &MemoryHouseholds{
+		households: map[string]model.Household{},
+		members:    map[memberKey]model.HouseholdMember{},
+		invites:    map[string]model.HouseholdInvite{},
+	}
+}
+
+func (m *MemoryHouseholds) Create(_ context.Context, household model.Household, owner model.HouseholdMember) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.households[household.Id]; ok {
+		return ErrDuplicate
+	}
+	m.households[household.Id] = household
+	m.members[memberKey{owner.HouseholdId, owner.UserId}] = owner
+	return nil
+}
+
+func (m *MemoryHouseholds) Get(_ context.Context, id string) (model.Household, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	household, ok := m.households[id]
+	if !ok {
+		return model.Household{}, ErrNotFound
+	}
+	return household, nil
+}
+
+func (m *MemoryHouseholds) Member(_ context.Context, householdID, userID string) (model.HouseholdMember, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	member, ok := m.members[memberKey{householdID, userID}]
+	if !ok {
+		return model.HouseholdMember{}, ErrNotFound
+	}
+	return member, nil
+}
+
+// filterMembers returns the members accepted by keep, oldest first.
+func (m *MemoryHouseholds) filterMembers(keep func(model.HouseholdMember) bool) []model.HouseholdMember {
+	m.mu.RLock()
+	var out []model.HouseholdMember
+	for _, member := range m.members {
+		if keep(member) {
+			out = append(out, member)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].JoinedAt.Equal(out[j].JoinedAt) {
+			return out[i].JoinedAt.Before(out[j].JoinedAt)
+		}
+		return out[i].HouseholdId+out[i].UserId < out[j].HouseholdId+out[j].UserId
+	})
+	return out
+}
+
+func (m *MemoryHouseholds) Members(_ context.Context, householdID string) ([]model.HouseholdMember, error) {
+	return m.filterMembers(func(member model.HouseholdMember) bool { return member.HouseholdId == householdID }), nil
+}
+
+func (m *MemoryHouseholds) Memberships(_ context.Context, userID string) ([]model.HouseholdMember, error) {
+	return m.filterMembers(func(member model.HouseholdMember) bool { return member.UserId == userID }), nil
+}
+
+func (m *MemoryHouseholds) AddMember(_ context.Context, member model.HouseholdMember) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := memberKey{member.HouseholdId, member.UserId}
+	if _, ok := m.members[key]; ok {
+		return ErrDuplicate
+	}
+	m.members[key] = member
+	return nil
+}
+
+func (m *MemoryHouseholds) UpdateMember(_ context.Context, member model.HouseholdMember) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := memberKey{member.HouseholdId, member.UserId}
+	if _, ok := m.members[key]; !ok {
+		return ErrNotFound
+	}
+	m.members[key] = member
+	return nil
+}
+
+func (m *MemoryHouseholds) RemoveMember(_ context.Context, householdID, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.members, memberKey{householdID, userID})
+	return nil
+}
+
+func (m *MemoryHouseholds) CreateInvite(_ context.Context, invite model.HouseholdInvite) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.invites[invite.Code]; ok {
+		return ErrDuplicate
+	}
+	m.invites[invite.Code] = invite
+	return nil
+}
+
+func (m *MemoryHouseholds) TakeInvite(_ context.Context, code string) (model.HouseholdInvite, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	invite, ok := m.invites[code]
+	if !ok {
+		return model.HouseholdInvite{}, ErrNotFound
+	}
+	delete(m.invites, code)
+	return invite, nil
+}
+
+// MemoryTwoFactors is a TwoFactorStore backed by maps. It is safe for
+// concurrent use.
+type MemoryTwoFactors struct {
+	mu       sync.Mutex
+	enrolled map[string]model.TwoFactor
+	// codes maps a recovery code hash to its user.
+	codes map[string]string
+}
+
+// NewMemoryTwoFactors returns an empty in-memory two-factor store.
+func NewMemoryTwoFactors() *MemoryTwoFactors {
+	return &MemoryTwoFactors{enrolled: map[string]model.TwoFactor{}, codes: map[string]string{}}
+}
+
+func (m *MemoryTwoFactors) Get(_ context.Context, userID string) (model.TwoFactor, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tf, ok := m.enrolled[userID]
+	if !ok {
+		return model.TwoFactor{}, ErrNotFound
+	}
+	return tf, nil
+}
+
+func (m *MemoryTwoFactors) Save(_ context.Context, tf model.TwoFactor) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.enrolled[tf.UserId] = tf
+	return nil
+}
+
+func (m *MemoryTwoFactors) Delete(_ context.Context, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.enrolled, userID)
+	m.deleteCodes(userID)
+	return nil
+}
+
+func (m *MemoryTwoFactors) UseStep(_ context.Context, userID string, step int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tf, ok := m.enrolled[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	if step <= tf.LastStep {
+		return ErrDuplicate
+	}
+	tf.LastStep = step
+	m.enrolled[userID] = tf
+	return nil
+}
+
+func (m *MemoryTwoFactors) SetRecoveryCodes(_ context.Context, userID string, hashes []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleteCodes(userID)
+	for _, h := range hashes {
+		m.codes[h] = userID
+	}
+	return nil
+}
+
+func (m *MemoryTwoFactors) UseRecoveryCode(_ context.Context, userID, hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.codes[hash] != userID {
+		return ErrNotFound
+	}
+	delete(m.codes, hash)
+	return nil
+}
+
+func (m *MemoryTwoFactors) RecoveryCodes(_ context.Context, userID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, owner := range m.codes {
+		if owner == userID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// deleteCodes must be called with mu held.
+func (m *MemoryTwoFactors) deleteCodes(userID string) {
+	for h, owner := range m.codes {
+		if owner == userID {
+			delete(m.codes, h)
+		}
+	}
+}
+
+// MemoryAccessTokens is an AccessTokenStore backed by a map. It is safe for
+// concurrent use.
+type MemoryAccessTokens struct {
+	mu     sync.Mutex
+	tokens map[string]model.AccessToken
+}
+
+// NewMemoryAccessTokens returns an empty in-memory access token store.
+func NewMemoryAccessTokens() *MemoryAccessTokens {
+	return &MemoryAccessTokens{tokens: map[string]model.AccessToken{}}
+}
+
+func (m *MemoryAccessTokens) Create(_ context.Context, token model.AccessToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.tokens[token.Id]; ok {
+		return ErrDuplicate
+	}
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	m.tokens[token.Id] = token
+	return nil
+}
+
+func (m *MemoryAccessTokens) List(_ context.Context, userID string) ([]model.AccessToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tokens := []model.AccessToken{}
+	for _, t := range m.tokens {
+		if t.UserId == userID {
+			tokens = append(tokens, t)
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].CreatedAt.Before(tokens[j].CreatedAt) })
+	return tokens, nil
+}
+
+func (m *MemoryAccessTokens) ByHash(_ context.Context, hash string) (model.AccessToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.tokens {
+		if t.TokenHash == hash {
+			return t, nil
+		}
+	}
+	return model.AccessToken{}, ErrNotFound
+}
+
+func (m *MemoryAccessTokens) Touch(_ context.Context, id string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tokens[id]
+	if !ok {
+		return ErrNotFound
+	}
+	t.LastUsedAt = &at
+	m.tokens[id] = t
+	return nil
+}
+
+func (m *MemoryAccessTokens) Delete(_ context.Context, userID, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t, ok := m.tokens[id]; !ok || t.UserId != userID {
+		return ErrNotFound
+	}
+	delete(m.tokens, id)
+	return nil
+}
+
+// MemoryLoginAttempts is a LoginAttemptStore backed by a slice and a map.
+// It is safe for concurrent use.
+type MemoryLoginAttempts struct {
+	mu        sync.Mutex
+	attempts  []model.LoginAttempt
+	throttles map[string]model.LoginThrottle
+}
+
+// NewMemoryLoginAttempts returns an empty in-memory login attempt store.
+func NewMemoryLoginAttempts() *MemoryLoginAttempts {
+	return &MemoryLoginAttempts{throttles: map[string]model.LoginThrottle{}}
+}
+
+func (m *MemoryLoginAttempts) Throttle(_ context.Context, key string) (model.LoginThrottle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t, ok := m.throttles[key]; ok {
+		return t, nil
+	}
+	return model.LoginThrottle{Key: key}, nil
+}
+
+func (m *MemoryLoginAttempts) RecordFailure(_ context.Context, key string, attempt model.LoginAttempt) (model.LoginThrottle, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.attempts = append(m.attempts, attempt)
+	t := m.throttles[key]
+	t.Key = key
+	t.Failures++
+	t.LastFailure = attempt.CreatedAt
+	m.throttles[key] = t
+	return t, nil
+}
+
+func (m *MemoryLoginAttempts) Lock(_ context.Context, key string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := m.throttles[key]
+	t.Key = key
+	t.LockedUntil = until
+	m.throttles[key] = t
+	return nil
+}
+
+func (m *MemoryLoginAttempts) Reset(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.throttles, key)
+	return nil
+}
+
+func (m *MemoryLoginAttempts) Failures(_ context.Context, f LoginAttemptFilter) ([]model.LoginAttempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	attempts := []model.LoginAttempt{}
+	for i := len(m.attempts) - 1; i >= 0; i-- {
+		a := m.attempts[i]
+		if (f.UserName != "" && a.UserName != f.UserName) || a.CreatedAt.Before(f.Since) {
+			continue
+		}
+		attempts = append(attempts, a)
+		if f.Limit > 0 && len(attempts) == f.Limit {
+			break
+		}
+	}
+	return attempts, nil
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"expense-tracker/model"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryExpenses_Filters(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryExpenses()
+	day := func(d int) time.Time { return time.Date(2025, 7, d, 12, 0, 0, 0, time.UTC) }
+	for i, e := range []model.Expense{
+		{Id: "a", User_id: "u1", Amount: 5, Currency: "USD", Category: "food", TimeStamp: day(3)},
+		{Id: "b", User_id: "u1", Amount: 7, Currency: "EUR", Category: "food", TimeStamp: day(1)},
+		{Id: "c", User_id: "u2", Amount: 9, Currency: "USD", Category: "rent", TimeStamp: day(2)},
+	} {
+		require.NoError(t, m.Create(ctx, e), i)
+	}
+	assert.ErrorIs(t, m.Create(ctx, model.Expense{Id: "a"}), ErrDuplicate)
+	require.NoError(t, m.Create(ctx, model.Expense{Id: "d", User_id: "u1", External_id: "F1", TimeStamp: day(4)}))
+	assert.ErrorIs(t, m.Create(ctx, model.Expense{Id: "e", User_id: "u1", External_id: "F1"}), ErrDuplicate)
+	require.NoError(t, m.Delete(ctx, "d"))
+
+	list, err := m.List(ctx, ExpenseFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "c", "a"}, ids(list))
+
+	list, err = m.List(ctx, ExpenseFilter{From: "2025-07-02", Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, ids(list))
+
+	_, err = m.List(ctx, ExpenseFilter{To: "yesterday"})
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+
+	summary, err := m.Summarize(ctx, ExpenseFilter{UserID: "u1"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"food": 12}, summary)
+
+	grouped, err := m.ByCategory(ctx, ExpenseFilter{}, []string{"food"}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, ids(grouped["food"]))
+
+	require.NoError(t, m.Delete(ctx, "a"))
+	_, err = m.Get(ctx, "a")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, m.Update(ctx, model.Expense{Id: "a"}), ErrNotFound)
+}
+
+func TestMemoryStores_ConcurrentUse(t *testing.T) {
+	ctx := context.Background()
+	expenses, users := NewMemoryExpenses(), NewMemoryUsers()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprint(i)
+			assert.NoError(t, users.Create(ctx, model.User{UserId: id, UserName: "user" + id}))
+			assert.NoError(t, expenses.Create(ctx, model.Expense{Id: id, User_id: id, Amount: 1, Category: "x"}))
+			_, err := expenses.Summarize(ctx, ExpenseFilter{})
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	summary, err := expenses.Summarize(ctx, ExpenseFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 50.0, summary["x"])
+	assert.ErrorIs(t, users.Create(ctx, model.User{UserId: "new", UserName: "user7"}), ErrDuplicate)
+}
+
+func ids(expenses []model.Expense) []string {
+	out := make([]string, len(expenses))
+	for i, e := range expenses {
+		out[i] = e.Id
+	}
+	return out
+}
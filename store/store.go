@@ -0,0 +1,280 @@
+// Package store defines the persistence interfaces behind the service layer
+// and a thread-safe in-memory implementation of them. The SQL implementation
+// lives in package postgresql.
+package store
+
+import (
+	"context"
+	"errors"
+	"expense-tracker/model"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when the requested record does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrInvalidArgument is returned when a request is missing required input.
+	ErrInvalidArgument = errors.New("invalid argument")
+	// ErrDuplicate is returned when a unique field is already taken.
+	ErrDuplicate = errors.New("already exists")
+)
+
+// DefaultPageSize is used when a list request does not set a limit.
+const DefaultPageSize = 10
+
+// ExpenseFilter narrows list and summary queries. Empty fields are ignored,
+// except HouseholdID: an empty one selects personal expenses, which keeps
+// household ledgers out of unscoped queries.
+type ExpenseFilter struct {
+	UserID      string
+	HouseholdID string
+	Category    string
+	Currency    string
+	AccountID   string
+	From        string
+	To          string
+	Limit       int
+	Offset      int
+}
+
+// Bounds parses From and To as UTC times; unset bounds are returned as the
+// zero time. Dates are YYYY-MM-DD (midnight UTC) or RFC 3339.
+func (f ExpenseFilter) Bounds() (from, to time.Time, err error) {
+	if f.From != "" {
+		if from, err = parseTime(f.From); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	if f.To != "" {
+		if to, err = parseTime(f.To); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	return from, to, nil
+}
+
+// PageSize returns Limit, or DefaultPageSize when it is not positive.
+func (f ExpenseFilter) PageSize() int {
+	if f.Limit <= 0 {
+		return DefaultPageSize
+	}
+	return f.Limit
+}
+
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid date %q", ErrInvalidArgument, s)
+	}
+	return t.UTC(), nil
+}
+
+// ExpenseStore persists expenses.
+type ExpenseStore interface {
+	// Create inserts an expense whose ID has already been assigned;
+	// ErrDuplicate if the ID, or a non-empty External_id in the same
+	// ledger, is taken.
+	Create(ctx context.Context, expense model.Expense) error
+	// Get returns ErrNotFound for an unknown ID.
+	Get(ctx context.Context, id string) (model.Expense, error)
+	// Update overwrites an existing expense.
+	Update(ctx context.Context, expense model.Expense) error
+	// Delete removes an expense; deleting a missing expense is not an error.
+	Delete(ctx context.Context, id string) error
+	// List returns one page of matching expenses ordered by time, then ID.
+	List(ctx context.Context, f ExpenseFilter) ([]model.Expense, error)
+	// Stream calls fn for every matching expense in List order, batchSize at
+	// a time, ignoring Limit and Offset. An error from fn stops the scan.
+	Stream(ctx context.Context, f ExpenseFilter, batchSize int, fn func(model.Expense) error) error
+	// Summarize totals amounts per category.
+	Summarize(ctx context.Context, f ExpenseFilter) (map[string]float64, error)
+	// SummarizeByUser totals amounts per user ID.
+	SummarizeByUser(ctx context.Context, f ExpenseFilter) (map[string]float64, error)
+	// ByCategory returns up to perCategory of the most recent matching
+	// expenses for each of categories.
+	ByCategory(ctx context.Context, f ExpenseFilter, categories []string, perCategory int) (map[string][]model.Expense, error)
+	// ExternalIDs reports which of ids are already the External_id of a
+	// matching expense.
+	ExternalIDs(ctx context.Context, f ExpenseFilter, ids []string) (map[string]bool, error)
+}
+
+// IncomeStore persists income. It is filtered with ExpenseFilter, whose
+// Category matches the income category.
+type IncomeStore interface {
+	// Create inserts income whose ID has already been assigned;
+	// ErrDuplicate if the ID, or a non-empty External_id in the same
+	// ledger, is taken.
+	Create(ctx context.Context, income model.Income) error
+	// Get returns ErrNotFound for an unknown ID.
+	Get(ctx context.Context, id string) (model.Income, error)
+	// Update overwrites existing income.
+	Update(ctx context.Context, income model.Income) error
+	// Delete removes income; deleting missing income is not an error.
+	Delete(ctx context.Context, id string) error
+	// List returns one page of matching income ordered by time, then ID.
+	List(ctx context.Context, f ExpenseFilter) ([]model.Income, error)
+	// Stream calls fn for every matching income in List order, batchSize at
+	// a time, ignoring Limit and Offset. An error from fn stops the scan.
+	Stream(ctx context.Context, f ExpenseFilter, batchSize int, fn func(model.Income) error) error
+	// ExternalIDs reports which of ids are already the External_id of
+	// matching income.
+	ExternalIDs(ctx context.Context, f ExpenseFilter, ids []string) (map[string]bool, error)
+}
+
+// AccountStore persists accounts.
+type AccountStore interface {
+	// Create inserts an account whose ID has already been assigned.
+	Create(ctx context.Context, account model.Account) error
+	// Get returns ErrNotFound for an unknown ID.
+	Get(ctx context.Context, id string) (model.Account, error)
+	// Update overwrites an existing account.
+	Update(ctx context.Context, account model.Account) error
+	// List returns the household's accounts, or the user's personal ones
+	// when householdID is empty, ordered by name, then ID.
+	List(ctx context.Context, userID, householdID string) ([]model.Account, error)
+}
+
+// TransferStore persists transfers between accounts.
+type TransferStore interface {
+	// Create inserts a transfer whose ID has already been assigned.
+	Create(ctx context.Context, transfer model.Transfer) error
+	// Get returns ErrNotFound for an unknown ID.
+	Get(ctx context.Context, id string) (model.Transfer, error)
+	// Delete removes a transfer; deleting a missing one is not an error.
+	Delete(ctx context.Context, id string) error
+	// List returns the transfers into or out of an account ordered by time,
+	// then ID.
+	List(ctx context.Context, accountID string) ([]model.Transfer, error)
+}
+
+// RuleStore persists categorization rules.
+type RuleStore interface {
+	// Create inserts a rule whose ID has already been assigned.
+	Create(ctx context.Context, rule model.Rule) error
+	// Get returns ErrNotFound for an unknown ID.
+	Get(ctx context.Context, id string) (model.Rule, error)
+	// Update overwrites an existing rule.
+	Update(ctx context.Context, rule model.Rule) error
+	// Delete removes a rule; deleting a missing rule is not an error.
+	Delete(ctx context.Context, id string) error
+	// List returns a user's rules in the order they run: by priority, then
+	// creation time, then ID.
+	List(ctx context.Context, userID string) ([]model.Rule, error)
+}
+
+// UserStore persists users.
+type UserStore interface {
+	// Create inserts a user; a taken user name is an error.
+	Create(ctx context.Context, user model.User) error
+	// ByName returns ErrNotFound for an unknown user name.
+	ByName(ctx context.Context, userName string) (model.User, error)
+	// ByID returns ErrNotFound for an unknown user ID.
+	ByID(ctx context.Context, id string) (model.User, error)
+	// ByIDs returns the users that exist among ids, keyed by ID.
+	ByIDs(ctx context.Context, ids []string) (map[string]model.User, error)
+	// Update overwrites an existing user.
+	Update(ctx context.Context, user model.User) error
+	// CreateReset stores a password reset token, replacing any the user
+	// already had outstanding.
+	CreateReset(ctx context.Context, reset model.PasswordReset) error
+	// TakeReset deletes and returns the reset with tokenHash so it can be
+	// used only once. It returns ErrNotFound for an unknown or used token.
+	TakeReset(ctx context.Context, tokenHash string) (model.PasswordReset, error)
+	// DeleteResets removes every outstanding reset token of a user.
+	DeleteResets(ctx context.Context, userID string) error
+	// Identity returns the external identity with issuer and subject, or
+	// ErrNotFound if it is not linked to a user.
+	Identity(ctx context.Context, issuer, subject string) (model.ExternalIdentity, error)
+	// LinkIdentity stores an external identity; ErrDuplicate if it is
+	// already linked.
+	LinkIdentity(ctx context.Context, identity model.ExternalIdentity) error
+}
+
+// HouseholdStore persists households, their members and invites.
+type HouseholdStore interface {
+	// Create inserts a household together with its owner's membership.
+	Create(ctx context.Context, household model.Household, owner model.HouseholdMember) error
+	// Get returns ErrNotFound for an unknown ID.
+	Get(ctx context.Context, id string) (model.Household, error)
+	// Member returns ErrNotFound when the user is not in the household.
+	Member(ctx context.Context, householdID, userID string) (model.HouseholdMember, error)
+	// Members lists the members of a household.
+	Members(ctx context.Context, householdID string) ([]model.HouseholdMember, error)
+	// Memberships lists the households a user belongs to.
+	Memberships(ctx context.Context, userID string) ([]model.HouseholdMember, error)
+	// AddMember inserts a membership; ErrDuplicate if the user is a member.
+	AddMember(ctx context.Context, member model.HouseholdMember) error
+	// UpdateMember changes the role of an existing member.
+	UpdateMember(ctx context.Context, member model.HouseholdMember) error
+	// RemoveMember deletes a membership; a missing one is not an error.
+	RemoveMember(ctx context.Context, householdID, userID string) error
+	// CreateInvite stores an invite code.
+	CreateInvite(ctx context.Context, invite model.HouseholdInvite) error
+	// TakeInvite deletes and returns an invite so it can be used only once.
+	// It returns ErrNotFound for an unknown or already used code.
+	TakeInvite(ctx context.Context, code string) (model.HouseholdInvite, error)
+}
+
+// TwoFactorStore persists TOTP enrolments and recovery codes.
+type TwoFactorStore interface {
+	// Get returns ErrNotFound when the user has not started enrolment.
+	Get(ctx context.Context, userID string) (model.TwoFactor, error)
+	// Save creates or replaces a user's enrolment.
+	Save(ctx context.Context, tf model.TwoFactor) error
+	// Delete removes a user's enrolment and recovery codes.
+	Delete(ctx context.Context, userID string) error
+	// UseStep records step as the last accepted TOTP step. It returns
+	// ErrDuplicate if a code from that step or a later one was already used.
+	UseStep(ctx context.Context, userID string, step int64) error
+	// SetRecoveryCodes replaces a user's recovery codes with hashes.
+	SetRecoveryCodes(ctx context.Context, userID string, hashes []string) error
+	// UseRecoveryCode deletes a recovery code; ErrNotFound if the user has
+	// no unused code with that hash.
+	UseRecoveryCode(ctx context.Context, userID, hash string) error
+	// RecoveryCodes counts a user's unused recovery codes.
+	RecoveryCodes(ctx context.Context, userID string) (int, error)
+}
+
+// LoginAttemptFilter narrows a listing of failed logins. Empty fields are
+// ignored.
+type LoginAttemptFilter struct {
+	UserName string
+	Since    time.Time
+	Limit    int
+}
+
+// LoginAttemptStore records failed logins and per-name throttling state.
+type LoginAttemptStore interface {
+	// Throttle returns the state for key, or a zero LoginThrottle with that
+	// key if there have been no failures.
+	Throttle(ctx context.Context, key string) (model.LoginThrottle, error)
+	// RecordFailure stores attempt and increments the failure count of key,
+	// returning the updated state.
+	RecordFailure(ctx context.Context, key string, attempt model.LoginAttempt) (model.LoginThrottle, error)
+	// Lock sets the time until which key is locked out.
+	Lock(ctx context.Context, key string, until time.Time) error
+	// Reset clears key's failures and lockout, after a successful login or
+	// by an admin.
+	Reset(ctx context.Context, key string) error
+	// Failures lists failed attempts, newest first.
+	Failures(ctx context.Context, filter LoginAttemptFilter) ([]model.LoginAttempt, error)
+}
+
+// AccessTokenStore persists personal access tokens.
+type AccessTokenStore interface {
+	// Create inserts a token whose ID has already been assigned.
+	Create(ctx context.Context, token model.AccessToken) error
+	// List returns a user's tokens, oldest first.
+	List(ctx context.Context, userID string) ([]model.AccessToken, error)
+	// ByHash returns ErrNotFound if no token has that hash.
+	ByHash(ctx context.Context, hash string) (model.AccessToken, error)
+	// Touch sets a token's LastUsedAt.
+	Touch(ctx context.Context, id string, at time.Time) error
+	// Delete revokes one of a user's tokens; ErrNotFound if the user has no
+	// token with that ID.
+	Delete(ctx context.Context, userID, id string) error
+}
@@ -0,0 +1,186 @@
+package testutil
+
+import (
+	"context"
+	"expense-tracker/auth"
+	"expense-tracker/model"
+	"expense-tracker/service"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DefaultPassword is the plain-text password of users built without one.
+const DefaultPassword = "password"
+
+// BaseTime is the timestamp of expenses built without one.
+var BaseTime = time.Date(2025, time.July, 1, 12, 0, 0, 0, time.UTC)
+
+// UserBuilder builds model.User fixtures.
+type UserBuilder struct {
+	user     model.User
+	password string
+}
+
+// User starts a user with a random ID and name and DefaultPassword.
+func User() *UserBuilder {
+	id := uuid.New().String()
+	return &UserBuilder{
+		user:     model.User{UserId: id, UserName: "user-" + id[:8], Tier: auth.DefaultTier},
+		password: DefaultPassword,
+	}
+}
+
+func (b *UserBuilder) ID(id string) *UserBuilder       { b.user.UserId = id; return b }
+func (b *UserBuilder) Name(name string) *UserBuilder   { b.user.UserName = name; return b }
+func (b *UserBuilder) Password(pw string) *UserBuilder { b.password = pw; return b }
+func (b *UserBuilder) Tier(tier string) *UserBuilder   { b.user.Tier = tier; return b }
+
+// Build returns the user with its password hashed at the minimum bcrypt cost.
+func (b *UserBuilder) Build() model.User {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(b.password), bcrypt.MinCost)
+	if err != nil {
+		panic(err)
+	}
+	user := b.user
+	user.Password = string(hashed)
+	return user
+}
+
+// Create builds the user and stores it in service.Users.
+func (b *UserBuilder) Create(t testing.TB) model.User {
+	t.Helper()
+	user := b.Build()
+	if err := service.Users.Create(context.Background(), user); err != nil {
+		t.Fatalf("create user fixture: %v", err)
+	}
+	return user
+}
+
+// ExpenseBuilder builds model.Expense fixtures.
+type ExpenseBuilder struct {
+	expense model.Expense
+}
+
+// Expense starts a 10 USD "food" expense at BaseTime with a random ID.
+func Expense() *ExpenseBuilder {
+	return &ExpenseBuilder{expense: model.Expense{
+		Id:          uuid.New().String(),
+		Amount:      10,
+		Currency:    "USD",
+		Category:    "food",
+		Description: "test expense",
+		TimeStamp:   BaseTime,
+	}}
+}
+
+func (b *ExpenseBuilder) ID(id string) *ExpenseBuilder       { b.expense.Id = id; return b }
+func (b *ExpenseBuilder) Owner(u model.User) *ExpenseBuilder { b.expense.User_id = u.UserId; return b }
+func (b *ExpenseBuilder) In(h model.Household) *ExpenseBuilder {
+	b.expense.Household_id = h.Id
+	return b
+}
+func (b *ExpenseBuilder) Amount(amount float64) *ExpenseBuilder { b.expense.Amount = amount; return b }
+func (b *ExpenseBuilder) Currency(code string) *ExpenseBuilder  { b.expense.Currency = code; return b }
+func (b *ExpenseBuilder) Category(name string) *ExpenseBuilder  { b.expense.Category = name; return b }
+func (b *ExpenseBuilder) Description(d string) *ExpenseBuilder  { b.expense.Description = d; return b }
+func (b *ExpenseBuilder) At(ts time.Time) *ExpenseBuilder       { b.expense.TimeStamp = ts.UTC(); return b }
+func (b *ExpenseBuilder) Account(id string) *ExpenseBuilder     { b.expense.Account_id = id; return b }
+
+// Build returns the expense.
+func (b *ExpenseBuilder) Build() model.Expense {
+	return b.expense
+}
+
+// Create builds the expense and stores it in service.Expenses.
+func (b *ExpenseBuilder) Create(t testing.TB) model.Expense {
+	t.Helper()
+	expense := b.Build()
+	if err := service.Expenses.Create(context.Background(), expense); err != nil {
+		t.Fatalf("create expense fixture: %v", err)
+	}
+	return expense
+}
+
+// IncomeBuilder builds a model.Income fixture.
+type IncomeBuilder struct {
+	income model.Income
+}
+
+// Income starts a 100 USD salary fixture at BaseTime with a fresh ID.
+func Income() *IncomeBuilder {
+	return &IncomeBuilder{income: model.Income{
+		Id:          uuid.New().String(),
+		Amount:      100,
+		Currency:    "USD",
+		Category:    service.IncomeSalary,
+		Description: "test income",
+		TimeStamp:   BaseTime,
+	}}
+}
+
+func (b *IncomeBuilder) Owner(u model.User) *IncomeBuilder { b.income.User_id = u.UserId; return b }
+func (b *IncomeBuilder) In(h model.Household) *IncomeBuilder {
+	b.income.Household_id = h.Id
+	return b
+}
+func (b *IncomeBuilder) Amount(amount float64) *IncomeBuilder { b.income.Amount = amount; return b }
+func (b *IncomeBuilder) Currency(code string) *IncomeBuilder  { b.income.Currency = code; return b }
+func (b *IncomeBuilder) Category(name string) *IncomeBuilder  { b.income.Category = name; return b }
+func (b *IncomeBuilder) At(ts time.Time) *IncomeBuilder       { b.income.TimeStamp = ts.UTC(); return b }
+func (b *IncomeBuilder) Account(id string) *IncomeBuilder     { b.income.Account_id = id; return b }
+
+// Create builds the income and stores it in service.Incomes.
+func (b *IncomeBuilder) Create(t testing.TB) model.Income {
+	t.Helper()
+	if err := service.Incomes.Create(context.Background(), b.income); err != nil {
+		t.Fatalf("create income fixture: %v", err)
+	}
+	return b.income
+}
+
+// HouseholdBuilder builds a model.Household fixture and its memberships.
+type HouseholdBuilder struct {
+	household model.Household
+	members   []model.HouseholdMember
+}
+
+// Household starts a household owned by owner.
+func Household(owner model.User) *HouseholdBuilder {
+	return &HouseholdBuilder{household: model.Household{
+		Id:        uuid.New().String(),
+		Name:      "household",
+		OwnerId:   owner.UserId,
+		CreatedAt: BaseTime,
+	}}
+}
+
+func (b *HouseholdBuilder) Name(name string) *HouseholdBuilder { b.household.Name = name; return b }
+
+// Member adds user with role (service.RoleEditor or service.RoleViewer).
+func (b *HouseholdBuilder) Member(user model.User, role string) *HouseholdBuilder {
+	b.members = append(b.members, model.HouseholdMember{
+		HouseholdId: b.household.Id, UserId: user.UserId, Role: role, JoinedAt: BaseTime,
+	})
+	return b
+}
+
+// Create stores the household, its owner and members in service.Households.
+func (b *HouseholdBuilder) Create(t testing.TB) model.Household {
+	t.Helper()
+	ctx := context.Background()
+	owner := model.HouseholdMember{
+		HouseholdId: b.household.Id, UserId: b.household.OwnerId, Role: service.RoleOwner, JoinedAt: BaseTime,
+	}
+	if err := service.Households.Create(ctx, b.household, owner); err != nil {
+		t.Fatalf("create household fixture: %v", err)
+	}
+	for _, m := range b.members {
+		if err := service.Households.AddMember(ctx, m); err != nil {
+			t.Fatalf("add household member fixture: %v", err)
+		}
+	}
+	return b.household
+}
@@ -0,0 +1,284 @@
+// Package testutil provides in-memory stores, fixture builders, JWT minting
+// and a fully routed gin engine so handler behaviour can be tested without a
+// database server. The same routes can run against the SQL stores on an
+// in-memory SQLite database by setting Backend.
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"expense-tracker/auth"
+	"expense-tracker/config"
+	"expense-tracker/logging"
+	"expense-tracker/model"
+	"expense-tracker/notify"
+	"expense-tracker/postgresql"
+	"expense-tracker/problem"
+	"expense-tracker/routes"
+	"expense-tracker/service"
+	"expense-tracker/store"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/logger"
+)
+
+// JWTSecret signs every token issued while testutil is in use.
+const JWTSecret = "testutil-secret"
+
+// The backends Router can store data in.
+const (
+	BackendMemory = "memory"
+	BackendSQLite = "sqlite"
+)
+
+// Backend selects the stores Router installs: BackendMemory, the default,
+// or BackendSQLite for the SQL stores on a fresh in-memory SQLite database.
+var Backend = BackendMemory
+
+// ConfigureAuth points the auth package at JWTSecret and checks sessions and
+// access tokens against the service stores, as the server does.
+func ConfigureAuth() {
+	if err := auth.Configure(config.JWT{Secret: JWTSecret, TTL: config.Duration{Duration: time.Hour}}); err != nil {
+		panic(err)
+	}
+	auth.SetSessionValidator(service.ValidateSession)
+	auth.SetAccessTokenValidator(service.ValidateAccessToken)
+}
+
+// ConfigureService changes the service configuration for the duration of
+// the test.
+func ConfigureService(t testing.TB, change func(c *service.Config)) {
+	t.Helper()
+	prev := service.CurrentConfig()
+	next := prev
+	change(&next)
+	service.Configure(next)
+	t.Cleanup(func() { service.Configure(prev) })
+}
+
+// Stores are the in-memory stores installed by UseMemoryStores, along with
+// the notifier that records password reset messages.
+type Stores struct {
+	Expenses      *store.MemoryExpenses
+	Incomes       *store.MemoryIncomes
+	Accounts      *store.MemoryAccounts
+	Transfers     *store.MemoryTransfers
+	Rules         *store.MemoryRules
+	Users         *store.MemoryUsers
+	Households    *store.MemoryHouseholds
+	TwoFactors    *store.MemoryTwoFactors
+	AccessTokens  *store.MemoryAccessTokens
+	LoginAttempts *store.MemoryLoginAttempts
+	Notifier      *notify.Memory
+}
+
+// UseMemoryStores replaces the service stores and notifier with empty
+// in-memory ones for the duration of the test.
+func UseMemoryStores(t testing.TB) Stores {
+	t.Helper()
+	stores := Stores{
+		Expenses:      store.NewMemoryExpenses(),
+		Incomes:       store.NewMemoryIncomes(),
+		Accounts:      store.NewMemoryAccounts(),
+		Transfers:     store.NewMemoryTransfers(),
+		Rules:         store.NewMemoryRules(),
+		Users:         store.NewMemoryUsers(),
+		Households:    store.NewMemoryHouseholds(),
+		TwoFactors:    store.NewMemoryTwoFactors(),
+		AccessTokens:  store.NewMemoryAccessTokens(),
+		LoginAttempts: store.NewMemoryLoginAttempts(),
+		Notifier:      &notify.Memory{},
+	}
+	prevExpenses, prevUsers, prevHouseholds := service.Expenses, service.Users, service.Households
+	prevTwoFactors, prevAccessTokens := service.TwoFactors, service.AccessTokens
+	service.Expenses, service.Users, service.Households = stores.Expenses, stores.Users, stores.Households
+	prevLoginAttempts, prevIncomes := service.LoginAttempts, service.Incomes
+	prevAccounts, prevTransfers, prevRules := service.Accounts, service.Transfers, service.Rules
+	service.TwoFactors, service.AccessTokens = stores.TwoFactors, stores.AccessTokens
+	service.LoginAttempts, service.Incomes = stores.LoginAttempts, stores.Incomes
+	service.Accounts, service.Transfers, service.Rules = stores.Accounts, stores.Transfers, stores.Rules
+	ConfigureService(t, func(c *service.Config) { c.Notifier = stores.Notifier })
+	t.Cleanup(func() {
+		service.Expenses, service.Users, service.Households = prevExpenses, prevUsers, prevHouseholds
+		service.TwoFactors, service.AccessTokens = prevTwoFactors, prevAccessTokens
+		service.LoginAttempts, service.Incomes = prevLoginAttempts, prevIncomes
+		service.Accounts, service.Transfers, service.Rules = prevAccounts, prevTransfers, prevRules
+	})
+	return stores
+}
+
+// UseSQLiteStores replaces the service stores with the SQL ones on a fresh,
+// migrated in-memory SQLite database, and the notifier with an in-memory
+// one, for the duration of the test.
+func UseSQLiteStores(t testing.TB) {
+	t.Helper()
+	db, err := postgresql.Open(config.Database{Driver: "sqlite", URL: ":memory:"})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	// Lookups that find nothing are expected; keep them out of the output.
+	db.Logger = logger.Default.LogMode(logger.Silent)
+
+	prevDB := postgresql.DB
+	prevExpenses, prevUsers, prevHouseholds := service.Expenses, service.Users, service.Households
+	prevTwoFactors, prevAccessTokens := service.TwoFactors, service.AccessTokens
+	prevLoginAttempts, prevIncomes := service.LoginAttempts, service.Incomes
+	prevAccounts, prevTransfers, prevRules := service.Accounts, service.Transfers, service.Rules
+	postgresql.DB = db
+	service.Expenses, service.Users, service.Households = postgresql.ExpenseStore{}, postgresql.UserStore{}, postgresql.HouseholdStore{}
+	service.TwoFactors, service.AccessTokens = postgresql.TwoFactorStore{}, postgresql.AccessTokenStore{}
+	service.LoginAttempts, service.Incomes = postgresql.LoginAttemptStore{}, postgresql.IncomeStore{}
+	service.Accounts, service.Transfers, service.Rules = postgresql.AccountStore{}, postgresql.TransferStore{}, postgresql.RuleStore{}
+	ConfigureService(t, func(c *service.Config) { c.Notifier = &notify.Memory{} })
+	t.Cleanup(func() {
+		postgresql.DB = prevDB
+		service.Expenses, service.Users, service.Households = prevExpenses, prevUsers, prevHouseholds
+		service.TwoFactors, service.AccessTokens = prevTwoFactors, prevAccessTokens
+		service.LoginAttempts, service.Incomes = prevLoginAttempts, prevIncomes
+		service.Accounts, service.Transfers, service.Rules = prevAccounts, prevTransfers, prevRules
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
+
+// Notifications returns the in-memory notifier installed by UseMemoryStores
+// or UseSQLiteStores.
+func Notifications(t testing.TB) *notify.Memory {
+	t.Helper()
+	notifier := service.CurrentConfig().Notifier
+	m, ok := notifier.(*notify.Memory)
+	if !ok {
+		t.Fatalf("the service notifier is %T, not the in-memory one", notifier)
+	}
+	return m
+}
+
+// Router returns an engine with every API route registered, backed by fresh
+// stores of the selected Backend, with auth configured and rate limits high enough not to
+// interfere. Use RouterWithLimits to test rate limiting.
+func Router(t testing.TB) *gin.Engine {
+	t.Helper()
+	return RouterWithLimits(t, config.GroupLimit{Anonymous: "1000-S", Default: "1000-S"})
+}
+
+// RouterWithLimits is Router with the same limit applied to every group.
+func RouterWithLimits(t testing.TB, limit config.GroupLimit) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	ConfigureAuth()
+	switch Backend {
+	case BackendSQLite:
+		UseSQLiteStores(t)
+	default:
+		UseMemoryStores(t)
+	}
+
+	cfg := config.Default().RateLimit
+	for group := range cfg.Groups {
+		cfg.Groups[group] = limit
+	}
+	rateStore, err := auth.NewRateLimitStore(cfg)
+	if err != nil {
+		t.Fatalf("rate limit store: %v", err)
+	}
+	limiter, err := auth.NewRateLimiter(rateStore, cfg)
+	if err != nil {
+		t.Fatalf("rate limiter: %v", err)
+	}
+
+	r := gin.New()
+	r.Use(logging.Middleware(), problem.Recovery())
+	routes.Register(r, limiter, config.Default().GraphQL)
+	return r
+}
+
+// Token mints a JWT for user, as a successful login would.
+func Token(t testing.TB, user model.User) string {
+	t.Helper()
+	ConfigureAuth()
+	tier := user.Tier
+	if tier == "" {
+		tier = auth.DefaultTier
+	}
+	token, err := auth.GenerateToken(user.UserId, tier, user.SessionVersion)
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	return token
+}
+
+// Do sends a request to h. A non-nil body is encoded as JSON unless it is
+// already a string or []byte; a non-empty token is sent as a bearer token.
+func Do(t testing.TB, h http.Handler, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = bytes.NewBufferString(b)
+	case []byte:
+		reader = bytes.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			t.Fatalf("encode body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+// Upload POSTs a multipart form to h with content as the file field
+// "file", named filename, alongside the given form fields.
+func Upload(t testing.TB, h http.Handler, path, token, filename string, content []byte, fields map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for name, value := range fields {
+		if err := mw.WriteField(name, value); err != nil {
+			t.Fatalf("write form field: %v", err)
+		}
+	}
+	part, err := mw.CreateFormFile("file", filename)
+	if err == nil {
+		_, err = part.Write(content)
+	}
+	if err == nil {
+		err = mw.Close()
+	}
+	if err != nil {
+		t.Fatalf("write multipart body: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+// Decode unmarshals the JSON response body into out.
+func Decode(t testing.TB, w *httptest.ResponseRecorder, out interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+		t.Fatalf("decode response %q: %v", w.Body.String(), err)
+	}
+}
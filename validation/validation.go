@@ -0,0 +1,161 @@
+// Package validation checks request DTOs against the rules in their
+// `validate` struct tags and reports every failing field, named as in JSON.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// MaxFuture is how far ahead of now a timestamp may lie: expenses can be
+// planned, but not for the next decade.
+const MaxFuture = 366 * 24 * time.Hour
+
+// FieldError is one failing field.
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// Errors lists every field that failed validation.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, f := range e {
+		parts[i] = f.Field + ": " + f.Reason
+	}
+	return "invalid " + strings.Join(parts, "; ")
+}
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	must(v.RegisterValidation("precision", validPrecision))
+	must(v.RegisterValidation("notfarfuture", notFarFuture))
+	return v
+}
+
+func must(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Struct validates v, returning Errors if any rule fails.
+func Struct(v interface{}) error {
+	err := validate.Struct(v)
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+	out := make(Errors, len(verrs))
+	for i, fe := range verrs {
+		out[i] = FieldError{Field: fieldPath(fe), Reason: reason(fe)}
+	}
+	return out
+}
+
+// fieldPath drops the struct name from the namespace, leaving the JSON path.
+func fieldPath(fe validator.FieldError) string {
+	_, path, _ := strings.Cut(fe.Namespace(), ".")
+	return path
+}
+
+func reason(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte":
+		return "must be at least " + fe.Param()
+	case "lte":
+		return "must be at most " + fe.Param()
+	case "nefield":
+		return "must differ from " + jsonName(fe.Param())
+	case "max":
+		if fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must have at most %s items", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s characters", fe.Param())
+	case "iso4217":
+		return "must be an ISO 4217 currency code"
+	case "precision":
+		return "has more decimal places than the currency allows"
+	case "notfarfuture":
+		return "is too far in the future"
+	case "oneof":
+		return "must be one of " + fe.Param()
+	}
+	return "is invalid (" + fe.Tag() + ")"
+}
+
+// jsonName turns a Go field name such as FromAccountID into its JSON name,
+// from_account_id, for messages that mention another field.
+func jsonName(field string) string {
+	var b strings.Builder
+	runes := []rune(field)
+	for i, r := range runes {
+		upper := unicode.IsUpper(r)
+		if upper && i > 0 && (!unicode.IsUpper(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// threeDecimalCurrencies and zeroDecimalCurrencies are the ISO 4217 codes
+// whose minor unit is not the usual 1/100.
+var (
+	threeDecimalCurrencies = map[string]bool{"BHD": true, "IQD": true, "JOD": true, "KWD": true, "LYD": true, "OMR": true, "TND": true}
+	zeroDecimalCurrencies  = map[string]bool{
+		"BIF": true, "CLP": true, "DJF": true, "GNF": true, "ISK": true, "JPY": true, "KMF": true, "KRW": true,
+		"PYG": true, "RWF": true, "UGX": true, "UYI": true, "VND": true, "VUV": true, "XAF": true, "XOF": true, "XPF": true,
+	}
+)
+
+// MinorUnits returns the number of decimal places amounts in currency have.
+func MinorUnits(currency string) int {
+	switch {
+	case zeroDecimalCurrencies[currency]:
+		return 0
+	case threeDecimalCurrencies[currency]:
+		return 3
+	}
+	return 2
+}
+
+// validPrecision checks that a float amount has no more decimal places than
+// the currency in the sibling field named by the tag parameter allows.
+func validPrecision(fl validator.FieldLevel) bool {
+	currency, _, _, ok := fl.GetStructFieldOKAdvanced2(fl.Parent(), fl.Param())
+	if !ok || currency.Kind() != reflect.String {
+		return false
+	}
+	// The shortest representation is the decimal the client sent.
+	s := strconv.FormatFloat(fl.Field().Float(), 'f', -1, 64)
+	_, decimals, _ := strings.Cut(s, ".")
+	return len(decimals) <= MinorUnits(currency.String())
+}
+
+func notFarFuture(fl validator.FieldLevel) bool {
+	t, ok := fl.Field().Interface().(time.Time)
+	return ok && !t.After(time.Now().Add(MaxFuture))
+}
@@ -0,0 +1,69 @@
+package validation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type money struct {
+	Amount   float64   `json:"amount" validate:"gt=0,precision=Currency"`
+	Currency string    `json:"currency" validate:"iso4217"`
+	Note     string    `json:"note,omitempty" validate:"max=5"`
+	When     time.Time `json:"when" validate:"notfarfuture"`
+}
+
+func TestStruct_ReportsEveryField(t *testing.T) {
+	err := Struct(money{Amount: -1, Currency: "XYZ", Note: "too long", When: time.Now().Add(2 * MaxFuture)})
+	var fields Errors
+	require.ErrorAs(t, err, &fields)
+	assert.Equal(t, Errors{
+		{"amount", "must be greater than 0"},
+		{"currency", "must be an ISO 4217 currency code"},
+		{"note", "must be at most 5 characters"},
+		{"when", "is too far in the future"},
+	}, fields)
+
+	assert.NoError(t, Struct(money{Amount: 1, Currency: "EUR", Note: "héllo", When: time.Now()}), "lengths count characters")
+}
+
+func TestStruct_PrecisionFollowsCurrency(t *testing.T) {
+	for _, tc := range []struct {
+		amount   float64
+		currency string
+		ok       bool
+	}{
+		{12.34, "USD", true},
+		{12.345, "USD", false},
+		{1500, "JPY", true},
+		{1500.5, "JPY", false},
+		{1.234, "KWD", true},
+		{1.2345, "KWD", false},
+	} {
+		err := Struct(money{Amount: tc.amount, Currency: tc.currency})
+		if tc.ok {
+			assert.NoError(t, err, "%v %s", tc.amount, tc.currency)
+		} else {
+			assert.EqualError(t, err, "invalid amount: has more decimal places than the currency allows", "%v %s", tc.amount, tc.currency)
+		}
+	}
+}
+
+func TestStruct_CrossFieldNamesUseJSON(t *testing.T) {
+	type transfer struct {
+		FromAccountID string `json:"from_account_id"`
+		ToAccountID   string `json:"to_account_id" validate:"nefield=FromAccountID"`
+	}
+	err := Struct(transfer{FromAccountID: "a", ToAccountID: "a"})
+	assert.EqualError(t, err, "invalid to_account_id: must differ from from_account_id")
+}
+
+func TestStruct_SliceLimitsCountItems(t *testing.T) {
+	type labels struct {
+		Tags []string `json:"tags" validate:"max=2,dive,max=3"`
+	}
+	assert.EqualError(t, Struct(labels{Tags: []string{"a", "b", "c"}}), "invalid tags: must have at most 2 items")
+	assert.EqualError(t, Struct(labels{Tags: []string{"long"}}), "invalid tags[0]: must be at most 3 characters")
+}